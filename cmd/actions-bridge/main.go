@@ -31,7 +31,15 @@ func main() {
 		BaseDir:       getEnv("ACTIONS_BRIDGE_BASE_DIR", "./actions-bridge-workspace"),
 		ActImage:      getEnv("ACT_DEFAULT_IMAGE", "catthehacker/ubuntu:act-latest"),
 		Platform:      getEnv("ACT_PLATFORM", "linux/amd64"),
+		RunnerImages:  getEnv("ACT_RUNNER_IMAGES", ""),
 		MaxConcurrent: getEnvInt("MAX_CONCURRENT_WORKFLOWS", 5),
+		HistoryMax:    getEnvInt("ACTIONS_BRIDGE_HISTORY_MAX_PER_UNIT", bridge.DefaultHistoryMaxPerUnit),
+		HistoryMaxAge: getEnvDuration("ACTIONS_BRIDGE_HISTORY_MAX_AGE", bridge.DefaultHistoryMaxAge),
+		Archive:       getEnv("ACTIONS_BRIDGE_ARCHIVE", ""),
+		LintConfig:    getEnv("ACTIONS_BRIDGE_LINT_CONFIG", ""),
+		ImportRoot:    getEnv("ACTIONS_BRIDGE_IMPORT_ROOT", ""),
+		KeptWorkspace: getEnvDuration("ACTIONS_BRIDGE_KEPT_WORKSPACE_TTL", bridge.DefaultKeptWorkspaceTTL),
+		Approvers:     getEnv("ACTIONS_BRIDGE_APPROVERS", ""),
 		HealthAddr:    getEnv("HEALTH_ADDR", ":8080"),
 		Debug:         getEnvBool("DEBUG", false),
 	}
@@ -41,8 +49,33 @@ func main() {
 		log.Fatalf("Invalid configuration: %v", err)
 	}
 
+	// Open the archive Finalize writes to; unset uses <BaseDir>/archive
+	var archive bridge.Archive
+	if config.Archive != "" {
+		opened, err := bridge.OpenArchive(config.Archive)
+		if err != nil {
+			log.Fatalf("Failed to open archive: %v", err)
+		}
+		archive = opened
+	}
+
 	// Create bridge instance
-	actionsBridge, err := bridge.NewActionsBridge(config.BaseDir)
+	actionsBridge, err := bridge.NewActionsBridgeWithConfig(bridge.BridgeConfig{
+		BaseDir:          config.BaseDir,
+		DefaultImage:     config.ActImage,
+		Platform:         config.Platform,
+		RunnerImagesFile: config.RunnerImages,
+		MaxConcurrent:    config.MaxConcurrent,
+		HistoryRetention: &bridge.HistoryRetention{
+			MaxPerUnit: config.HistoryMax,
+			MaxAge:     config.HistoryMaxAge,
+		},
+		Archive:          archive,
+		LintConfigFile:   config.LintConfig,
+		ImportRoot:       config.ImportRoot,
+		KeptWorkspaceTTL: config.KeptWorkspace,
+		ApproversFile:    config.Approvers,
+	})
 	if err != nil {
 		log.Fatalf("Failed to create bridge: %v", err)
 	}
@@ -154,7 +187,15 @@ type Config struct {
 	BaseDir       string
 	ActImage      string
 	Platform      string
+	RunnerImages  string // Path to a runs-on label to image mapping file
 	MaxConcurrent int
+	HistoryMax    int           // Executions kept per unit, 0 for no limit
+	HistoryMaxAge time.Duration // Age after which executions are removed, 0 for no limit
+	Archive       string        // Archive directory or URL (file://, s3://bucket/prefix?endpoint=...)
+	LintConfig    string        // Path to a lint rule configuration file
+	ImportRoot    string        // Directory holding the checkouts Import may read; unset disables repo_path
+	KeptWorkspace time.Duration // How long workspaces of failed runs are kept
+	Approvers     string        // Path to the file of approvers trusted by units requiring approvals
 	HealthAddr    string
 	Debug         bool
 }
@@ -194,6 +235,15 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
 func getEnvBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		return value == "true" || value == "1" || value == "yes"
@@ -2,25 +2,35 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/confighub/actions-bridge/pkg/bridge"
+	"github.com/confighub/sdk/bridge-worker/api"
 	"github.com/google/uuid"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
 )
 
 var (
 	// Version is set at build time
 	Version = "dev"
 
-	// Global flag
-	verbose bool
+	// Global flags
+	verbose    bool
+	historyDir string
+	lintConfig string
 )
 
 func main() {
@@ -35,13 +45,21 @@ workflows based on configuration units.`,
 
 	// Global flags
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+	rootCmd.PersistentFlags().StringVar(&historyDir, "history-dir", "", "Execution history directory (default: $ACTIONS_BRIDGE_HISTORY_DIR or the user cache dir)")
+	rootCmd.PersistentFlags().StringVar(&lintConfig, "lint-config", "", "Lint rule configuration file (default: $ACTIONS_BRIDGE_LINT_CONFIG)")
 
 	// Add commands
 	rootCmd.AddCommand(
 		runCommand(),
 		validateCommand(),
 		listCommand(),
+		listRulesCommand(),
 		cleanCommand(),
+		historyCommand(),
+		importCommand(),
+		exportCommand(),
+		approvalKeyCommand(),
+		approveCommand(),
 		versionCommand(),
 	)
 
@@ -58,12 +76,20 @@ func runCommand() *cobra.Command {
 		unit         string
 		dryRun       bool
 		event        string
+		entrypoint   string
+		jobs         []string
+		matrix       []string
+		runnerImages []string
+		imagesFile   string
+		socket       string
 		inputs       []string
 		platform     string
 		artifactDir  string
 		envFile      string
 		secretsFile  string
 		validateOnly bool
+		format       string
+		failOn       string
 		watch        bool
 		timeout      int
 	)
@@ -73,24 +99,46 @@ func runCommand() *cobra.Command {
 		Short: "Run a GitHub Actions workflow locally",
 		Long: `Run a GitHub Actions workflow using act. This command provides
 full control over the execution environment and allows testing workflows
-before deploying them.`,
+before deploying them. WORKFLOW may also be an ActionsBundle unit carrying
+several workflows, local actions and scripts.`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			workflowPath := args[0]
 
+			if err := bridge.ValidateEvent(event); err != nil {
+				return err
+			}
+			if err := validateReportFlags(format, failOn); err != nil {
+				return err
+			}
+			if format != bridge.FormatText && !validateOnly {
+				return fmt.Errorf("--format %s requires --validate", format)
+			}
+
 			// Validate workflow exists
 			if _, err := os.Stat(workflowPath); err != nil {
 				return fmt.Errorf("workflow file not found: %s", workflowPath)
 			}
 
 			// Read workflow
-			workflowData, err := os.ReadFile(workflowPath)
+			unitData, err := os.ReadFile(workflowPath)
 			if err != nil {
 				return fmt.Errorf("read workflow: %w", err)
 			}
 
-			// Strip ConfigHub metadata if present
-			workflowData = stripConfigHubMetadata(workflowData)
+			// Bundles carry workflows, local actions and scripts; a plain
+			// workflow is run as a bundle of one file
+			bundle, envelope, err := loadUnit(unitData)
+			if err != nil {
+				return fmt.Errorf("%s: %w", workflowPath, err)
+			}
+			if entrypoint != "" {
+				bundle.Entrypoint = entrypoint
+				if err := bundle.Validate(); err != nil {
+					return err
+				}
+			}
+			workflowData := bundle.Workflow()
 
 			// Create temporary workspace
 			tempDir, err := os.MkdirTemp("", "actions-cli-*")
@@ -111,31 +159,51 @@ before deploying them.`,
 			}
 			defer ws.SecureCleanup()
 
-			// Check compatibility
-			checker := bridge.NewCompatibilityChecker()
-			warnings := checker.CheckWorkflow(workflowData)
-
-			if len(warnings) > 0 {
-				fmt.Println("Compatibility warnings:")
-				for _, w := range warnings {
-					fmt.Printf("  [%s] Line %d: %s\n", w.Level, w.Line, w.Message)
+			// Load secrets if provided
+			secrets := make(map[string]string)
+			lintOptions := bridge.LintOptions{ReadFile: bundle.ReadFile}
+			if secretsFile != "" {
+				secrets, err = bridge.ParseSecretsFile(secretsFile)
+				if err != nil {
+					return fmt.Errorf("parse secrets: %w", err)
 				}
-				fmt.Println()
+				lintOptions.Secrets = sortedNames(secrets)
+			}
+
+			// Check compatibility; errors stop the run as they would on Apply
+			checker, err := newCompatibilityChecker()
+			if err != nil {
+				return err
 			}
 
 			// Validation mode
 			if validateOnly {
-				supported, reason := checker.IsWorkflowSupported(workflowData)
-				if !supported {
-					return fmt.Errorf("workflow not supported: %s", reason)
+				report := bridge.NewLintReport(checker)
+				report.Root = repositoryRoot()
+				addUnit(report, workflowPath, unitData, bundle, envelope, lintOptions)
+				return writeReport(report, format, failOn)
+			}
+
+			errorCount := 0
+			for _, name := range bundle.Workflows() {
+				warnings := checker.CheckWorkflowWith([]byte(bundle.Files[name]), lintOptions)
+				if len(warnings) == 0 {
+					continue
 				}
-				fmt.Println("✓ Workflow is valid and supported")
-				return nil
+				errorCount += len(bridge.WarningsAtLeast(warnings, bridge.SeverityError))
+
+				fmt.Printf("Compatibility warnings (%s):\n", name)
+				for _, w := range warnings {
+					fmt.Printf("  %s\n", formatWarning(w))
+				}
+				fmt.Println()
+			}
+			if errorCount > 0 {
+				return fmt.Errorf("workflow has %d error(s)", errorCount)
 			}
 
-			// Write workflow to workspace
-			// Act expects the workflow to be named "workflow.yml"
-			if err := ws.WriteWorkflow("workflow.yml", workflowData); err != nil {
+			// Lay out the bundle files in the workspace
+			if err := ws.WriteBundle(bundle); err != nil {
 				return fmt.Errorf("write workflow: %w", err)
 			}
 
@@ -149,13 +217,10 @@ before deploying them.`,
 				inputMap[parts[0]] = parts[1]
 			}
 
-			// Load secrets if provided
-			secrets := make(map[string]string)
-			if secretsFile != "" {
-				secrets, err = bridge.ParseSecretsFile(secretsFile)
-				if err != nil {
-					return fmt.Errorf("parse secrets: %w", err)
-				}
+			// Parse matrix selectors
+			matrixFilter, err := bridge.ParseMatrixSelector(matrix)
+			if err != nil {
+				return err
 			}
 
 			// Load environment if provided
@@ -167,43 +232,131 @@ before deploying them.`,
 				}
 			}
 
+			// Without a unit, history is kept under the workflow's file name
+			if unit == "" {
+				unit = strings.TrimSuffix(filepath.Base(workflowPath), filepath.Ext(workflowPath))
+			}
+
 			// Prepare execution context
 			execCtx := &bridge.ExecutionContext{
-				Workspace:  ws,
-				ConfigData: workflowData,
+				Workspace:    ws,
+				ConfigData:   workflowData,
+				WorkflowFile: bundle.Entrypoint,
 				Metadata: bridge.ExecutionMetadata{
 					Space:    space,
 					Unit:     unit,
 					Revision: 1,
 					Actor:    os.Getenv("USER"),
 				},
-				Secrets:     secrets,
-				Environment: environment,
+				Secrets:         secrets,
+				Environment:     environment,
+				EventName:       event,
+				Jobs:            jobs,
+				ContainerSocket: socket,
+				Matrix:          matrixFilter,
 				EventPayload: map[string]interface{}{
-					"action": event,
 					"inputs": inputMap,
 				},
-				DryRun: dryRun,
+				DryRun:  dryRun,
+				Timeout: time.Duration(timeout) * time.Second,
 			}
-			
+
 			// Create runner with default container image
 			containerImage := "catthehacker/ubuntu:act-latest"
 			runner := bridge.NewActRunner(platform, containerImage)
-			
+
+			// Record the execution so it shows up in `history`
+			if store, err := openHistoryStore(bridge.HistoryRetention{
+				MaxPerUnit: bridge.DefaultHistoryMaxPerUnit,
+				MaxAge:     bridge.DefaultHistoryMaxAge,
+			}); err != nil {
+				log.Printf("Execution history disabled: %v", err)
+			} else {
+				runner.SetHistoryStore(store)
+			}
+
+			// Map runs-on labels to images; flags win over the file
+			images := bridge.RunnerImages{}
+			if imagesFile != "" {
+				if images, err = bridge.LoadRunnerImages(imagesFile); err != nil {
+					return err
+				}
+			}
+			if len(runnerImages) > 0 {
+				flagImages, err := bridge.ParseRunnerImages(strings.Join(runnerImages, ","))
+				if err != nil {
+					return err
+				}
+				images = images.Merge(flagImages)
+			}
+			runner.SetRunnerImages(images)
+
 			// Execute workflow
 			fmt.Printf("Running workflow: %s\n", workflowPath)
 			if dryRun {
 				fmt.Println("DRY RUN - No actual execution")
 			}
 
-			result, err := runner.Execute(execCtx)
+			// Stop the workflow on Ctrl-C or SIGTERM
+			runCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			result, err := runner.Execute(runCtx, execCtx)
 			if err != nil {
 				return fmt.Errorf("execution failed: %w", err)
 			}
 
+			// Dry runs only report the plan, and fail like a run when act
+			// could not walk it
+			if result.Plan != nil {
+				fmt.Println()
+				for _, line := range result.Plan.Summary() {
+					fmt.Println(line)
+				}
+				if result.Status != bridge.ExecutionStatusSuccess {
+					for _, line := range result.Logs {
+						if strings.HasPrefix(line, "ERROR:") {
+							fmt.Println(line)
+						}
+					}
+					return fmt.Errorf("dry run %s with exit code %d", strings.ReplaceAll(result.Status, "_", " "), result.ExitCode)
+				}
+				return nil
+			}
+
 			// Display results
-			fmt.Printf("\nExecution completed in %s\n", result.Duration)
+			switch result.Status {
+			case bridge.ExecutionStatusTimedOut:
+				fmt.Printf("\nExecution timed out after %s\n", result.Duration)
+			case bridge.ExecutionStatusCancelled:
+				fmt.Printf("\nExecution cancelled after %s\n", result.Duration)
+			default:
+				fmt.Printf("\nExecution completed in %s\n", result.Duration)
+			}
 			fmt.Printf("Exit code: %d\n", result.ExitCode)
+			if result.Runtime != nil {
+				fmt.Printf("Container runtime: %s %s (%s)\n", result.Runtime.Name, result.Runtime.Version, result.Runtime.Host)
+			}
+
+			if len(result.Jobs) > 0 {
+				fmt.Printf("\nJobs:\n")
+				for _, job := range result.Jobs {
+					fmt.Printf("  [%s] %s\n", job.Conclusion, job.Name)
+					for _, step := range job.Steps {
+						fmt.Printf("      [%s] %s\n", step.Conclusion, step.Name)
+					}
+					for _, name := range sortedNames(job.Outputs) {
+						fmt.Printf("      output %s=%s\n", name, job.Outputs[name])
+					}
+				}
+			}
+
+			if len(result.Outputs) > 0 {
+				fmt.Printf("\nOutputs:\n")
+				for _, name := range sortedNames(result.Outputs) {
+					fmt.Printf("  %s=%s\n", name, result.Outputs[name])
+				}
+			}
 
 			if len(result.Artifacts) > 0 {
 				fmt.Printf("\nArtifacts:\n")
@@ -230,7 +383,7 @@ before deploying them.`,
 			}
 
 			if result.ExitCode != 0 {
-				return fmt.Errorf("workflow failed with exit code %d", result.ExitCode)
+				return fmt.Errorf("workflow %s with exit code %d", strings.ReplaceAll(result.Status, "_", " "), result.ExitCode)
 			}
 
 			return nil
@@ -241,13 +394,21 @@ before deploying them.`,
 	cmd.Flags().StringVar(&space, "space", "", "ConfigHub space")
 	cmd.Flags().StringVar(&unit, "unit", "", "ConfigHub unit")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be executed without running")
-	cmd.Flags().StringVar(&event, "event", "workflow_dispatch", "GitHub event type to simulate")
+	cmd.Flags().StringVar(&event, "event", "", fmt.Sprintf("GitHub event to simulate (%s); defaults to one the workflow triggers on", strings.Join(bridge.SupportedEvents, ", ")))
+	cmd.Flags().StringVar(&entrypoint, "entrypoint", "", "Workflow to run from a bundle (e.g. .github/workflows/deploy.yml)")
+	cmd.Flags().StringArrayVar(&jobs, "job", nil, "Run only this job and the jobs it needs (repeatable)")
+	cmd.Flags().StringArrayVar(&matrix, "matrix", nil, "Run only matrix combinations matching key=value[,key=value] (repeatable)")
+	cmd.Flags().StringArrayVar(&runnerImages, "runner-image", nil, "Map a runs-on label or pattern to an image, e.g. ubuntu-24.04=catthehacker/ubuntu:act-24.04 (repeatable)")
+	cmd.Flags().StringVar(&imagesFile, "runner-images-file", "", "YAML or JSON file mapping runs-on labels to images")
 	cmd.Flags().StringSliceVarP(&inputs, "input", "i", nil, "Workflow inputs (key=value)")
 	cmd.Flags().StringVar(&platform, "platform", "linux/amd64", "Execution platform")
+	cmd.Flags().StringVar(&socket, "socket", "", "Container engine socket, e.g. /run/podman/podman.sock (default: DOCKER_HOST or the Docker socket)")
 	cmd.Flags().StringVar(&artifactDir, "artifact-dir", "", "Directory to save artifacts")
 	cmd.Flags().StringVar(&envFile, "env-file", "", "Environment file to load")
 	cmd.Flags().StringVar(&secretsFile, "secrets-file", "", "Secrets file to load")
 	cmd.Flags().BoolVar(&validateOnly, "validate", false, "Validate workflow without running")
+	cmd.Flags().StringVar(&format, "format", bridge.FormatText, fmt.Sprintf("Output format of --validate (%s)", strings.Join(bridge.ReportFormats, ", ")))
+	cmd.Flags().StringVar(&failOn, "fail-on", bridge.SeverityError, "Lowest severity of findings that fails --validate (error, warning, info or none)")
 	cmd.Flags().BoolVar(&watch, "watch", false, "Watch workflow file for changes")
 	cmd.Flags().IntVar(&timeout, "timeout", 3600, "Execution timeout in seconds")
 
@@ -256,59 +417,64 @@ before deploying them.`,
 
 // validateCommand creates the validate command
 func validateCommand() *cobra.Command {
-	return &cobra.Command{
-		Use:   "validate WORKFLOW",
-		Short: "Validate a GitHub Actions workflow",
-		Long:  "Check if a workflow is valid and can be executed locally with act.",
-		Args:  cobra.ExactArgs(1),
-		RunE: func(cmd *cobra.Command, args []string) error {
-			workflowPath := args[0]
-
-			// Read workflow
-			workflowData, err := os.ReadFile(workflowPath)
-			if err != nil {
-				return fmt.Errorf("read workflow: %w", err)
-			}
+	var (
+		format string
+		failOn string
+	)
 
-			// Parse as YAML to check syntax
-			var workflow map[string]interface{}
-			if err := yaml.Unmarshal(workflowData, &workflow); err != nil {
-				return fmt.Errorf("invalid YAML syntax: %w", err)
+	cmd := &cobra.Command{
+		Use:   "validate WORKFLOW...",
+		Short: "Validate GitHub Actions workflows",
+		Long: `Check if workflows are valid and can be executed locally with act.
+
+The workflow is checked against the workflow syntax, and the inputs passed to
+local actions and reusable workflows against their definitions. A unit with
+an envelope has every bundle workflow checked; local actions missing from the
+bundle are read from the repository the workflow is in.
+
+Findings are printed as text, JSON or SARIF. Validation fails when a finding
+is at or above the --fail-on severity, or a workflow cannot run at all. Files
+that cannot be read or parsed are reported and the rest are still checked.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateReportFlags(format, failOn); err != nil {
+				return err
 			}
 
 			// Check with compatibility checker
-			checker := bridge.NewCompatibilityChecker()
-			warnings := checker.CheckWorkflow(workflowData)
-
-			supported, reason := checker.IsWorkflowSupported(workflowData)
-			if !supported {
-				return fmt.Errorf("workflow not supported: %s", reason)
+			checker, err := newCompatibilityChecker()
+			if err != nil {
+				return err
 			}
+			report := bridge.NewLintReport(checker)
+			report.Root = repositoryRoot()
 
-			fmt.Printf("✓ Workflow is valid: %s\n", workflowPath)
-
-			if len(warnings) > 0 {
-				fmt.Printf("\nCompatibility notes:\n")
-				for _, w := range warnings {
-					fmt.Printf("  [%s] Line %d: %s\n", w.Level, w.Line, w.Message)
+			// Files that cannot be read or parsed are reported, and the
+			// rest are still checked
+			for _, workflowPath := range args {
+				workflowData, err := os.ReadFile(workflowPath)
+				if err != nil {
+					report.AddError(workflowPath, fmt.Errorf("read workflow: %w", err))
+					continue
 				}
-
-				// Show suggestions
-				suggestions := checker.SuggestFixes(warnings)
-				if len(suggestions) > 0 {
-					fmt.Printf("\nSuggestions:\n")
-					for _, s := range suggestions {
-						fmt.Printf("  - %s\n", s)
-					}
+				bundle, envelope, err := loadUnit(workflowData)
+				if err != nil {
+					report.AddError(workflowPath, err)
+					continue
 				}
+				addUnit(report, workflowPath, workflowData, bundle, envelope, bridge.LintOptions{ReadFile: repositoryReader(bundle, workflowPath)})
 			}
 
-			return nil
+			return writeReport(report, format, failOn)
 		},
 	}
+
+	cmd.Flags().StringVar(&format, "format", bridge.FormatText, fmt.Sprintf("Output format (%s)", strings.Join(bridge.ReportFormats, ", ")))
+	cmd.Flags().StringVar(&failOn, "fail-on", bridge.SeverityError, "Lowest severity of findings that fails validation (error, warning, info or none)")
+
+	return cmd
 }
 
-// listCommand lists known limitations
 func listCommand() *cobra.Command {
 	return &cobra.Command{
 		Use:   "list-limitations",
@@ -329,6 +495,41 @@ func listCommand() *cobra.Command {
 	}
 }
 
+// listRulesCommand lists the lint rules
+func listRulesCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list-rules",
+		Short: "List the lint rules workflows are checked with",
+		Long: `Display the built-in lint rules and the custom rules of the lint
+configuration, with their severity and whether they are turned on. A finding
+is suppressed with a comment such as "# actions-bridge-ignore: AB012".`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			checker, err := newCompatibilityChecker()
+			if err != nil {
+				return err
+			}
+			linter := checker.Linter()
+
+			for _, rule := range linter.Rules() {
+				state := ""
+				if !linter.Enabled(rule.ID) {
+					state = " (off)"
+				}
+				fmt.Printf("%-8s %-8s %s%s\n", rule.ID, rule.Severity, rule.Description, state)
+				if verbose {
+					if rule.Fix != "" {
+						fmt.Printf("         fix: %s\n", rule.Fix)
+					}
+					if rule.DocURL != "" {
+						fmt.Printf("         doc: %s\n", rule.DocURL)
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
 // cleanCommand creates the clean command
 func cleanCommand() *cobra.Command {
 	return &cobra.Command{
@@ -364,6 +565,338 @@ func cleanCommand() *cobra.Command {
 	}
 }
 
+// historyCommand creates the history command
+func historyCommand() *cobra.Command {
+	var (
+		limit      int
+		asJSON     bool
+		prune      bool
+		maxRuns    int
+		maxAge     time.Duration
+		deleteUnit bool
+		unitSpace  string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "history [UNIT [EXECUTION_ID]]",
+		Short: "Show recorded workflow executions",
+		Long: `List the units with recorded executions, the executions of a unit, or
+the details of one execution. Executions are recorded by 'run'. Units are
+kept per space; use --space for a unit run with one.`,
+		Args: cobra.MaximumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := openHistoryStore(bridge.HistoryRetention{MaxPerUnit: maxRuns, MaxAge: maxAge})
+			if err != nil {
+				return err
+			}
+
+			if prune {
+				removed, err := store.Prune()
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Removed %d execution record(s)\n", removed)
+				return nil
+			}
+
+			switch len(args) {
+			case 0:
+				units, err := store.Units()
+				if err != nil {
+					return err
+				}
+				if len(units) == 0 {
+					fmt.Printf("No executions recorded in %s\n", store.Dir())
+					return nil
+				}
+				for _, unit := range units {
+					latest, err := store.Latest(unit.Space, unit.Unit)
+					if err != nil {
+						continue
+					}
+					fmt.Printf("%-30s %-10s %s\n", unit, latest.Status, latest.StartTime.Format(time.RFC3339))
+				}
+
+			case 1:
+				if deleteUnit {
+					removed, err := store.DeleteUnit(unitSpace, args[0])
+					if err != nil {
+						return err
+					}
+					fmt.Printf("Removed %d execution record(s) of %s\n", removed, args[0])
+					return nil
+				}
+				records, err := store.List(unitSpace, args[0], limit)
+				if err != nil {
+					return err
+				}
+				if len(records) == 0 {
+					return fmt.Errorf("no executions recorded for unit %s", args[0])
+				}
+				if asJSON {
+					return printJSON(records)
+				}
+				for _, record := range records {
+					fmt.Printf("%s  %s  %-10s exit=%-3d %-18s %s\n", record.ID, record.StartTime.Format(time.RFC3339),
+						record.Status, record.ExitCode, record.Inputs.Event, record.EndTime.Sub(record.StartTime).Round(time.Millisecond))
+				}
+
+			case 2:
+				record, err := store.Get(unitSpace, args[0], args[1])
+				if err != nil {
+					return err
+				}
+				if asJSON {
+					return printJSON(record)
+				}
+				fmt.Printf("Execution: %s\n", record.ID)
+				fmt.Printf("Unit:      %s\n", record.UnitID)
+				fmt.Printf("Status:    %s (exit code %d)\n", record.Status, record.ExitCode)
+				fmt.Printf("Started:   %s\n", record.StartTime.Format(time.RFC3339))
+				fmt.Printf("Duration:  %s\n", record.EndTime.Sub(record.StartTime).Round(time.Millisecond))
+				fmt.Printf("Event:     %s\n", record.Inputs.Event)
+				fmt.Printf("Config:    sha256:%s\n", record.ConfigHash)
+				for _, job := range record.Jobs {
+					fmt.Printf("  [%s] %s\n", job.Conclusion, job.Name)
+				}
+				for _, artifact := range record.ArtifactManifest {
+					fmt.Printf("  artifact %s (%d bytes, sha256:%s)\n", artifact.Path, artifact.Size, artifact.SHA256)
+				}
+				if verbose {
+					fmt.Printf("\nExecution logs:\n")
+					for _, line := range record.Logs {
+						fmt.Println(line)
+					}
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&limit, "limit", 20, "Maximum number of executions to list (0 for all)")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print records as JSON")
+	cmd.Flags().BoolVar(&deleteUnit, "delete", false, "Delete all recorded executions of UNIT")
+	cmd.Flags().StringVar(&unitSpace, "space", "", "ConfigHub space of UNIT")
+	cmd.Flags().BoolVar(&prune, "prune", false, "Remove executions beyond --max-runs or older than --max-age")
+	cmd.Flags().IntVar(&maxRuns, "max-runs", bridge.DefaultHistoryMaxPerUnit, "Executions to keep per unit when pruning (0 for no limit)")
+	cmd.Flags().DurationVar(&maxAge, "max-age", bridge.DefaultHistoryMaxAge, "Age after which executions are pruned (0 for no limit)")
+
+	return cmd
+}
+
+// importCommand creates the import command
+func importCommand() *cobra.Command {
+	var outputDir string
+
+	cmd := &cobra.Command{
+		Use:   "import [REPO_DIR]",
+		Short: "Import the workflows of a repository as units",
+		Long: `Create a ConfigHub unit for every workflow in the .github/workflows
+directory of a git checkout (default: the current directory). Workflows that
+use local actions or reusable workflows become bundles carrying those files.
+Units are printed as a multi-document YAML stream, or written to --output.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoDir := "."
+			if len(args) > 0 {
+				repoDir = args[0]
+			}
+
+			units, err := bridge.ImportRepository(repoDir)
+			if err != nil {
+				return err
+			}
+
+			if outputDir == "" {
+				_, err := os.Stdout.Write(bridge.JoinUnits(units))
+				return err
+			}
+
+			if err := os.MkdirAll(outputDir, 0755); err != nil {
+				return fmt.Errorf("create output dir: %w", err)
+			}
+			for _, unit := range units {
+				file := filepath.Join(outputDir, unit.Name+".yaml")
+				if err := os.WriteFile(file, unit.Data, 0644); err != nil {
+					return fmt.Errorf("write unit: %w", err)
+				}
+				fmt.Printf("%-30s %s", unit.Name, unit.SourcePath)
+				if len(unit.Files) > 1 {
+					fmt.Printf(" (bundle of %d files)", len(unit.Files))
+				}
+				fmt.Println()
+			}
+			fmt.Printf("\nWrote %d unit(s) to %s\n", len(units), outputDir)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputDir, "output", "o", "", "Write one <unit>.yaml file per unit to this directory")
+
+	return cmd
+}
+
+// exportCommand creates the export command
+func exportCommand() *cobra.Command {
+	var outputDir string
+
+	cmd := &cobra.Command{
+		Use:   "export UNIT_FILE...",
+		Short: "Export units to a .github/workflows tree",
+		Long: `Write the workflows of ConfigHub units back to the files they came from,
+ready to commit for real GitHub runs. Each file may hold several units, as
+printed by 'import'. Workflows are written to their source-path annotation or
+to .github/workflows/<unit>.yml; bundles are written file by file. Nothing
+is written unless every workflow can run locally and imports back as the
+same unit.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var units [][]byte
+			for _, file := range args {
+				data, err := os.ReadFile(file)
+				if err != nil {
+					return fmt.Errorf("read units: %w", err)
+				}
+				units = append(units, bridge.SplitUnits(data)...)
+			}
+			if len(units) == 0 {
+				return fmt.Errorf("no units found in %s", strings.Join(args, ", "))
+			}
+
+			files, err := bridge.ExportUnits(units...)
+			if err != nil {
+				return err
+			}
+			checks, err := bridge.WriteExport(outputDir, files)
+			if err != nil {
+				return err
+			}
+
+			for _, file := range files {
+				fmt.Printf("%-50s (unit %s)\n", file.Path, file.Unit)
+			}
+
+			for _, check := range checks {
+				if len(check.Warnings) > 0 && verbose {
+					fmt.Printf("\n%s compatibility notes:\n", check.Path)
+					for _, w := range check.Warnings {
+						fmt.Printf("  %s\n", formatWarning(w))
+					}
+				}
+			}
+
+			fmt.Printf("\n✓ Exported %d unit(s) as %d file(s) to %s\n", len(units), len(files), outputDir)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&outputDir, "output", "o", ".", "Repository root to write the files to")
+
+	return cmd
+}
+
+// approvalKeyCommand creates the approval-key command
+func approvalKeyCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "approval-key KEY_FILE",
+		Short: "Create a key for signing approvals",
+		Long: `Write a new private key for signing approvals with 'approve' to KEY_FILE
+and print its public key. List the public key under the approver's name in
+the worker's ACTIONS_BRIDGE_APPROVERS file.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			private, public, err := bridge.GenerateApprovalKey()
+			if err != nil {
+				return err
+			}
+			file, err := os.OpenFile(args[0], os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+			if err != nil {
+				return fmt.Errorf("create key file: %w", err)
+			}
+			if _, err := fmt.Fprintln(file, private); err != nil {
+				file.Close()
+				return fmt.Errorf("write key file: %w", err)
+			}
+			if err := file.Close(); err != nil {
+				return fmt.Errorf("write key file: %w", err)
+			}
+			fmt.Println(public)
+			return nil
+		},
+	}
+}
+
+// approveCommand creates the approve command
+func approveCommand() *cobra.Command {
+	var (
+		keyFile  string
+		approver string
+		action   string
+		space    string
+		unitID   string
+		revision int64
+	)
+
+	cmd := &cobra.Command{
+		Use:   "approve UNIT_FILE",
+		Short: "Sign an approval of an Apply or Destroy of a unit revision",
+		Long: `Sign an approval of an Apply or Destroy of a revision of a unit requiring
+approvals and print it as JSON. UNIT_FILE must hold the revision's data as
+ConfigHub stores it. Pass the approvals to the worker as a list in the
+approvals extra parameter; only approvals of approvers in the worker's
+ACTIONS_BRIDGE_APPROVERS file count.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			actions := map[string]api.ActionType{"apply": api.ActionApply, "destroy": api.ActionDestroy}
+			actionType, ok := actions[action]
+			if !ok {
+				return fmt.Errorf("invalid action %q (expected apply or destroy)", action)
+			}
+			spaceID, err := uuid.Parse(space)
+			if err != nil {
+				return fmt.Errorf("invalid space ID: %w", err)
+			}
+			unit, err := uuid.Parse(unitID)
+			if err != nil {
+				return fmt.Errorf("invalid unit ID: %w", err)
+			}
+
+			keyData, err := os.ReadFile(keyFile)
+			if err != nil {
+				return fmt.Errorf("read key file: %w", err)
+			}
+			key, err := bridge.ParseApprovalKey(keyData)
+			if err != nil {
+				return err
+			}
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("read unit: %w", err)
+			}
+
+			approval := bridge.SignApproval(approver, key, bridge.ApprovalMessage(actionType, spaceID, unit, revision, data))
+			output, err := json.Marshal(approval)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(output))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&keyFile, "key", "", "Private key file written by approval-key")
+	cmd.Flags().StringVar(&approver, "approver", "", "Approver name, as listed in the worker's approvers file")
+	cmd.Flags().StringVar(&action, "action", "apply", "Action to approve (apply or destroy)")
+	cmd.Flags().StringVar(&space, "space", "", "ConfigHub space ID")
+	cmd.Flags().StringVar(&unitID, "unit-id", "", "ConfigHub unit ID")
+	cmd.Flags().Int64Var(&revision, "revision", 0, "Revision number of the unit")
+	for _, flag := range []string{"key", "approver", "space", "unit-id", "revision"} {
+		cmd.MarkFlagRequired(flag)
+	}
+
+	return cmd
+}
+
 // versionCommand shows version information
 func versionCommand() *cobra.Command {
 	return &cobra.Command{
@@ -379,6 +912,229 @@ func versionCommand() *cobra.Command {
 
 // Helper functions
 
+// openHistoryStore opens the execution history used by the CLI
+func openHistoryStore(retention bridge.HistoryRetention) (*bridge.HistoryStore, error) {
+	dir := historyDir
+	if dir == "" {
+		dir = os.Getenv("ACTIONS_BRIDGE_HISTORY_DIR")
+	}
+	if dir == "" {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("locate history dir: %w", err)
+		}
+		dir = filepath.Join(cacheDir, "actions-bridge", "history")
+	}
+
+	return bridge.NewHistoryStore(dir, retention)
+}
+
+// newCompatibilityChecker creates a checker configured with the lint
+// configuration of --lint-config or $ACTIONS_BRIDGE_LINT_CONFIG
+func newCompatibilityChecker() (*bridge.CompatibilityChecker, error) {
+	checker := bridge.NewCompatibilityChecker()
+	path := lintConfig
+	if path == "" {
+		path = os.Getenv("ACTIONS_BRIDGE_LINT_CONFIG")
+	}
+	if path == "" {
+		return checker, nil
+	}
+
+	config, err := bridge.LoadLintConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := checker.Linter().Configure(config); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return checker, nil
+}
+
+// loadUnit reads the files of a unit; a plain workflow is a bundle of one
+// file and has no envelope
+func loadUnit(data []byte) (*bridge.Bundle, *bridge.Envelope, error) {
+	envelope, err := bridge.ParseEnvelope(data)
+	if errors.Is(err, bridge.ErrNoEnvelope) {
+		return bridge.NewWorkflowBundle(data), nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	bundle, err := envelope.Files()
+	if err != nil {
+		return nil, nil, err
+	}
+	return bundle, envelope, nil
+}
+
+// addUnit checks the workflows of a unit file for a report. The workflow of
+// an Actions unit is reported with the lines of the file when its header
+// comes first; bundle workflows are reported by their path.
+func addUnit(report *bridge.LintReport, file string, data []byte, bundle *bridge.Bundle, envelope *bridge.Envelope, options bridge.LintOptions) {
+	if envelope == nil {
+		report.Add(file, "", 1, data, options)
+		return
+	}
+	if envelope.Bundle == nil && bytes.HasSuffix(data, envelope.Workflow) {
+		header := data[:len(data)-len(envelope.Workflow)]
+		report.Add(file, "", bytes.Count(header, []byte("\n"))+1, envelope.Workflow, options)
+		return
+	}
+	for _, name := range bundle.Workflows() {
+		report.Add(file, name, 0, []byte(bundle.Files[name]), options)
+	}
+}
+
+// failOnNone is the --fail-on value that never fails on findings
+const failOnNone = "none"
+
+// validateReportFlags checks the --format and --fail-on flags
+func validateReportFlags(format, failOn string) error {
+	if !slices.Contains(bridge.ReportFormats, format) {
+		return fmt.Errorf("unknown format %q (expected %s)", format, strings.Join(bridge.ReportFormats, ", "))
+	}
+	switch failOn {
+	case bridge.SeverityError, bridge.SeverityWarning, bridge.SeverityInfo, failOnNone:
+		return nil
+	}
+	return fmt.Errorf("unknown --fail-on severity %q (expected error, warning, info or none)", failOn)
+}
+
+// writeReport prints a lint report and fails when a workflow cannot run or
+// has findings at or above failOn
+func writeReport(report *bridge.LintReport, format, failOn string) error {
+	var err error
+	switch format {
+	case bridge.FormatJSON:
+		err = report.WriteJSON(os.Stdout)
+	case bridge.FormatSARIF:
+		err = report.WriteSARIF(os.Stdout, "cub-local-actions", Version)
+	default:
+		printReport(report, failOn)
+	}
+	if err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+
+	unsupported := 0
+	for _, f := range report.Files {
+		if !f.Supported {
+			unsupported++
+		}
+	}
+	if unsupported > 0 {
+		return fmt.Errorf("%d workflow(s) not supported", unsupported)
+	}
+	if failOn != failOnNone {
+		if failing := report.AtLeast(failOn); len(failing) > 0 {
+			return fmt.Errorf("%d finding(s) at or above %s", len(failing), failOn)
+		}
+	}
+	return nil
+}
+
+// printReport prints the findings of each workflow of a report, followed by
+// the fix suggestions
+func printReport(report *bridge.LintReport, failOn string) {
+	for _, f := range report.Files {
+		name := f.File
+		if f.Workflow != "" {
+			name = fmt.Sprintf("%s (%s)", f.File, f.Workflow)
+		}
+		var warnings []bridge.Warning
+		for _, w := range report.Warnings {
+			if w.File == f.File && w.Workflow == f.Workflow {
+				warnings = append(warnings, w.Warning)
+			}
+		}
+
+		switch {
+		case !f.Supported:
+			fmt.Printf("✗ Workflow not supported: %s: %s\n", name, f.Reason)
+		case failOn != failOnNone && len(bridge.WarningsAtLeast(warnings, failOn)) > 0:
+			fmt.Printf("✗ Workflow has errors: %s\n", name)
+		default:
+			fmt.Printf("✓ Workflow is valid: %s\n", name)
+		}
+		for _, w := range warnings {
+			fmt.Printf("  %s\n", formatWarning(w))
+		}
+	}
+
+	if len(report.Suggestions) > 0 {
+		fmt.Printf("\nSuggestions:\n")
+		for _, s := range report.Suggestions {
+			fmt.Printf("  - %s\n", s)
+		}
+	}
+}
+
+// repositoryReader reads files of a unit from its bundle, and otherwise from
+// the repository of the workflow file: the parent of its .github directory,
+// or the current directory
+func repositoryReader(bundle *bridge.Bundle, workflowPath string) func(name string) ([]byte, error) {
+	root := "."
+	if abs, err := filepath.Abs(workflowPath); err == nil {
+		dir := filepath.Dir(abs)
+		if filepath.Base(dir) == "workflows" && filepath.Base(filepath.Dir(dir)) == ".github" {
+			root = filepath.Dir(filepath.Dir(dir))
+		}
+	}
+	return func(name string) ([]byte, error) {
+		if data, err := bundle.ReadFile(name); err == nil {
+			return data, nil
+		}
+		return os.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+	}
+}
+
+// repositoryRoot returns the root of the git repository the current
+// directory is in, or the current directory outside of a repository
+func repositoryRoot() string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for dir := cwd; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		if filepath.Dir(dir) == dir {
+			return cwd
+		}
+	}
+}
+
+// formatWarning renders a warning with its rule and location
+func formatWarning(w bridge.Warning) string {
+	location := ""
+	switch {
+	case w.Column > 0:
+		location = fmt.Sprintf(" Line %d:%d:", w.Line, w.Column)
+	case w.Line > 0:
+		location = fmt.Sprintf(" Line %d:", w.Line)
+	}
+	return fmt.Sprintf("[%s %s]%s %s", w.Level, w.Rule, location, w.Message)
+}
+
+// printJSON writes a value as indented JSON to stdout
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// sortedNames returns the keys of a map in sorted order
+func sortedNames(values map[string]string) []string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func parseEnvFile(path string) (map[string]string, error) {
 	env := make(map[string]string)
 
@@ -442,19 +1198,3 @@ func copyArtifacts(srcDir, dstDir string) error {
 		return err
 	})
 }
-
-// stripConfigHubMetadata removes the first 4 lines if they contain ConfigHub metadata
-func stripConfigHubMetadata(data []byte) []byte {
-	lines := bytes.Split(data, []byte("\n"))
-	
-	// Check if the first line contains apiVersion: actions.confighub.com
-	if len(lines) > 0 && bytes.Contains(lines[0], []byte("apiVersion:")) && bytes.Contains(lines[0], []byte("actions.confighub.com")) {
-		// If we have more than 4 lines, skip the first 4
-		if len(lines) > 4 {
-			return bytes.Join(lines[4:], []byte("\n"))
-		}
-	}
-	
-	// Otherwise, return the original data
-	return data
-}
@@ -3,16 +3,40 @@ package bridge
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
-	"sync"
+	"strings"
 	"time"
 
+	"github.com/docker/docker/api/types/container"
 	"github.com/google/uuid"
+	"github.com/nektos/act/pkg/common"
 	"github.com/nektos/act/pkg/model"
 	"github.com/nektos/act/pkg/runner"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultExecutionTimeout bounds a workflow execution when no timeout is configured
+const DefaultExecutionTimeout = time.Hour
+
+// runtimeCheckTimeout bounds the reachability check of the container runtime
+const runtimeCheckTimeout = 10 * time.Second
+
+// Execution statuses reported in ExecutionResult.Status
+const (
+	ExecutionStatusSuccess   = "success"
+	ExecutionStatusFailure   = "failure"
+	ExecutionStatusTimedOut  = "timed_out"
+	ExecutionStatusCancelled = "cancelled"
+)
+
+// Exit codes used when an execution is stopped before it completes
+const (
+	exitCodeTimedOut  = 124
+	exitCodeCancelled = 130
 )
 
 // ActRunner wraps the act library for workflow execution
@@ -20,21 +44,41 @@ type ActRunner struct {
 	platform        string
 	containerImage  string
 	reuseContainers bool
-	executions      sync.Map // map[string]*ExecutionRecord
+	images          RunnerImages
+	hostLock        *dockerHostLock
+	history         *HistoryStore // Nil keeps no history
+	concurrency     *ConcurrencyGroups
 }
 
 // ExecutionRecord tracks a workflow execution
 type ExecutionRecord struct {
-	ID         string
-	UnitID     string
-	StartTime  time.Time
-	EndTime    time.Time
-	ExitCode   int
-	ConfigData []byte
-	OutputData []byte
-	Logs       []string
-	Artifacts  []string
-	Timestamp  time.Time
+	ID               string            `json:"id"`
+	UnitID           string            `json:"unit"`
+	Space            string            `json:"space,omitempty"`
+	Revision         int               `json:"revision,omitempty"`
+	StartTime        time.Time         `json:"start_time"`
+	EndTime          time.Time         `json:"end_time"`
+	Status           string            `json:"status"`
+	ExitCode         int               `json:"exit_code"`
+	Inputs           ExecutionInputs   `json:"inputs"`
+	ConfigHash       string            `json:"config_hash"`
+	ConfigData       []byte            `json:"config_data"`
+	OutputData       json.RawMessage   `json:"output_data,omitempty"`
+	Jobs             []JobResult       `json:"jobs,omitempty"`
+	Outputs          map[string]string `json:"outputs,omitempty"`
+	Logs             []string          `json:"logs,omitempty"`
+	Artifacts        []string          `json:"artifacts,omitempty"`
+	ArtifactManifest []ArtifactEntry   `json:"artifact_manifest,omitempty"`
+	Timestamp        time.Time         `json:"timestamp"`
+}
+
+// ExecutionInputs records what an execution was asked to run
+type ExecutionInputs struct {
+	Event   string                 `json:"event"`
+	Jobs    []string               `json:"jobs,omitempty"`
+	Matrix  map[string][]string    `json:"matrix,omitempty"`
+	Inputs  map[string]interface{} `json:"inputs,omitempty"`
+	Timeout string                 `json:"timeout,omitempty"`
 }
 
 // NewActRunner creates a new act runner
@@ -43,15 +87,47 @@ func NewActRunner(platform, containerImage string) *ActRunner {
 		platform:        platform,
 		containerImage:  containerImage,
 		reuseContainers: false,
+		images:          DefaultRunnerImages(containerImage),
+		hostLock:        newDockerHostLock(),
+		concurrency:     NewConcurrencyGroups(),
 	}
 }
 
-// getContainerOptions returns container options including volume mounts
-func (ar *ActRunner) getContainerOptions() string {
+// containerArchitecture returns the platform containers run on for an execution
+func (ar *ActRunner) containerArchitecture(execCtx *ExecutionContext) string {
+	if execCtx.Platform != "" {
+		return execCtx.Platform
+	}
+	return ar.platform
+}
+
+// SetHistoryStore makes the runner record every execution in a history store
+func (ar *ActRunner) SetHistoryStore(history *HistoryStore) {
+	ar.history = history
+}
+
+// SetRunnerImages adds runs-on label mappings on top of the default image
+func (ar *ActRunner) SetRunnerImages(images RunnerImages) {
+	ar.images = DefaultRunnerImages(ar.containerImage).Merge(images)
+}
+
+// getContainerOptions returns container options including labels and volume mounts
+func (ar *ActRunner) getContainerOptions(execID string, ctx *ExecutionContext) string {
+	// Label containers so they can be found and removed on timeout or cancellation
+	options := []string{
+		fmt.Sprintf("--label %s=%s", LabelExecutionID, execID),
+	}
+	if ctx.Metadata.Space != "" {
+		options = append(options, fmt.Sprintf("--label %s=%s", LabelSpace, ctx.Metadata.Space))
+	}
+	if ctx.Metadata.Unit != "" {
+		options = append(options, fmt.Sprintf("--label %s=%s", LabelUnit, ctx.Metadata.Unit))
+	}
+
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		// If we can't get home dir, return empty options
-		return ""
+		// If we can't get home dir, skip the volume mounts
+		return strings.Join(options, " ")
 	}
 
 	// Mount ~/.confighub to container's root and runner home directories
@@ -62,11 +138,14 @@ func (ar *ActRunner) getContainerOptions() string {
 	// Check if .confighub directory exists
 	if _, err := os.Stat(confighubPath); os.IsNotExist(err) {
 		// No .confighub directory, no need to mount
-		return ""
+		return strings.Join(options, " ")
 	}
 
 	// Mount to both /root/.confighub and /home/runner/.confighub for compatibility
-	return fmt.Sprintf("-v %s:/root/.confighub:ro -v %s:/home/runner/.confighub:ro", confighubPath, confighubPath)
+	options = append(options,
+		fmt.Sprintf("-v %s:/root/.confighub:ro", confighubPath),
+		fmt.Sprintf("-v %s:/home/runner/.confighub:ro", confighubPath))
+	return strings.Join(options, " ")
 }
 
 // Validate checks if a GitHub Actions workflow is valid
@@ -88,8 +167,12 @@ func (ar *ActRunner) Validate(workflowData []byte) error {
 		return fmt.Errorf("parse workflow: %w", err)
 	}
 
-	// Try to get plan for workflow_dispatch event
-	_, err = planner.PlanEvent("workflow_dispatch")
+	// Try to get a plan for the event the workflow would run with
+	eventName, err := selectEvent(planner, "")
+	if err != nil {
+		return fmt.Errorf("select event: %w", err)
+	}
+	_, err = planner.PlanEvent(eventName)
 	if err != nil {
 		return fmt.Errorf("plan workflow: %w", err)
 	}
@@ -103,18 +186,27 @@ type ExecutionResult struct {
 	StartTime  time.Time
 	EndTime    time.Time
 	Duration   time.Duration
+	Status     string
 	ExitCode   int
+	Reason     string // Why a cancelled execution was cancelled, if known
 	Logs       []string
+	LogEntries []LogEntry
+	Jobs       []JobResult
+	Outputs    map[string]string // Workflow outputs declared under on.workflow_call
+	Plan       *ExecutionPlan    // Set for dry runs only
+	Runtime    *RuntimeInfo      // Not set for dry runs
 	Artifacts  []string
 	OutputData []byte
 }
 
-// Execute runs a GitHub Actions workflow
-func (ar *ActRunner) Execute(ctx *ExecutionContext) (*ExecutionResult, error) {
+// Execute runs a GitHub Actions workflow. The execution is stopped when ctx is
+// cancelled or when the execution timeout expires, whichever happens first.
+func (ar *ActRunner) Execute(ctx context.Context, execCtx *ExecutionContext) (*ExecutionResult, error) {
 	execID := uuid.New().String()
 	result := &ExecutionResult{
 		ID:        execID,
 		StartTime: time.Now(),
+		Status:    ExecutionStatusSuccess,
 		Logs:      []string{},
 		Artifacts: []string{},
 	}
@@ -124,62 +216,132 @@ func (ar *ActRunner) Execute(ctx *ExecutionContext) (*ExecutionResult, error) {
 		if r := recover(); r != nil {
 			result.EndTime = time.Now()
 			result.Duration = result.EndTime.Sub(result.StartTime)
+			result.Status = ExecutionStatusFailure
 			result.ExitCode = -1
 			result.Logs = append(result.Logs, fmt.Sprintf("PANIC: %v", r))
 			log.Printf("PANIC in Execute: %v", r)
 		}
 	}()
 
+	// Read workflow file
+	workflowPath, err := resolveWorkflowPath(execCtx)
+	if err != nil {
+		return nil, err
+	}
+	planner, err := model.NewWorkflowPlanner(workflowPath, false, false)
+	if err != nil {
+		return nil, fmt.Errorf("create workflow planner: %w", err)
+	}
+
+	// Pick the event to simulate
+	eventName, err := selectEvent(planner, execCtx.EventName)
+	if err != nil {
+		return nil, fmt.Errorf("select event: %w", err)
+	}
+
 	// Prepare event file
-	eventPath, err := ar.prepareEvent(ctx)
+	eventPath, event, err := ar.prepareEvent(execCtx, eventName, workflowPath)
 	if err != nil {
 		return nil, fmt.Errorf("prepare event: %w", err)
 	}
 
 	// Create act runner config
 	config := &runner.Config{
-		EventPath: eventPath,
-		EventName: "workflow_dispatch", // Default event
-		Platforms: map[string]string{
-			"ubuntu-latest": ar.containerImage,
-			"ubuntu-22.04":  ar.containerImage,
-			"ubuntu-20.04":  ar.containerImage,
-			"ubuntu-18.04":  ar.containerImage,
-		},
-		Secrets:            ctx.Secrets,
-		Env:                ctx.Environment,
-		Privileged:         false,
-		UsernsMode:         "auto",
-		ReuseContainers:    ar.reuseContainers,
-		BindWorkdir:        false,
-		Workdir:            ctx.Workspace.Root,
-		ArtifactServerPath: ctx.Workspace.OutputDir,
-		Actor:              "confighub",
-		InsecureSecrets:    false,
-		LogOutput:          true,
-		ContainerOptions:   ar.getContainerOptions(),
+		EventPath:             eventPath,
+		EventName:             eventName,
+		Secrets:               execCtx.Secrets,
+		Env:                   executionEnv(execCtx),
+		Privileged:            false,
+		UsernsMode:            "auto",
+		ReuseContainers:       ar.reuseContainers,
+		BindWorkdir:           false,
+		Workdir:               execCtx.Workspace.Root,
+		ArtifactServerPath:    execCtx.Workspace.OutputDir,
+		Actor:                 "confighub",
+		InsecureSecrets:       false,
+		LogOutput:             true,
+		ContainerOptions:      ar.getContainerOptions(execID, execCtx),
+		ContainerArchitecture: ar.containerArchitecture(execCtx),
+		Matrix:                matrixFilter(execCtx.Matrix),
+	}
+	if execCtx.Network != "" {
+		config.ContainerNetworkMode = container.NetworkMode(execCtx.Network)
 	}
 
-	// Create runner
-	actRunner, err := runner.New(config)
+	// Get the plan
+	plan, err := planner.PlanEvent(config.EventName)
 	if err != nil {
-		return nil, fmt.Errorf("create runner: %w", err)
+		return nil, fmt.Errorf("plan event: %w", err)
 	}
 
-	// Run the workflow
-	runnerCtx := context.Background()
+	// Narrow the plan to the selected jobs, pulling in the jobs they need
+	plan, err = selectJobs(plan, execCtx.Jobs)
+	if err != nil {
+		return nil, fmt.Errorf("select jobs: %w", err)
+	}
+	plan, err = skipJobs(plan, execCtx.SkipJobs)
+	if err != nil {
+		return nil, fmt.Errorf("skip jobs: %w", err)
+	}
+	if err := checkMatrixSelection(plan, config.Matrix); err != nil {
+		return nil, err
+	}
 
-	// Read workflow file
-	workflowPath := filepath.Join(ctx.Workspace.WorkflowDir, "workflow.yml")
-	planner, err := model.NewWorkflowPlanner(workflowPath, false, false)
+	// Map the runs-on labels of the planned jobs to images
+	config.Platforms, err = resolvePlatforms(plan, ar.images.Merge(execCtx.RunnerImages), config.Matrix)
 	if err != nil {
-		return nil, fmt.Errorf("create workflow planner: %w", err)
+		return nil, err
 	}
 
-	// Get the plan
-	plan, err := planner.PlanEvent(config.EventName)
+	// Dry run: report the plan without starting any containers
+	if execCtx.DryRun {
+		return ar.dryRun(ctx, execCtx, plan, config, workflowPath, result)
+	}
+
+	// The execution timeout also bounds the wait for concurrency groups
+	timeout := executionTimeout(execCtx)
+	ctx, cancelTimeout := context.WithTimeout(ctx, timeout)
+	defer cancelTimeout()
+
+	// Wait for, or cancel, other runs in the same concurrency groups.
+	// Verification runs only read and never take part.
+	ctx, cancelRun := context.WithCancelCause(ctx)
+	defer cancelRun(nil)
+	if !execCtx.ReadOnly {
+		release, err := ar.acquireConcurrency(ctx, cancelRun, execCtx, plan, workflowPath, eventName, event, result)
+		if errors.Is(err, ErrConcurrencyCancelled) || errors.Is(err, context.DeadlineExceeded) {
+			// Superseded or timed out while waiting: the run never started
+			result.EndTime = time.Now()
+			result.Duration = result.EndTime.Sub(result.StartTime)
+			recordOutcome(result, err, nil, timeout, nil)
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
+	// Check the container runtime before starting any job
+	runtime := NewContainerRuntime(execCtx.ContainerSocket)
+	result.Runtime, err = ar.CheckRuntime(ctx, execCtx.ContainerSocket)
 	if err != nil {
-		return nil, fmt.Errorf("plan event: %w", err)
+		return nil, err
+	}
+	if host := runtime.Host(); host != "" {
+		config.ContainerDaemonSocket = host
+	}
+
+	// act creates its container client from DOCKER_HOST
+	if err := ar.hostLock.acquire(ctx, runtime.Host()); err != nil {
+		return nil, err
+	}
+	defer ar.hostLock.release()
+
+	// Create runner
+	actRunner, err := runner.New(config)
+	if err != nil {
+		return nil, fmt.Errorf("create runner: %w", err)
 	}
 
 	// Create executor
@@ -187,91 +349,350 @@ func (ar *ActRunner) Execute(ctx *ExecutionContext) (*ExecutionResult, error) {
 		return nil
 	})
 
-	// Execute the plan
-	err = executor(runnerCtx)
+	// Collect job and step output through act's job loggers, and kill the job
+	// containers as soon as the run is stopped, so act does not keep waiting
+	// on a step that never finishes
+	collector := newLogCollector()
+	declared := declaredJobOutputs(plan)
+	stopped, err := runBounded(runner.WithJobLoggerFactory(ctx, collector), executor, timeout, func() {
+		ar.removeContainers(runtime, execID)
+	})
 
 	result.EndTime = time.Now()
 	result.Duration = result.EndTime.Sub(result.StartTime)
 
+	// Redact secrets before the logs leave the runner
+	detector := secretDetector(execCtx.Secrets)
+	result.LogEntries = detector.SanitizeEntries(collector.Entries())
+	for _, entry := range result.LogEntries {
+		result.Logs = append(result.Logs, entry.String())
+	}
+	recordOutcome(result, stopped, err, timeout, detector)
+
+	// Build per-job and per-step results from the plan and what act reported
+	result.Jobs = buildJobResults(plan, collector, declared, stopped != nil)
+	for i := range result.Jobs {
+		detector.SanitizeMap(result.Jobs[i].Outputs)
+		for j := range result.Jobs[i].Steps {
+			detector.SanitizeMap(result.Jobs[i].Steps[j].Outputs)
+		}
+	}
+
+	// Evaluate the workflow's own outputs from the job outputs
+	outputs, err := workflowOutputs(workflowPath, result.Jobs)
 	if err != nil {
-		result.ExitCode = 1
-		// Don't return error, capture it in result
-		result.Logs = append(result.Logs, fmt.Sprintf("ERROR: %v", err))
+		result.Logs = append(result.Logs, fmt.Sprintf("WARNING: workflow outputs: %v", err))
 	}
+	result.Outputs = detector.SanitizeMap(outputs)
 
 	// Collect artifacts
-	artifacts, _ := ctx.Workspace.GetArtifacts()
+	artifacts, _ := execCtx.Workspace.GetArtifacts()
 	result.Artifacts = artifacts
 
+	// Publish everything as the LiveState document
+	result.OutputData, err = NewLiveState(result).JSON()
+	if err != nil {
+		return nil, fmt.Errorf("encode live state: %w", err)
+	}
+
 	// Store execution record
+	inputs, _ := execCtx.EventPayload["inputs"].(map[string]interface{})
 	record := &ExecutionRecord{
-		ID:         execID,
-		UnitID:     ctx.Metadata.Unit,
-		StartTime:  result.StartTime,
-		EndTime:    result.EndTime,
-		ExitCode:   result.ExitCode,
-		ConfigData: ctx.ConfigData,
-		OutputData: result.OutputData,
-		Logs:       result.Logs,
-		Artifacts:  result.Artifacts,
-		Timestamp:  time.Now(),
-	}
-	ar.executions.Store(ctx.Metadata.Unit, record)
+		ID:        execID,
+		UnitID:    execCtx.Metadata.Unit,
+		Space:     execCtx.Metadata.Space,
+		Revision:  execCtx.Metadata.Revision,
+		StartTime: result.StartTime,
+		EndTime:   result.EndTime,
+		Status:    result.Status,
+		ExitCode:  result.ExitCode,
+		Inputs: ExecutionInputs{
+			Event:   eventName,
+			Jobs:    execCtx.Jobs,
+			Matrix:  execCtx.Matrix,
+			Inputs:  inputs,
+			Timeout: timeout.String(),
+		},
+		ConfigHash:       configHash(execCtx.ConfigData),
+		ConfigData:       execCtx.ConfigData,
+		OutputData:       result.OutputData,
+		Jobs:             result.Jobs,
+		Outputs:          result.Outputs,
+		Logs:             result.Logs,
+		Artifacts:        result.Artifacts,
+		ArtifactManifest: artifactManifest(execCtx.Workspace.OutputDir, result.Artifacts),
+		Timestamp:        time.Now(),
+	}
+	// Verification runs must not replace the Apply they are compared with
+	if ar.history != nil && !execCtx.ReadOnly {
+		// Artifacts are kept with the record for Finalize to archive
+		if err := ar.history.SaveArtifacts(record, execCtx.Workspace.OutputDir); err != nil {
+			log.Printf("Failed to store artifacts of execution %s: %v", execID, err)
+		}
+		if err := ar.history.Save(record); err != nil {
+			log.Printf("Failed to record execution %s: %v", execID, err)
+		}
+	}
 
 	return result, nil
 }
 
-// GetLastExecution retrieves the last execution for a unit
-func (ar *ActRunner) GetLastExecution(unitID string) (*ExecutionRecord, error) {
-	if val, ok := ar.executions.Load(unitID); ok {
-		return val.(*ExecutionRecord), nil
+// dryRun runs a plan in act's dry-run mode and reports it. act evaluates
+// job and step conditions, matrices and step names as in a real run but
+// pulls no images and starts no containers; jobs with service containers
+// still create their network. Nothing is recorded in the history.
+func (ar *ActRunner) dryRun(ctx context.Context, execCtx *ExecutionContext, plan *model.Plan, config *runner.Config, workflowPath string, result *ExecutionResult) (*ExecutionResult, error) {
+	actRunner, err := runner.New(config)
+	if err != nil {
+		return nil, fmt.Errorf("create runner: %w", err)
+	}
+
+	timeout := executionTimeout(execCtx)
+	collector := newLogCollector()
+	declared := declaredJobOutputs(plan)
+	ctx = common.WithDryrun(runner.WithJobLoggerFactory(ctx, collector), true)
+	stopped, runErr := runBounded(ctx, actRunner.NewPlanExecutor(plan), timeout, func() {})
+
+	result.EndTime = time.Now()
+	result.Duration = result.EndTime.Sub(result.StartTime)
+
+	detector := secretDetector(execCtx.Secrets)
+	result.LogEntries = detector.SanitizeEntries(collector.Entries())
+	result.Jobs = buildJobResults(plan, collector, declared, stopped != nil)
+	result.Plan, err = buildExecutionPlan(plan, config, workflowPath, execCtx, result.Jobs)
+	if err != nil {
+		return nil, fmt.Errorf("build execution plan: %w", err)
 	}
-	return nil, fmt.Errorf("no execution found for unit %s", unitID)
+	result.Logs = append(result.Logs, result.Plan.Summary()...)
+	recordOutcome(result, stopped, runErr, timeout, detector)
+
+	result.OutputData, err = NewLiveState(result).JSON()
+	if err != nil {
+		return nil, fmt.Errorf("encode live state: %w", err)
+	}
+	return result, nil
 }
 
-// prepareEvent creates the GitHub event JSON file
-func (ar *ActRunner) prepareEvent(ctx *ExecutionContext) (string, error) {
-	// Default event payload
-	event := map[string]interface{}{
-		"action": "workflow_dispatch",
-		"inputs": map[string]interface{}{
-			"space":    ctx.Metadata.Space,
-			"unit":     ctx.Metadata.Unit,
-			"revision": fmt.Sprintf("%d", ctx.Metadata.Revision),
-		},
-		"repository": map[string]interface{}{
-			"name":      ctx.Metadata.Unit,
-			"full_name": fmt.Sprintf("confighub/%s/%s", ctx.Metadata.Space, ctx.Metadata.Unit),
-			"owner": map[string]interface{}{
-				"login": "confighub",
-			},
-		},
-		"sender": map[string]interface{}{
-			"login": ctx.Metadata.Actor,
-			"type":  "User",
-		},
-		"ref": "refs/heads/main",
-		"sha": fmt.Sprintf("%040d", ctx.Metadata.Revision),
+// executionTimeout returns the timeout of an execution
+func executionTimeout(execCtx *ExecutionContext) time.Duration {
+	if execCtx.Timeout <= 0 {
+		return DefaultExecutionTimeout
+	}
+	return execCtx.Timeout
+}
+
+// runBounded runs an executor until it finishes, ctx is cancelled or the
+// timeout expires. stop is called as soon as the run is stopped early. It
+// returns why the run was stopped, nil if it was not, and the executor's
+// error.
+func runBounded(ctx context.Context, executor common.Executor, timeout time.Duration, stop func()) (stopped, err error) {
+	runnerCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan struct{})
+	watched := make(chan struct{})
+	go func() {
+		defer close(watched)
+		select {
+		case <-runnerCtx.Done():
+			stop()
+		case <-done:
+		}
+	}()
+
+	err = executor(runnerCtx)
+	close(done)
+	<-watched
+
+	if runnerCtx.Err() != nil {
+		return context.Cause(runnerCtx), err
+	}
+	return nil, err
+}
+
+// recordOutcome sets the status and exit code of a result from how its run
+// ended: timed out, cancelled, failed or succeeded
+func recordOutcome(result *ExecutionResult, stopped, err error, timeout time.Duration, detector *LeakDetector) {
+	switch {
+	case errors.Is(stopped, context.DeadlineExceeded):
+		result.Status = ExecutionStatusTimedOut
+		result.ExitCode = exitCodeTimedOut
+		result.Logs = append(result.Logs, fmt.Sprintf("ERROR: workflow timed out after %s", timeout))
+	case errors.Is(stopped, ErrConcurrencyCancelled):
+		result.Status = ExecutionStatusCancelled
+		result.ExitCode = exitCodeCancelled
+		result.Reason = stopped.Error()
+		result.Logs = append(result.Logs, fmt.Sprintf("ERROR: workflow %v", stopped))
+	case stopped != nil:
+		result.Status = ExecutionStatusCancelled
+		result.ExitCode = exitCodeCancelled
+		result.Logs = append(result.Logs, "ERROR: workflow execution cancelled")
+	case err != nil:
+		result.Status = ExecutionStatusFailure
+		result.ExitCode = 1
+		// Don't return error, capture it in result
+		result.Logs = append(result.Logs, detector.SanitizeLogs([]string{fmt.Sprintf("ERROR: %v", err)})...)
+	}
+}
+
+// acquireConcurrency evaluates the concurrency groups of a run and waits
+// until the run holds them. Groups are scoped to the unit, as GitHub scopes
+// them to the repository.
+func (ar *ActRunner) acquireConcurrency(ctx context.Context, cancel context.CancelCauseFunc, execCtx *ExecutionContext,
+	plan *model.Plan, workflowPath, eventName string, event map[string]interface{}, result *ExecutionResult) (func(), error) {
+	data, err := os.ReadFile(workflowPath)
+	if err != nil {
+		return nil, fmt.Errorf("read workflow: %w", err)
+	}
+
+	// github.workflow is the workflow name, or its path if it has none
+	workflowName := filepath.ToSlash(execCtx.WorkflowFile)
+	if root, _ := parseWorkflowNode(data); root != nil {
+		if _, name := mappingValue(root, "name"); name != nil && name.Kind == yaml.ScalarNode && name.Value != "" {
+			workflowName = name.Value
+		}
+	}
+
+	var jobs []string
+	for _, stage := range plan.Stages {
+		for _, run := range stage.Runs {
+			jobs = append(jobs, run.JobID)
+		}
+	}
+
+	env := concurrencyEnvironment(execCtx, eventName, workflowName, event)
+	groups, notes, err := workflowConcurrency(data, jobs, env)
+	if err != nil {
+		return nil, err
+	}
+	for _, note := range notes {
+		log.Printf("Unit %s: %s", execCtx.Metadata.Unit, note)
+		result.Logs = append(result.Logs, "WARNING: "+note)
+	}
+
+	scope := execCtx.Metadata.Space + "/" + execCtx.Metadata.Unit
+	for _, group := range groups {
+		log.Printf("Unit %s: joining concurrency group %s (cancel-in-progress: %t)", execCtx.Metadata.Unit, group.Name, group.CancelInProgress)
+	}
+	return ar.concurrency.Acquire(ctx, scope, groups, cancel)
+}
+
+// executionEnv returns the environment of a run; read-only runs are flagged
+func executionEnv(execCtx *ExecutionContext) map[string]string {
+	if !execCtx.ReadOnly {
+		return execCtx.Environment
+	}
+	env := make(map[string]string, len(execCtx.Environment)+1)
+	for k, v := range execCtx.Environment {
+		env[k] = v
+	}
+	env[EnvReadOnly] = "true"
+	return env
+}
+
+// resolveWorkflowPath returns the entrypoint workflow of an execution. Without
+// an explicit WorkflowFile, workflow.yml or the only workflow in the workspace is used.
+func resolveWorkflowPath(execCtx *ExecutionContext) (string, error) {
+	if execCtx.WorkflowFile != "" {
+		if err := validateRelativePath(execCtx.WorkflowFile); err != nil {
+			return "", fmt.Errorf("invalid workflow file: %w", err)
+		}
+		return filepath.Join(execCtx.Workspace.Root, filepath.FromSlash(execCtx.WorkflowFile)), nil
+	}
+
+	defaultPath := filepath.Join(execCtx.Workspace.WorkflowDir, "workflow.yml")
+	if _, err := os.Stat(defaultPath); err == nil {
+		return defaultPath, nil
+	}
+
+	var workflows []string
+	for _, pattern := range []string{"*.yml", "*.yaml"} {
+		matches, _ := filepath.Glob(filepath.Join(execCtx.Workspace.WorkflowDir, pattern))
+		workflows = append(workflows, matches...)
+	}
+	if len(workflows) != 1 {
+		return "", fmt.Errorf("found %d workflows in workspace, set WorkflowFile to choose one", len(workflows))
+	}
+	return workflows[0], nil
+}
+
+// CheckRuntime verifies that the container runtime behind a socket is
+// reachable. An empty socket checks DOCKER_HOST or the default Docker socket.
+func (ar *ActRunner) CheckRuntime(ctx context.Context, socket string) (*RuntimeInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, runtimeCheckTimeout)
+	defer cancel()
+
+	return NewContainerRuntime(socket).Info(ctx)
+}
+
+// removeContainers force-removes the containers of a stopped execution
+func (ar *ActRunner) removeContainers(runtime *ContainerRuntime, execID string) {
+	// The execution context is already done, so use a fresh one for cleanup
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	removed, err := runtime.RemoveContainers(ctx, LabelExecutionID, execID)
+	if err != nil {
+		log.Printf("Failed to remove containers for execution %s: %v", execID, err)
+		return
+	}
+	if removed > 0 {
+		log.Printf("Removed %d container(s) for stopped execution %s", removed, execID)
+	}
+}
+
+// GetLastExecution retrieves the last execution for a unit in a space
+func (ar *ActRunner) GetLastExecution(space, unitID string) (*ExecutionRecord, error) {
+	if ar.history == nil {
+		return nil, fmt.Errorf("no execution found for unit %s", unitID)
+	}
+	record, err := ar.history.Latest(space, unitID)
+	if err != nil {
+		return nil, fmt.Errorf("no execution found for unit %s: %w", unitID, err)
+	}
+	return record, nil
+}
+
+// History returns the runner's history store, or nil if it keeps none
+func (ar *ActRunner) History() *HistoryStore {
+	return ar.history
+}
+
+// prepareEvent creates the GitHub event JSON file for the selected event and
+// returns its path and payload
+func (ar *ActRunner) prepareEvent(ctx *ExecutionContext, eventName, workflowPath string) (string, map[string]interface{}, error) {
+	event := buildEventPayload(eventName, ctx.Metadata)
+
+	// Scheduled runs report the cron expression that fired
+	if eventName == EventSchedule {
+		cron, err := firstSchedule(workflowPath)
+		if err != nil {
+			return "", nil, err
+		}
+		event["schedule"] = cron
 	}
 
 	// Merge with custom event payload
-	for k, v := range ctx.EventPayload {
-		event[k] = v
+	mergeEventPayload(event, ctx.EventPayload)
+
+	// Verification runs are flagged where workflow expressions can see it
+	if ctx.ReadOnly {
+		event[EventFieldReadOnly] = true
 	}
 
 	data, err := json.MarshalIndent(event, "", "  ")
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
 	// Act expects event files in .github directory
 	githubDir := filepath.Join(ctx.Workspace.Root, ".github")
 	if err := os.MkdirAll(githubDir, 0755); err != nil {
-		return "", fmt.Errorf("create .github dir: %w", err)
+		return "", nil, fmt.Errorf("create .github dir: %w", err)
 	}
 
 	eventPath := filepath.Join(githubDir, "event.json")
-	return eventPath, os.WriteFile(eventPath, data, 0644)
+	return eventPath, event, os.WriteFile(eventPath, data, 0644)
 }
 
 // prepareSecrets creates the secrets file
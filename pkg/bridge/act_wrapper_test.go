@@ -0,0 +1,154 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTimeout(t *testing.T) {
+	tests := []struct {
+		value   interface{}
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: float64(90), want: 90 * time.Second},
+		{value: 1.5, want: 1500 * time.Millisecond},
+		{value: 30, want: 30 * time.Second},
+		{value: "45", want: 45 * time.Second},
+		{value: "15m", want: 15 * time.Minute},
+		{value: "1h30m", want: 90 * time.Minute},
+		{value: "0", wantErr: true},
+		{value: -5, wantErr: true},
+		{value: "-1m", wantErr: true},
+		{value: "soon", wantErr: true},
+		{value: true, wantErr: true},
+		{value: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v", tt.value), func(t *testing.T) {
+			got, err := parseTimeout(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// blockingExecutor runs until its context is done, like a step that never ends
+func blockingExecutor(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestRunBoundedTimeout(t *testing.T) {
+	var stops atomic.Int32
+	stopped, err := runBounded(context.Background(), blockingExecutor, 10*time.Millisecond, func() { stops.Add(1) })
+	assert.ErrorIs(t, stopped, context.DeadlineExceeded)
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), stops.Load(), "containers are removed once")
+
+	result := &ExecutionResult{Status: ExecutionStatusSuccess}
+	recordOutcome(result, stopped, err, 10*time.Millisecond, NewLeakDetector())
+	assert.Equal(t, ExecutionStatusTimedOut, result.Status)
+	assert.Equal(t, exitCodeTimedOut, result.ExitCode)
+	assert.Contains(t, result.Logs, "ERROR: workflow timed out after 10ms")
+}
+
+func TestRunBoundedCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	executor := func(ctx context.Context) error {
+		close(started)
+		return blockingExecutor(ctx)
+	}
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	var stops atomic.Int32
+	stopped, err := runBounded(ctx, executor, time.Hour, func() { stops.Add(1) })
+	assert.ErrorIs(t, stopped, context.Canceled)
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), stops.Load())
+
+	result := &ExecutionResult{Status: ExecutionStatusSuccess}
+	recordOutcome(result, stopped, err, time.Hour, NewLeakDetector())
+	assert.Equal(t, ExecutionStatusCancelled, result.Status)
+	assert.Equal(t, exitCodeCancelled, result.ExitCode)
+	assert.Empty(t, result.Reason)
+
+	// A newer run in the same concurrency group gives its reason
+	ctx, cancelCause := context.WithCancelCause(context.Background())
+	cancelCause(fmt.Errorf("group deploy: %w", ErrConcurrencyCancelled))
+	stopped, err = runBounded(ctx, blockingExecutor, time.Hour, func() {})
+	result = &ExecutionResult{Status: ExecutionStatusSuccess}
+	recordOutcome(result, stopped, err, time.Hour, NewLeakDetector())
+	assert.Equal(t, ExecutionStatusCancelled, result.Status)
+	assert.Equal(t, "group deploy: cancelled by a newer run", result.Reason)
+}
+
+func TestRunBoundedCompletion(t *testing.T) {
+	var stops atomic.Int32
+	stop := func() { stops.Add(1) }
+
+	stopped, err := runBounded(context.Background(), func(context.Context) error { return nil }, time.Hour, stop)
+	assert.NoError(t, stopped)
+	assert.NoError(t, err)
+	result := &ExecutionResult{Status: ExecutionStatusSuccess}
+	recordOutcome(result, stopped, err, time.Hour, NewLeakDetector())
+	assert.Equal(t, ExecutionStatusSuccess, result.Status)
+	assert.Zero(t, result.ExitCode)
+
+	// A failing job is a failure, with secrets redacted from the error
+	detector := NewLeakDetector()
+	detector.Track("TOKEN", "s3cr3t-value")
+	stopped, err = runBounded(context.Background(), func(context.Context) error {
+		return errors.New("login with s3cr3t-value failed")
+	}, time.Hour, stop)
+	assert.NoError(t, stopped)
+	result = &ExecutionResult{Status: ExecutionStatusSuccess}
+	recordOutcome(result, stopped, err, time.Hour, detector)
+	assert.Equal(t, ExecutionStatusFailure, result.Status)
+	assert.Equal(t, 1, result.ExitCode)
+	require.Len(t, result.Logs, 1)
+	assert.NotContains(t, result.Logs[0], "s3cr3t-value")
+
+	assert.Zero(t, stops.Load(), "runs that finish are not stopped")
+}
+
+func TestExecuteConcurrencyTimeout(t *testing.T) {
+	manager, err := NewWorkspaceManager(t.TempDir())
+	require.NoError(t, err)
+	ws, err := manager.CreateWorkspace("exec")
+	require.NoError(t, err)
+	workflow := []byte("on: push\nconcurrency: deploy\njobs:\n  deploy:\n    runs-on: ubuntu-latest\n    steps:\n      - run: ./deploy.sh\n")
+	require.NoError(t, ws.WriteWorkflow("deploy.yml", workflow))
+
+	// Another run of the unit holds the group for longer than the timeout
+	ar := NewActRunner("linux/amd64", "catthehacker/ubuntu:act-latest")
+	release, err := ar.concurrency.Acquire(context.Background(), "space/web", []ConcurrencyGroup{{Name: "deploy"}}, func(error) {})
+	require.NoError(t, err)
+	defer release()
+
+	result, err := ar.Execute(context.Background(), &ExecutionContext{
+		Workspace:  ws,
+		ConfigData: workflow,
+		Metadata:   ExecutionMetadata{Space: "space", Unit: "web", Revision: 1},
+		Timeout:    10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ExecutionStatusTimedOut, result.Status)
+	assert.Equal(t, exitCodeTimedOut, result.ExitCode)
+	assert.Contains(t, result.Logs, "ERROR: workflow timed out after 10ms")
+}
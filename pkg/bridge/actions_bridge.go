@@ -2,10 +2,16 @@ package bridge
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/confighub/sdk/bridge-worker/api"
@@ -14,6 +20,9 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// AnnotationTimeout sets the execution timeout of a unit (seconds or a duration such as "15m")
+const AnnotationTimeout = "actions.confighub.com/timeout"
+
 // ActionsBridge implements the ConfigHub BridgeWorker interface for GitHub Actions
 type ActionsBridge struct {
 	workspaceManager   *WorkspaceManager
@@ -23,34 +32,166 @@ type ActionsBridge struct {
 	baseDir            string
 	executionSemaphore chan struct{} // Limit concurrent executions
 	maxConcurrent      int
+	archive            Archive
+	unitLocks          *unitLocks // Keep Destroy from racing other operations of a unit
+	importRoot         string     // Directory repo_path imports are confined to
+	keptWorkspaceTTL   time.Duration
+	approvers          Approvers // Whose signatures units requiring approvals accept
 	logger             *Logger
 }
 
+// BridgeConfig configures an ActionsBridge
+type BridgeConfig struct {
+	BaseDir          string
+	DefaultImage     string       // Image for ubuntu-* labels without a mapping
+	Platform         string       // Container architecture, e.g. linux/amd64
+	RunnerImages     RunnerImages // Additional runs-on label mappings
+	RunnerImagesFile string       // YAML or JSON file with runs-on label mappings
+	MaxConcurrent    int
+	HistoryDir       string            // Defaults to <BaseDir>/history
+	HistoryRetention *HistoryRetention // Nil uses the default retention
+	Archive          Archive           // Where Finalize archives executions; defaults to <BaseDir>/archive
+	LintConfigFile   string            // YAML file turning lint rules on or off and adding custom rules
+	LintRules        []Rule            // Additional lint rules
+	ImportRoot       string            // Directory Import may read repo_path checkouts from; empty disables repo_path
+	KeptWorkspaceTTL time.Duration     // How long a failed run's workspace is kept; defaults to DefaultKeptWorkspaceTTL
+	Approvers        Approvers         // Approvers whose signatures units requiring approvals accept
+	ApproversFile    string            // YAML or JSON file mapping approver names to public keys
+}
+
+// Defaults used for unset BridgeConfig fields
+const (
+	DefaultRunnerImage   = "catthehacker/ubuntu:act-22.04"
+	DefaultPlatform      = "linux/amd64"
+	DefaultMaxConcurrent = 5
+	// DefaultKeptWorkspaceTTL is how long workspaces of failed runs are kept
+	DefaultKeptWorkspaceTTL = 24 * time.Hour
+)
+
 // NewActionsBridge creates a new GitHub Actions bridge
 func NewActionsBridge(baseDir string) (*ActionsBridge, error) {
-	workspaceManager, err := NewWorkspaceManager(baseDir)
+	return NewActionsBridgeWithConfig(BridgeConfig{BaseDir: baseDir})
+}
+
+// NewActionsBridgeWithConfig creates a new GitHub Actions bridge from a configuration
+func NewActionsBridgeWithConfig(config BridgeConfig) (*ActionsBridge, error) {
+	if config.DefaultImage == "" {
+		config.DefaultImage = DefaultRunnerImage
+	}
+	if config.Platform == "" {
+		config.Platform = DefaultPlatform
+	}
+	if config.MaxConcurrent < 1 {
+		config.MaxConcurrent = DefaultMaxConcurrent
+	}
+	if config.KeptWorkspaceTTL <= 0 {
+		config.KeptWorkspaceTTL = DefaultKeptWorkspaceTTL
+	}
+
+	workspaceManager, err := NewWorkspaceManager(config.BaseDir)
 	if err != nil {
 		return nil, fmt.Errorf("create workspace manager: %w", err)
 	}
+	// Workspaces kept before a restart are no longer tracked
+	if err := workspaceManager.CleanupOldWorkspaces(config.KeptWorkspaceTTL); err != nil {
+		log.Printf("Failed to clean up old workspaces: %v", err)
+	}
 
 	secretHandler, err := NewSecretHandler()
 	if err != nil {
 		return nil, fmt.Errorf("create secret handler: %w", err)
 	}
 
-	maxConcurrent := 5 // Default, can be made configurable
+	// File mappings first, explicit mappings win
+	images := RunnerImages{}
+	if config.RunnerImagesFile != "" {
+		if images, err = LoadRunnerImages(config.RunnerImagesFile); err != nil {
+			return nil, err
+		}
+	}
+	if config.RunnerImages != nil {
+		validated, err := config.RunnerImages.validate()
+		if err != nil {
+			return nil, err
+		}
+		images = images.Merge(validated)
+	}
+
+	// File approvers first, explicit approvers win
+	approvers := Approvers{}
+	if config.ApproversFile != "" {
+		if approvers, err = LoadApprovers(config.ApproversFile); err != nil {
+			return nil, err
+		}
+	}
+	for name, key := range config.Approvers {
+		approvers[name] = key
+	}
+
+	// Execution history survives restarts so Refresh can compare against it
+	if config.HistoryDir == "" {
+		config.HistoryDir = filepath.Join(config.BaseDir, "history")
+	}
+	retention := HistoryRetention{
+		MaxPerUnit: DefaultHistoryMaxPerUnit,
+		MaxAge:     DefaultHistoryMaxAge,
+	}
+	if config.HistoryRetention != nil {
+		retention = *config.HistoryRetention
+	}
+	history, err := NewHistoryStore(config.HistoryDir, retention)
+	if err != nil {
+		return nil, fmt.Errorf("create history store: %w", err)
+	}
+	if removed, err := history.Prune(); err != nil {
+		log.Printf("Failed to prune execution history: %v", err)
+	} else if removed > 0 {
+		log.Printf("Pruned %d expired execution record(s)", removed)
+	}
+
+	if config.Archive == nil {
+		archive, err := NewLocalArchive(filepath.Join(config.BaseDir, "archive"))
+		if err != nil {
+			return nil, fmt.Errorf("create archive: %w", err)
+		}
+		config.Archive = archive
+	}
+
+	compatChecker := NewCompatibilityChecker()
+	if err := compatChecker.Linter().Register(config.LintRules...); err != nil {
+		return nil, fmt.Errorf("register lint rules: %w", err)
+	}
+	if config.LintConfigFile != "" {
+		lintConfig, err := LoadLintConfig(config.LintConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := compatChecker.Linter().Configure(lintConfig); err != nil {
+			return nil, fmt.Errorf("configure lint rules: %w", err)
+		}
+	}
+
+	actRunner := NewActRunner(config.Platform, config.DefaultImage)
+	actRunner.SetRunnerImages(images)
+	actRunner.SetHistoryStore(history)
 
 	logger := NewLogger("ActionsBridge")
-	logger.Info("Initializing GitHub Actions Bridge: baseDir=%s maxConcurrent=%d", baseDir, maxConcurrent)
+	logger.Info("Initializing GitHub Actions Bridge: baseDir=%s maxConcurrent=%d image=%s platform=%s",
+		config.BaseDir, config.MaxConcurrent, config.DefaultImage, config.Platform)
 
 	return &ActionsBridge{
 		workspaceManager:   workspaceManager,
-		actRunner:          NewActRunner("linux/amd64", "catthehacker/ubuntu:act-22.04"), // Use specific version
-		compatChecker:      NewCompatibilityChecker(),
+		actRunner:          actRunner,
+		compatChecker:      compatChecker,
 		secretHandler:      secretHandler,
-		baseDir:            baseDir,
-		executionSemaphore: make(chan struct{}, maxConcurrent),
-		maxConcurrent:      maxConcurrent,
+		baseDir:            config.BaseDir,
+		executionSemaphore: make(chan struct{}, config.MaxConcurrent),
+		maxConcurrent:      config.MaxConcurrent,
+		archive:            config.Archive,
+		unitLocks:          newUnitLocks(),
+		importRoot:         config.ImportRoot,
+		keptWorkspaceTTL:   config.KeptWorkspaceTTL,
+		approvers:          approvers,
 		logger:             logger,
 	}, nil
 }
@@ -94,9 +235,18 @@ func (b *ActionsBridge) Apply(ctx api.BridgeWorkerContext, payload api.BridgeWor
 	}()
 
 	// Log workflow execution start
-	b.logger.Info("Starting workflow execution: space=%s unit=%s revision=%d", 
+	b.logger.Info("Starting workflow execution: space=%s unit=%s revision=%d",
 		payload.SpaceID, payload.UnitSlug, payload.RevisionNum)
 
+	// Destroy waits until the run is done with the unit's resources. Like
+	// Refresh and Destroy, take the unit before an execution slot, so no run
+	// holds a slot while it waits for the unit.
+	unlock, err := b.unitLocks.acquire(ctx.Context(), unitKey(payload), false)
+	if err != nil {
+		return b.sendError(ctx, payload, "Unit is being destroyed", err, time.Now())
+	}
+	defer unlock()
+
 	// Acquire execution slot with context awareness
 	select {
 	case b.executionSemaphore <- struct{}{}:
@@ -112,6 +262,38 @@ func (b *ActionsBridge) Apply(ctx api.BridgeWorkerContext, payload api.BridgeWor
 		return b.sendError(ctx, payload, "Invalid payload", err, startTime)
 	}
 
+	// Parse target parameters
+	targetParams, err := b.parseTargetParams(payload.TargetParams)
+	if err != nil {
+		return b.sendError(ctx, payload, "Failed to parse target parameters", err, startTime)
+	}
+
+	// Parse extra parameters (secrets and configs)
+	extraParams, err := b.parseExtraParams(payload.ExtraParams)
+	if err != nil {
+		return b.sendError(ctx, payload, "Failed to parse extra parameters", err, startTime)
+	}
+
+	// Execution settings of the unit, validated with the payload
+	options, err := ParseUnitOptions(unitAnnotations(payload.Data))
+	if err != nil {
+		return b.sendError(ctx, payload, "Invalid annotation", err, startTime)
+	}
+
+	// Units requiring approvals run only with enough signatures of trusted approvers
+	if err := b.approvers.Check(options.RequiredApprovals, payloadApprovalMessage(api.ActionApply, payload), extraParams.Approvals); err != nil {
+		return b.sendError(ctx, payload, "Approval required", err, startTime)
+	}
+
+	// Make sure the target's container runtime is up before accepting the work
+	if !targetParams.DryRun {
+		runtime, err := b.actRunner.CheckRuntime(ctx.Context(), targetParams.Socket)
+		if err != nil {
+			return b.sendError(ctx, payload, "Container runtime unavailable", err, startTime)
+		}
+		b.logger.Debug("Using %s %s at %s for unit=%s", runtime.Name, runtime.Version, runtime.Host, payload.UnitSlug)
+	}
+
 	// Send initial status
 	if err := ctx.SendStatus(&api.ActionResult{
 		UnitID:            payload.UnitID,
@@ -128,11 +310,18 @@ func (b *ActionsBridge) Apply(ctx api.BridgeWorkerContext, payload api.BridgeWor
 	}
 
 	// Create isolated workspace
-	ws, err := b.workspaceManager.CreateWorkspace(payload.QueuedOperationID.String())
+	ws, err := b.workspaceManager.CreateUnitWorkspace(payload.QueuedOperationID.String(), payload.SpaceID.String(), payload.UnitSlug)
 	if err != nil {
 		return b.sendError(ctx, payload, "Failed to create workspace", err, startTime)
 	}
+	keepWorkspace := false
 	defer func() {
+		// A kept workspace stays tracked, so Destroy removes it with the unit
+		if keepWorkspace {
+			b.workspaceManager.KeepWorkspace(ws, b.keptWorkspaceTTL)
+			b.logger.Warn("Keeping workspace %s of failed run of unit=%s at %s for %s", ws.ID, payload.UnitSlug, ws.Root, b.keptWorkspaceTTL)
+			return
+		}
 		if err := ws.SecureCleanup(); err != nil {
 			b.logger.Warn("Failed to cleanup workspace %s: %v", ws.ID, err)
 		} else {
@@ -141,32 +330,42 @@ func (b *ActionsBridge) Apply(ctx api.BridgeWorkerContext, payload api.BridgeWor
 		b.workspaceManager.RemoveWorkspace(ws.ID)
 	}()
 
-	// Strip first 4 lines from YAML (Kubernetes metadata)
-	strippedData := b.stripKubernetesMetadata(payload.Data)
+	// Load the unit files; a plain workflow unit is a bundle of one file
+	bundle, err := b.loadBundle(payload.Data)
+	if err != nil {
+		return b.sendError(ctx, payload, "Invalid workflow bundle", err, startTime)
+	}
 
 	// Validate workflow compatibility
-	warnings := b.compatChecker.CheckWorkflow(strippedData)
-	if len(warnings) > 0 {
-		b.sendWarnings(ctx, payload, warnings)
+	for _, name := range bundle.Workflows() {
+		warnings := b.compatChecker.CheckWorkflowWith([]byte(bundle.Files[name]), LintOptions{Secrets: secretNames(extraParams.Secrets), ReadFile: bundle.ReadFile})
+		if len(warnings) > 0 {
+			b.sendWarnings(ctx, payload, warnings)
+		}
 	}
 
-	// Parse workflow file
-	workflowName := "workflow.yml"
-	if err := ws.WriteWorkflow(workflowName, strippedData); err != nil {
+	// Lay out workflows, local actions and scripts in the workspace
+	if err := ws.WriteBundle(bundle); err != nil {
 		return b.sendError(ctx, payload, "Failed to write workflow", err, startTime)
 	}
 
-	// Parse target parameters
-	targetParams, err := b.parseTargetParams(payload.TargetParams)
-	if err != nil {
-		return b.sendError(ctx, payload, "Failed to parse target parameters", err, startTime)
+	// Unit annotations win over the target params
+	timeout := targetParams.Timeout
+	if options.Timeout > 0 {
+		timeout = options.Timeout
 	}
-
-	// Parse extra parameters (secrets and configs)
-	extraParams, err := b.parseExtraParams(payload.ExtraParams)
-	if err != nil {
-		return b.sendError(ctx, payload, "Failed to parse extra parameters", err, startTime)
+	eventName := targetParams.Event
+	if options.Event != "" {
+		eventName = options.Event
 	}
+	if err := ValidateEvent(eventName); err != nil {
+		return b.sendError(ctx, payload, "Invalid event", err, startTime)
+	}
+	jobs := targetParams.Jobs
+	if len(options.Jobs) > 0 {
+		jobs = options.Jobs
+	}
+	runnerImages := targetParams.RunnerImages.Merge(options.RunnerImages)
 
 	// Prepare secrets
 	if len(extraParams.Secrets) > 0 {
@@ -185,45 +384,84 @@ func (b *ActionsBridge) Apply(ctx api.BridgeWorkerContext, payload api.BridgeWor
 
 	// Prepare execution context
 	execCtx := &ExecutionContext{
-		Workspace:  ws,
-		ConfigData: payload.Data,
+		Workspace:    ws,
+		ConfigData:   payload.Data,
+		WorkflowFile: bundle.Entrypoint,
 		Metadata: ExecutionMetadata{
 			Space:    payload.SpaceID.String(),
 			Unit:     payload.UnitSlug,
 			Revision: int(payload.RevisionNum),
 			Actor:    "confighub",
 		},
-		Secrets:     extraParams.Secrets,
-		Environment: extraParams.Environment,
-		DryRun:      targetParams.DryRun,
+		Secrets:         extraParams.Secrets,
+		Environment:     extraParams.Environment,
+		EventName:       eventName,
+		Jobs:            jobs,
+		SkipJobs:        skippedLifecycleJobs(payload.Data, jobs),
+		Matrix:          targetParams.Matrix,
+		RunnerImages:    runnerImages,
+		ContainerSocket: targetParams.Socket,
+		Platform:        targetParams.Platform,
+		Network:         options.Network,
+		DryRun:          targetParams.DryRun,
+		Timeout:         timeout,
 	}
 
 	// Execute workflow
-	b.logger.Debug("Executing workflow for unit=%s", payload.UnitSlug)
-	result, err := b.actRunner.Execute(execCtx)
+	b.logger.Debug("Executing workflow for unit=%s timeout=%s", payload.UnitSlug, timeout)
+	result, err := b.actRunner.Execute(ctx.Context(), execCtx)
 	if err != nil {
+		keepWorkspace = options.KeepWorkspaceOnFailure
 		b.logger.Error("Workflow execution failed: unit=%s error=%v", payload.UnitSlug, err)
 		return b.sendError(ctx, payload, "Workflow execution failed", err, startTime)
 	}
+	keepWorkspace = options.KeepWorkspaceOnFailure && result.Status != ExecutionStatusSuccess
 
 	// Log execution result
-	b.logger.WorkflowExecutionLog(result.ID, payload.UnitSlug, "success", result.Duration.String())
+	b.logger.WorkflowExecutionLog(result.ID, payload.UnitSlug, result.Status, result.Duration.String())
 
 	// Sanitize logs
 	result.Logs = b.secretHandler.SanitizeLogs(result.Logs)
+	result.LogEntries = b.secretHandler.SanitizeEntries(result.LogEntries)
+
+	// Publish the structured LiveState document
+	outputJSON, err := NewLiveState(result).JSON()
+	if err != nil {
+		return b.sendError(ctx, payload, "Failed to encode live state", err, startTime)
+	}
 
-	// Prepare output data
-	outputData := map[string]interface{}{
-		"execution_id": result.ID,
-		"duration":     result.Duration.String(),
-		"exit_code":    result.ExitCode,
-		"artifacts":    result.Artifacts,
-		"logs":         result.Logs,
+	// Map the execution status to the action result
+	status := api.ActionStatusCompleted
+	actionResult := api.ActionResultApplyCompleted
+	message := fmt.Sprintf("Workflow executed successfully in %s", result.Duration)
+	switch result.Status {
+	case ExecutionStatusSuccess:
+		if result.Plan != nil {
+			message = fmt.Sprintf("Dry run: %d job(s) in %d stage(s) would run for event %s",
+				result.Plan.JobCount(), len(result.Plan.Stages), result.Plan.Event)
+		}
+	case ExecutionStatusTimedOut:
+		status, actionResult = api.ActionStatusFailed, api.ActionResultApplyFailed
+		message = fmt.Sprintf("Workflow timed out after %s", timeout)
+	case ExecutionStatusCancelled:
+		status, actionResult = api.ActionStatusFailed, api.ActionResultApplyFailed
+		message = fmt.Sprintf("Workflow cancelled after %s", result.Duration)
+		if result.Reason != "" {
+			message = fmt.Sprintf("Workflow %s after %s", result.Reason, result.Duration)
+		}
+	case ExecutionStatusFailure:
+		status, actionResult = api.ActionStatusFailed, api.ActionResultApplyFailed
+		message = fmt.Sprintf("Workflow failed with exit code %d after %s", result.ExitCode, result.Duration)
 	}
 
-	outputJSON, _ := json.Marshal(outputData)
+	if result.Runtime != nil {
+		message = fmt.Sprintf("%s (%s %s)", message, result.Runtime.Name, result.Runtime.Version)
+	}
+	if keepWorkspace {
+		message = fmt.Sprintf("%s; workspace kept at %s for %s", message, ws.Root, b.keptWorkspaceTTL)
+	}
 
-	// Send success status
+	// Send final status
 	terminatedAt := time.Now()
 	return ctx.SendStatus(&api.ActionResult{
 		UnitID:            payload.UnitID,
@@ -232,9 +470,9 @@ func (b *ActionsBridge) Apply(ctx api.BridgeWorkerContext, payload api.BridgeWor
 		ActionResultBaseMeta: api.ActionResultBaseMeta{
 			RevisionNum:  payload.RevisionNum,
 			Action:       api.ActionApply,
-			Result:       api.ActionResultApplyCompleted,
-			Status:       api.ActionStatusCompleted,
-			Message:      fmt.Sprintf("Workflow executed successfully in %s", result.Duration),
+			Result:       actionResult,
+			Status:       status,
+			Message:      message,
 			StartedAt:    startTime,
 			TerminatedAt: &terminatedAt,
 		},
@@ -248,7 +486,7 @@ func (b *ActionsBridge) Refresh(ctx api.BridgeWorkerContext, payload api.BridgeW
 	startTime := time.Now()
 
 	// Get last execution state
-	lastExec, err := b.actRunner.GetLastExecution(payload.UnitSlug)
+	lastExec, err := b.actRunner.GetLastExecution(payload.SpaceID.String(), payload.UnitSlug)
 	if err != nil {
 		terminatedAt := time.Now()
 		return ctx.SendStatus(&api.ActionResult{
@@ -267,15 +505,39 @@ func (b *ActionsBridge) Refresh(ctx api.BridgeWorkerContext, payload api.BridgeW
 		})
 	}
 
-	// Compare with current configuration
-	drift := !bytes.Equal(lastExec.ConfigData, payload.Data)
-
+	// Compare the workflows semantically with what was last applied
 	result := api.ActionResultRefreshAndNoDrift
 	message := fmt.Sprintf("Last execution: %s (no drift detected)", lastExec.Timestamp.Format(time.RFC3339))
 
-	if drift {
+	diff, err := b.diffConfig(lastExec.ConfigData, payload.Data)
+	if err != nil {
+		b.logger.Warn("Semantic comparison failed for %s, comparing raw config: %v", payload.UnitSlug, err)
+		if !bytes.Equal(lastExec.ConfigData, payload.Data) {
+			result = api.ActionResultRefreshAndDrifted
+			message = fmt.Sprintf("Configuration drift detected since %s", lastExec.Timestamp.Format(time.RFC3339))
+		}
+	} else if diff.HasChanges() {
 		result = api.ActionResultRefreshAndDrifted
-		message = fmt.Sprintf("Configuration drift detected since %s", lastExec.Timestamp.Format(time.RFC3339))
+		message = fmt.Sprintf("Configuration drift detected since %s: %s", lastExec.Timestamp.Format(time.RFC3339), diff.Summary())
+	}
+
+	// Run the unit's verification job, if it has one, against the live state
+	verification, err := b.verifyLiveState(ctx, payload, lastExec)
+	if err != nil {
+		return b.sendActionError(ctx, payload, api.ActionRefresh, api.ActionResultRefreshFailed, "Live state verification failed", err, startTime)
+	}
+	if verification != nil && verification.Drifted() {
+		result = api.ActionResultRefreshAndDrifted
+		if diff != nil && diff.HasChanges() {
+			message = fmt.Sprintf("%s; live state drifted: %s", message, verification.Summary())
+		} else {
+			message = fmt.Sprintf("Live state drift detected since %s: %s", lastExec.Timestamp.Format(time.RFC3339), verification.Summary())
+		}
+	}
+
+	liveState, err := refreshLiveState(lastExec, diff, verification)
+	if err != nil {
+		return b.sendActionError(ctx, payload, api.ActionRefresh, api.ActionResultRefreshFailed, "Failed to build live state", err, startTime)
 	}
 
 	terminatedAt := time.Now()
@@ -292,16 +554,228 @@ func (b *ActionsBridge) Refresh(ctx api.BridgeWorkerContext, payload api.BridgeW
 			StartedAt:    startTime,
 			TerminatedAt: &terminatedAt,
 		},
-		LiveState: lastExec.OutputData,
+		LiveState: liveState,
+	})
+}
+
+// diffConfig compares the workflows of two unit versions, ignoring the
+// ConfigHub header and formatting
+func (b *ActionsBridge) diffConfig(before, after []byte) (*WorkflowDiff, error) {
+	oldBundle, err := b.loadBundle(before)
+	if err != nil {
+		return nil, fmt.Errorf("load applied config: %w", err)
+	}
+	newBundle, err := b.loadBundle(after)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	return DiffBundles(oldBundle, newBundle)
+}
+
+// verifyLiveState runs the verification job of the last applied config in
+// read-only mode and compares its outputs with those of the last Apply. It
+// returns nil when the unit declares no verification.
+func (b *ActionsBridge) verifyLiveState(ctx api.BridgeWorkerContext, payload api.BridgeWorkerPayload, lastExec *ExecutionRecord) (*VerificationResult, error) {
+	bundle, err := b.loadBundle(lastExec.ConfigData)
+	if err != nil {
+		return nil, fmt.Errorf("load applied config: %w", err)
+	}
+	run, err := findLifecycleRun(bundle, unitAnnotations(lastExec.ConfigData), EventConfigHubRefresh)
+	if err != nil || run == nil {
+		return nil, err
+	}
+
+	unlock, err := b.unitLocks.acquire(ctx.Context(), unitKey(payload), false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	b.logger.Debug("Verifying live state of unit=%s with %s", payload.UnitSlug, run.Workflow)
+	result, err := b.runLifecycle(ctx, payload, lastExec.ConfigData, lastExec.Revision, bundle, run, true)
+	if err != nil {
+		return nil, err
+	}
+	if result.Status != ExecutionStatusSuccess {
+		return nil, fmt.Errorf("verification run %s ended with status %s (exit code %d)", result.ID, result.Status, result.ExitCode)
+	}
+
+	observed := observedOutputs(result, run.Jobs)
+	return &VerificationResult{
+		ExecutionID: result.ID,
+		Workflow:    run.Workflow,
+		Event:       run.EventName,
+		Jobs:        run.Jobs,
+		Status:      result.Status,
+		Outputs:     observed,
+		Mismatches:  compareOutputs(appliedOutputs(lastExec), observed),
+	}, nil
+}
+
+// runLifecycle executes a refresh or teardown run of a unit's config in a
+// fresh workspace, with the secrets, configs and target of the payload. The
+// caller holds the unit's lock.
+func (b *ActionsBridge) runLifecycle(ctx api.BridgeWorkerContext, payload api.BridgeWorkerPayload,
+	configData []byte, revision int, bundle *Bundle, run *LifecycleRun, readOnly bool) (*ExecutionResult, error) {
+
+	targetParams, err := b.parseTargetParams(payload.TargetParams)
+	if err != nil {
+		return nil, fmt.Errorf("parse target parameters: %w", err)
+	}
+	extraParams, err := b.parseExtraParams(payload.ExtraParams)
+	if err != nil {
+		return nil, fmt.Errorf("parse extra parameters: %w", err)
+	}
+
+	// Lifecycle runs start containers like Apply does
+	select {
+	case b.executionSemaphore <- struct{}{}:
+		defer func() { <-b.executionSemaphore }()
+	case <-ctx.Context().Done():
+		return nil, fmt.Errorf("context cancelled while waiting for execution slot")
+	}
+
+	ws, err := b.workspaceManager.CreateUnitWorkspace(payload.QueuedOperationID.String(), payload.SpaceID.String(), payload.UnitSlug)
+	if err != nil {
+		return nil, fmt.Errorf("create workspace: %w", err)
+	}
+	defer func() {
+		if err := ws.SecureCleanup(); err != nil {
+			b.logger.Warn("Failed to cleanup workspace %s: %v", ws.ID, err)
+		}
+		b.workspaceManager.RemoveWorkspace(ws.ID)
+	}()
+
+	if err := ws.WriteBundle(bundle); err != nil {
+		return nil, fmt.Errorf("write workflow: %w", err)
+	}
+	if len(extraParams.Secrets) > 0 {
+		if _, err := b.secretHandler.PrepareSecrets(ws, extraParams.Secrets); err != nil {
+			return nil, fmt.Errorf("prepare secrets: %w", err)
+		}
+	}
+	if len(extraParams.Configs) > 0 {
+		if err := NewConfigInjector(ws).InjectConfigs(extraParams.Configs); err != nil {
+			return nil, fmt.Errorf("inject configurations: %w", err)
+		}
+	}
+
+	// Runner images, network and timeout of the unit apply here as well
+	options, err := ParseUnitOptions(unitAnnotations(configData))
+	if err != nil {
+		return nil, fmt.Errorf("invalid annotation: %w", err)
+	}
+	timeout := targetParams.Timeout
+	if options.Timeout > 0 {
+		timeout = options.Timeout
+	}
+
+	return b.actRunner.Execute(ctx.Context(), &ExecutionContext{
+		Workspace:    ws,
+		ConfigData:   configData,
+		WorkflowFile: run.Workflow,
+		Metadata: ExecutionMetadata{
+			Space:    payload.SpaceID.String(),
+			Unit:     payload.UnitSlug,
+			Revision: revision,
+			Actor:    "confighub",
+		},
+		Secrets:         extraParams.Secrets,
+		Environment:     extraParams.Environment,
+		EventName:       run.EventName,
+		Jobs:            run.Jobs,
+		RunnerImages:    targetParams.RunnerImages.Merge(options.RunnerImages),
+		ContainerSocket: targetParams.Socket,
+		Platform:        targetParams.Platform,
+		Network:         options.Network,
+		Timeout:         timeout,
+		ReadOnly:        readOnly,
 	})
 }
 
-// Import discovers existing GitHub Actions workflows
+// refreshLiveState returns the LiveState of the last execution with the
+// drift and verification results attached
+func refreshLiveState(lastExec *ExecutionRecord, diff *WorkflowDiff, verification *VerificationResult) ([]byte, error) {
+	state, err := recordLiveState(lastExec)
+	if err != nil {
+		return nil, err
+	}
+	state.Drift = diff
+	state.Verification = verification
+	return state.JSON()
+}
+
+// Import discovers existing GitHub Actions workflows. With the repo_path
+// target parameter it reads the unit's workflow from a repository checkout
+// below the bridge's import root; otherwise it returns what was last applied
+// for the unit.
 func (b *ActionsBridge) Import(ctx api.BridgeWorkerContext, payload api.BridgeWorkerPayload) error {
 	startTime := time.Now()
-	terminatedAt := time.Now()
 
-	// For local act, there's nothing to import
+	targetParams, err := b.parseTargetParams(payload.TargetParams)
+	if err != nil {
+		return b.sendActionError(ctx, payload, api.ActionImport, api.ActionResultImportFailed, "Failed to parse target parameters", err, startTime)
+	}
+
+	// Import one workflow of a repository checkout as the unit
+	if targetParams.RepoPath != "" {
+		repoDir, err := b.importRepoDir(targetParams.RepoPath)
+		if err != nil {
+			return b.sendActionError(ctx, payload, api.ActionImport, api.ActionResultImportFailed, "Invalid repo_path", err, startTime)
+		}
+		units, err := ImportRepository(repoDir)
+		if err != nil {
+			return b.sendActionError(ctx, payload, api.ActionImport, api.ActionResultImportFailed, "Failed to import repository", err, startTime)
+		}
+		workflow := targetParams.Workflow
+		if workflow == "" {
+			workflow = payload.UnitSlug
+		}
+		unit, err := selectImportedUnit(units, workflow)
+		if err != nil {
+			return b.sendActionError(ctx, payload, api.ActionImport, api.ActionResultImportFailed, "Failed to import repository", err, startTime)
+		}
+		b.logger.Info("Imported %s from %s for unit=%s", unit.SourcePath, targetParams.RepoPath, payload.UnitSlug)
+
+		terminatedAt := time.Now()
+		return ctx.SendStatus(&api.ActionResult{
+			UnitID:            payload.UnitID,
+			SpaceID:           payload.SpaceID,
+			QueuedOperationID: payload.QueuedOperationID,
+			ActionResultBaseMeta: api.ActionResultBaseMeta{
+				RevisionNum:  payload.RevisionNum,
+				Action:       api.ActionImport,
+				Result:       api.ActionResultImportCompleted,
+				Status:       api.ActionStatusCompleted,
+				Message:      fmt.Sprintf("Imported %s from %s", unit.SourcePath, targetParams.RepoPath),
+				StartedAt:    startTime,
+				TerminatedAt: &terminatedAt,
+			},
+			Data: unit.Data,
+		})
+	}
+
+	// Otherwise re-import what was last applied for the unit, if anything
+	lastExec, err := b.actRunner.GetLastExecution(payload.SpaceID.String(), payload.UnitSlug)
+	if err != nil {
+		terminatedAt := time.Now()
+		return ctx.SendStatus(&api.ActionResult{
+			UnitID:            payload.UnitID,
+			SpaceID:           payload.SpaceID,
+			QueuedOperationID: payload.QueuedOperationID,
+			ActionResultBaseMeta: api.ActionResultBaseMeta{
+				RevisionNum:  payload.RevisionNum,
+				Action:       api.ActionImport,
+				Result:       api.ActionResultImportCompleted,
+				Status:       api.ActionStatusCompleted,
+				Message:      "No existing workflows to import (local execution only)",
+				StartedAt:    startTime,
+				TerminatedAt: &terminatedAt,
+			},
+		})
+	}
+
+	terminatedAt := time.Now()
 	return ctx.SendStatus(&api.ActionResult{
 		UnitID:            payload.UnitID,
 		SpaceID:           payload.SpaceID,
@@ -311,23 +785,127 @@ func (b *ActionsBridge) Import(ctx api.BridgeWorkerContext, payload api.BridgeWo
 			Action:       api.ActionImport,
 			Result:       api.ActionResultImportCompleted,
 			Status:       api.ActionStatusCompleted,
-			Message:      "No existing workflows to import (local execution only)",
+			Message:      fmt.Sprintf("Imported configuration of execution %s (%s)", lastExec.ID, lastExec.Timestamp.Format(time.RFC3339)),
 			StartedAt:    startTime,
 			TerminatedAt: &terminatedAt,
 		},
+		Data:      lastExec.ConfigData,
+		LiveState: lastExec.OutputData,
 	})
 }
 
-// Destroy cleans up workflow resources
+// importRepoDir resolves a repo_path target parameter below the import
+// root. Payloads come from ConfigHub, so they never name host paths directly.
+func (b *ActionsBridge) importRepoDir(repoPath string) (string, error) {
+	if b.importRoot == "" {
+		return "", fmt.Errorf("repository imports are disabled on this worker")
+	}
+	return resolveBelow(b.importRoot, repoPath)
+}
+
+// Destroy runs the unit's teardown job, if it declares one, and removes
+// everything the bridge keeps for the unit
 func (b *ActionsBridge) Destroy(ctx api.BridgeWorkerContext, payload api.BridgeWorkerPayload) error {
 	startTime := time.Now()
+	summary := &DestroySummary{}
+	liveState := &LiveState{Version: LiveStateVersion, Status: "destroyed", Jobs: []JobResult{}}
+
+	// Wait for Apply and Refresh runs of the unit, so their workspaces and
+	// containers are not removed from under them, and keep new ones out
+	unlock, err := b.unitLocks.acquire(ctx.Context(), unitKey(payload), true)
+	if err != nil {
+		return b.sendActionError(ctx, payload, api.ActionDestroy, api.ActionResultDestroyFailed, "Failed to lock unit", err, startTime)
+	}
+	defer unlock()
 
-	// Clean up any workspace for this unit
-	if ws, exists := b.workspaceManager.GetWorkspace(payload.UnitID.String()); exists {
+	// Tear down what was last applied; without history, use the unit as sent
+	configData, revision := payload.Data, int(payload.RevisionNum)
+	if lastExec, err := b.actRunner.GetLastExecution(payload.SpaceID.String(), payload.UnitSlug); err == nil {
+		configData, revision = lastExec.ConfigData, lastExec.Revision
+	}
+
+	// Units requiring approvals, now or when last applied, are torn down and
+	// released only with enough signatures of trusted approvers
+	required, err := requiredApprovals(configData, payload.Data)
+	if err != nil {
+		return b.sendActionError(ctx, payload, api.ActionDestroy, api.ActionResultDestroyFailed, "Invalid annotation", err, startTime)
+	}
+	extraParams, err := b.parseExtraParams(payload.ExtraParams)
+	if err != nil {
+		return b.sendActionError(ctx, payload, api.ActionDestroy, api.ActionResultDestroyFailed, "Failed to parse extra parameters", err, startTime)
+	}
+	if err := b.approvers.Check(required, payloadApprovalMessage(api.ActionDestroy, payload), extraParams.Approvals); err != nil {
+		return b.sendActionError(ctx, payload, api.ActionDestroy, api.ActionResultDestroyFailed, "Approval required", err, startTime)
+	}
+
+	if len(configData) > 0 {
+		bundle, err := b.loadBundle(configData)
+		if err != nil {
+			return b.sendActionError(ctx, payload, api.ActionDestroy, api.ActionResultDestroyFailed, "Invalid workflow bundle", err, startTime)
+		}
+		run, err := findLifecycleRun(bundle, unitAnnotations(configData), EventDestroy)
+		if err != nil {
+			return b.sendActionError(ctx, payload, api.ActionDestroy, api.ActionResultDestroyFailed, "Invalid teardown job", err, startTime)
+		}
+		if run != nil {
+			b.logger.Info("Running teardown of unit=%s with %s", payload.UnitSlug, run.Workflow)
+			result, err := b.runLifecycle(ctx, payload, configData, revision, bundle, run, false)
+			if err != nil {
+				return b.sendActionError(ctx, payload, api.ActionDestroy, api.ActionResultDestroyFailed, "Teardown failed", err, startTime)
+			}
+			// Keep everything when the teardown fails, so Destroy can be retried
+			if result.Status != ExecutionStatusSuccess {
+				err := fmt.Errorf("run %s ended with status %s (exit code %d)", result.ID, result.Status, result.ExitCode)
+				return b.sendActionError(ctx, payload, api.ActionDestroy, api.ActionResultDestroyFailed, "Teardown failed", err, startTime)
+			}
+			summary.TeardownExecutionID = result.ID
+			summary.TeardownWorkflow = run.Workflow
+
+			result.Logs = b.secretHandler.SanitizeLogs(result.Logs)
+			result.LogEntries = b.secretHandler.SanitizeEntries(result.LogEntries)
+			liveState = NewLiveState(result)
+		}
+	}
+
+	// Workspaces still held for the unit, with their artifacts
+	for _, ws := range b.workspaceManager.UnitWorkspaces(payload.SpaceID.String(), payload.UnitSlug) {
+		artifacts, _ := ws.GetArtifacts()
 		if err := ws.SecureCleanup(); err != nil {
-			return b.sendError(ctx, payload, "Failed to cleanup workspace", err, startTime)
+			return b.sendActionError(ctx, payload, api.ActionDestroy, api.ActionResultDestroyFailed, "Failed to cleanup workspace", err, startTime)
+		}
+		b.workspaceManager.RemoveWorkspace(ws.ID)
+		summary.Workspaces++
+		summary.Artifacts += len(artifacts)
+	}
+
+	// Containers act left behind, and their networks and volumes
+	targetParams, err := b.parseTargetParams(payload.TargetParams)
+	if err != nil {
+		return b.sendActionError(ctx, payload, api.ActionDestroy, api.ActionResultDestroyFailed, "Failed to parse target parameters", err, startTime)
+	}
+	cleanupCtx, cancel := context.WithTimeout(ctx.Context(), time.Minute)
+	removed, err := NewContainerRuntime(targetParams.Socket).RemoveResources(cleanupCtx, map[string]string{
+		LabelSpace: payload.SpaceID.String(),
+		LabelUnit:  payload.UnitSlug,
+	})
+	cancel()
+	if err != nil {
+		return b.sendActionError(ctx, payload, api.ActionDestroy, api.ActionResultDestroyFailed, "Failed to remove containers", err, startTime)
+	}
+	summary.RemovedResources = *removed
+
+	// Execution history, including the artifact manifests
+	if history := b.actRunner.History(); history != nil {
+		if summary.Executions, err = history.DeleteUnit(payload.SpaceID.String(), payload.UnitSlug); err != nil {
+			return b.sendActionError(ctx, payload, api.ActionDestroy, api.ActionResultDestroyFailed, "Failed to delete execution history", err, startTime)
 		}
-		b.workspaceManager.RemoveWorkspace(payload.UnitID.String())
+	}
+
+	b.logger.Info("Destroyed unit=%s: %s", payload.UnitSlug, summary)
+	liveState.Destroyed = summary
+	liveStateJSON, err := liveState.JSON()
+	if err != nil {
+		return b.sendActionError(ctx, payload, api.ActionDestroy, api.ActionResultDestroyFailed, "Failed to encode live state", err, startTime)
 	}
 
 	terminatedAt := time.Now()
@@ -340,19 +918,61 @@ func (b *ActionsBridge) Destroy(ctx api.BridgeWorkerContext, payload api.BridgeW
 			Action:       api.ActionDestroy,
 			Result:       api.ActionResultDestroyCompleted,
 			Status:       api.ActionStatusCompleted,
-			Message:      "Workflow resources cleaned up successfully",
+			Message:      fmt.Sprintf("Unit resources released: %s", summary),
 			StartedAt:    startTime,
 			TerminatedAt: &terminatedAt,
 		},
+		LiveState: liveStateJSON,
 	})
 }
 
-// Finalize archives workflow execution data
+// Finalize archives the sanitized logs, LiveState and artifacts of the
+// unit's last execution, with a manifest of their checksums
 func (b *ActionsBridge) Finalize(ctx api.BridgeWorkerContext, payload api.BridgeWorkerPayload) error {
 	startTime := time.Now()
 
-	// Archive execution logs and artifacts
-	// In a production system, this would upload to S3 or similar
+	lastExec, err := b.actRunner.GetLastExecution(payload.SpaceID.String(), payload.UnitSlug)
+	if err != nil {
+		terminatedAt := time.Now()
+		return ctx.SendStatus(&api.ActionResult{
+			UnitID:            payload.UnitID,
+			SpaceID:           payload.SpaceID,
+			QueuedOperationID: payload.QueuedOperationID,
+			ActionResultBaseMeta: api.ActionResultBaseMeta{
+				RevisionNum:  payload.RevisionNum,
+				Action:       api.ActionFinalize,
+				Result:       api.ActionResultNone,
+				Status:       api.ActionStatusCompleted,
+				Message:      "No execution to archive",
+				StartedAt:    startTime,
+				TerminatedAt: &terminatedAt,
+			},
+		})
+	}
+
+	artifactsDir := ""
+	if history := b.actRunner.History(); history != nil {
+		if artifactsDir, err = history.ArtifactsDir(lastExec); err != nil {
+			return b.sendActionError(ctx, payload, api.ActionFinalize, api.ActionResultNone, "Failed to locate artifacts", err, startTime)
+		}
+	}
+
+	archived, err := ArchiveExecution(ctx.Context(), b.archive, lastExec, artifactsDir, b.secretHandler.SanitizeLogs)
+	if err != nil {
+		return b.sendActionError(ctx, payload, api.ActionFinalize, api.ActionResultNone, "Failed to archive execution", err, startTime)
+	}
+	b.logger.Info("Archived execution %s of unit=%s to %s", lastExec.ID, payload.UnitSlug, archived.Location)
+
+	// Publish the archive location and checksums with the execution's LiveState
+	state, err := recordLiveState(lastExec)
+	if err != nil {
+		return b.sendActionError(ctx, payload, api.ActionFinalize, api.ActionResultNone, "Failed to build live state", err, startTime)
+	}
+	state.Archive = archived
+	liveState, err := state.JSON()
+	if err != nil {
+		return b.sendActionError(ctx, payload, api.ActionFinalize, api.ActionResultNone, "Failed to encode live state", err, startTime)
+	}
 
 	terminatedAt := time.Now()
 	return ctx.SendStatus(&api.ActionResult{
@@ -360,14 +980,16 @@ func (b *ActionsBridge) Finalize(ctx api.BridgeWorkerContext, payload api.Bridge
 		SpaceID:           payload.SpaceID,
 		QueuedOperationID: payload.QueuedOperationID,
 		ActionResultBaseMeta: api.ActionResultBaseMeta{
-			RevisionNum:  payload.RevisionNum,
-			Action:       api.ActionFinalize,
-			Result:       api.ActionResultApplyCompleted,
-			Status:       api.ActionStatusCompleted,
-			Message:      "Workflow execution data archived",
+			RevisionNum: payload.RevisionNum,
+			Action:      api.ActionFinalize,
+			Result:      api.ActionResultNone,
+			Status:      api.ActionStatusCompleted,
+			Message: fmt.Sprintf("Archived execution %s to %s (%d file(s), manifest sha256 %s)",
+				lastExec.ID, archived.Location, len(archived.Files), archived.ManifestSHA256),
 			StartedAt:    startTime,
 			TerminatedAt: &terminatedAt,
 		},
+		LiveState: liveState,
 	})
 }
 
@@ -375,6 +997,12 @@ func (b *ActionsBridge) Finalize(ctx api.BridgeWorkerContext, payload api.Bridge
 
 func (b *ActionsBridge) sendError(ctx api.BridgeWorkerContext, payload api.BridgeWorkerPayload,
 	message string, err error, startTime time.Time) error {
+	return b.sendActionError(ctx, payload, api.ActionApply, api.ActionResultApplyFailed, message, err, startTime)
+}
+
+// sendActionError reports a failed action
+func (b *ActionsBridge) sendActionError(ctx api.BridgeWorkerContext, payload api.BridgeWorkerPayload,
+	action api.ActionType, result api.ActionResultType, message string, err error, startTime time.Time) error {
 
 	terminatedAt := time.Now()
 	fullMessage := fmt.Sprintf("%s: %v", message, err)
@@ -385,8 +1013,8 @@ func (b *ActionsBridge) sendError(ctx api.BridgeWorkerContext, payload api.Bridg
 		QueuedOperationID: payload.QueuedOperationID,
 		ActionResultBaseMeta: api.ActionResultBaseMeta{
 			RevisionNum:  payload.RevisionNum,
-			Action:       api.ActionApply,
-			Result:       api.ActionResultApplyFailed,
+			Action:       action,
+			Result:       result,
 			Status:       api.ActionStatusFailed,
 			Message:      fullMessage,
 			StartedAt:    startTime,
@@ -397,7 +1025,12 @@ func (b *ActionsBridge) sendError(ctx api.BridgeWorkerContext, payload api.Bridg
 
 func (b *ActionsBridge) sendWarnings(ctx api.BridgeWorkerContext, payload api.BridgeWorkerPayload, warnings []Warning) {
 	for _, warning := range warnings {
-		log.Printf("Compatibility %s: %s", warning.Level, warning.Message)
+		log.Printf("Compatibility %s %s: %s", warning.Level, warning.Rule, warning.Message)
+
+		message := fmt.Sprintf("[%s %s] %s", warning.Level, warning.Rule, warning.Message)
+		if warning.Line > 0 {
+			message = fmt.Sprintf("%s (line %d)", message, warning.Line)
+		}
 
 		// Send informational status for warnings
 		ctx.SendStatus(&api.ActionResult{
@@ -406,23 +1039,31 @@ func (b *ActionsBridge) sendWarnings(ctx api.BridgeWorkerContext, payload api.Br
 			QueuedOperationID: payload.QueuedOperationID,
 			ActionResultBaseMeta: api.ActionResultBaseMeta{
 				Status:  api.ActionStatusProgressing,
-				Message: fmt.Sprintf("[%s] %s", warning.Level, warning.Message),
+				Message: message,
 			},
 		})
 	}
 }
 
 type targetParameters struct {
-	Platform string
-	DryRun   bool
-	Socket   string
+	Platform     string
+	DryRun       bool
+	Socket       string
+	Timeout      time.Duration
+	Event        string
+	Jobs         []string
+	Matrix       map[string][]string
+	RunnerImages RunnerImages // Added on top of the bridge's mappings
+	RepoPath     string       // Repository checkout below the import root that Import reads workflows from
+	Workflow     string       // Unit name or path of the workflow Import reads; defaults to the unit slug
 }
 
 func (b *ActionsBridge) parseTargetParams(data []byte) (targetParameters, error) {
 	params := targetParameters{
-		Platform: "linux/amd64",
+		Platform: "", // The bridge's platform
 		DryRun:   false,
-		Socket:   "/var/run/docker.sock",
+		Socket:   "", // DOCKER_HOST or the default Docker socket
+		Timeout:  DefaultExecutionTimeout,
 	}
 
 	if len(data) == 0 {
@@ -443,14 +1084,62 @@ func (b *ActionsBridge) parseTargetParams(data []byte) (targetParameters, error)
 	if s, ok := raw["socket"].(string); ok {
 		params.Socket = s
 	}
+	if e, ok := raw["event"].(string); ok {
+		params.Event = e
+	}
+	if r, ok := raw["repo_path"].(string); ok {
+		params.RepoPath = r
+	}
+	if w, ok := raw["workflow"].(string); ok {
+		params.Workflow = w
+	}
+	if j, ok := raw["jobs"]; ok {
+		jobs, err := parseStringList(j)
+		if err != nil {
+			return params, fmt.Errorf("jobs: %w", err)
+		}
+		params.Jobs = jobs
+	}
+	if m, ok := raw["matrix"]; ok {
+		matrix, err := parseMatrixParam(m)
+		if err != nil {
+			return params, fmt.Errorf("matrix: %w", err)
+		}
+		params.Matrix = matrix
+	}
+	if ri, ok := raw["runner_images"]; ok {
+		images, err := runnerImagesParam(ri)
+		if err != nil {
+			return params, fmt.Errorf("runner_images: %w", err)
+		}
+		params.RunnerImages = images
+	}
+	if t, ok := raw["timeout"]; ok {
+		timeout, err := parseTimeout(t)
+		if err != nil {
+			return params, err
+		}
+		params.Timeout = timeout
+	}
 
 	return params, nil
 }
 
+// secretNames returns the names of secrets, sorted
+func secretNames(secrets map[string]string) []string {
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 type extraParameters struct {
 	Secrets     map[string]string
 	Configs     map[string]interface{}
 	Environment map[string]string
+	Approvals   []Approval // Signatures approving the operation
 }
 
 func (b *ActionsBridge) parseExtraParams(data []byte) (extraParameters, error) {
@@ -492,23 +1181,99 @@ func (b *ActionsBridge) parseExtraParams(data []byte) (extraParameters, error) {
 		}
 	}
 
+	// Parse approvals
+	if approvals, ok := raw["approvals"]; ok {
+		list, err := parseApprovals(approvals)
+		if err != nil {
+			return params, fmt.Errorf("approvals: %w", err)
+		}
+		params.Approvals = list
+	}
+
 	return params, nil
 }
 
-// stripKubernetesMetadata removes the first 4 lines of Kubernetes metadata from the YAML
-func (b *ActionsBridge) stripKubernetesMetadata(data []byte) []byte {
-	lines := bytes.Split(data, []byte("\n"))
+// unitAnnotations reads metadata.annotations from the ConfigHub header of a unit
+func unitAnnotations(data []byte) map[string]string {
+	envelope, err := ParseEnvelope(data)
+	if err != nil || envelope.Metadata.Annotations == nil {
+		return map[string]string{}
+	}
+	return envelope.Metadata.Annotations
+}
 
-	// Check if the first line contains apiVersion: actions.confighub.com
-	if len(lines) > 0 && bytes.Contains(lines[0], []byte("apiVersion:")) && bytes.Contains(lines[0], []byte("actions.confighub.com")) {
-		// If we have more than 4 lines, skip the first 4
-		if len(lines) > 4 {
-			return bytes.Join(lines[4:], []byte("\n"))
+// parseStringList accepts a list of strings or a comma-separated string
+func parseStringList(value interface{}) ([]string, error) {
+	var items []string
+	switch v := value.(type) {
+	case string:
+		items = strings.Split(v, ",")
+	case []interface{}:
+		for _, item := range v {
+			str, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid list item type %T", item)
+			}
+			items = append(items, str)
 		}
+	default:
+		return nil, fmt.Errorf("invalid list type %T", value)
 	}
 
-	// Return data as-is if no ConfigHub metadata found
-	return data
+	var list []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list, nil
+}
+
+// parseMatrixParam accepts a selector string such as "os=ubuntu-22.04,node=20"
+// or an object mapping each key to a value or a list of values
+func parseMatrixParam(value interface{}) (map[string][]string, error) {
+	switch v := value.(type) {
+	case string:
+		return ParseMatrixSelector([]string{v})
+	case map[string]interface{}:
+		matrix := make(map[string][]string, len(v))
+		for key, val := range v {
+			if list, ok := val.([]interface{}); ok {
+				for _, item := range list {
+					matrix[key] = append(matrix[key], fmt.Sprint(item))
+				}
+				continue
+			}
+			matrix[key] = []string{fmt.Sprint(val)}
+		}
+		return matrix, nil
+	default:
+		return nil, fmt.Errorf("invalid matrix type %T", value)
+	}
+}
+
+// parseTimeout accepts a number of seconds or a duration string such as "15m"
+func parseTimeout(value interface{}) (time.Duration, error) {
+	var timeout time.Duration
+	switch v := value.(type) {
+	case float64:
+		timeout = time.Duration(v * float64(time.Second))
+	case int:
+		timeout = time.Duration(v) * time.Second
+	case string:
+		if secs, err := strconv.Atoi(v); err == nil {
+			timeout = time.Duration(secs) * time.Second
+		} else if timeout, err = time.ParseDuration(v); err != nil {
+			return 0, fmt.Errorf("invalid timeout %q: %w", v, err)
+		}
+	default:
+		return 0, fmt.Errorf("invalid timeout type %T", value)
+	}
+
+	if timeout <= 0 {
+		return 0, fmt.Errorf("timeout must be positive, got %s", timeout)
+	}
+	return timeout, nil
 }
 
 // validatePayload validates the incoming payload
@@ -522,24 +1287,73 @@ func (b *ActionsBridge) validatePayload(payload api.BridgeWorkerPayload) error {
 		return fmt.Errorf("workflow too large: %d bytes (max 10MB)", len(payload.Data))
 	}
 
-	// Strip Kubernetes metadata for validation
-	strippedData := b.stripKubernetesMetadata(payload.Data)
+	// Load the unit files and check every workflow in them
+	bundle, err := b.loadBundle(payload.Data)
+	if err != nil {
+		return fmt.Errorf("invalid workflow bundle: %w", err)
+	}
+
+	// Secret references are checked against the secrets of the payload, and
+	// local actions against the bundle files
+	lintOptions := LintOptions{ReadFile: bundle.ReadFile}
+	if extraParams, err := b.parseExtraParams(payload.ExtraParams); err == nil {
+		lintOptions.Secrets = secretNames(extraParams.Secrets)
+	}
+
+	for _, name := range bundle.Workflows() {
+		content := []byte(bundle.Files[name])
+
+		// Validate YAML syntax
+		var workflow map[string]interface{}
+		if err := yaml.Unmarshal(content, &workflow); err != nil {
+			return fmt.Errorf("invalid workflow YAML in %s: %w", name, err)
+		}
+
+		// Check if workflow is supported
+		supported, reason := b.compatChecker.IsWorkflowSupported(content)
+		if !supported {
+			return fmt.Errorf("workflow %s not supported: %s", name, reason)
+		}
+
+		// Findings of error severity, such as broken expressions, fail the payload
+		if errs := WarningsAtLeast(b.compatChecker.CheckWorkflowWith(content, lintOptions), SeverityError); len(errs) > 0 {
+			messages := make([]string, len(errs))
+			for i, w := range errs {
+				messages[i] = fmt.Sprintf("line %d: %s (%s)", w.Line, w.Message, w.Rule)
+			}
+			return fmt.Errorf("workflow %s: %s", name, strings.Join(messages, "; "))
+		}
+	}
 
-	// Validate YAML syntax on stripped data
-	var workflow map[string]interface{}
-	if err := yaml.Unmarshal(strippedData, &workflow); err != nil {
-		return fmt.Errorf("invalid workflow YAML: %w", err)
+	if err := validateLifecycleRuns(bundle, unitAnnotations(payload.Data)); err != nil {
+		return fmt.Errorf("invalid lifecycle jobs: %w", err)
 	}
 
-	// Check if workflow is supported (using stripped data)
-	supported, reason := b.compatChecker.IsWorkflowSupported(strippedData)
-	if !supported {
-		return fmt.Errorf("workflow not supported: %s", reason)
+	options, err := ParseUnitOptions(unitAnnotations(payload.Data))
+	if err != nil {
+		return fmt.Errorf("invalid annotation: %w", err)
+	}
+	if err := options.Validate(bundle); err != nil {
+		return fmt.Errorf("invalid annotation: %w", err)
 	}
 
 	return nil
 }
 
+// loadBundle reads the files of a unit. Plain workflow units become a bundle
+// of one file; the entrypoint annotation can pick another bundle workflow.
+// Data without a ConfigHub header is taken as a bare workflow.
+func (b *ActionsBridge) loadBundle(data []byte) (*Bundle, error) {
+	envelope, err := ParseEnvelope(data)
+	if errors.Is(err, ErrNoEnvelope) {
+		return NewWorkflowBundle(data), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return envelope.Files()
+}
+
 // HealthHandler handles health check requests
 func (b *ActionsBridge) HealthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,143 @@
+package bridge
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/confighub/sdk/bridge-worker/api"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// Approval is an approver's signature of an operation on a unit revision,
+// passed to the worker in the approvals extra parameter. Whoever sends the
+// payload can add approvals, so only signatures made with the key of an
+// approver the worker trusts count.
+type Approval struct {
+	Approver  string `json:"approver" yaml:"approver"`
+	Signature string `json:"signature" yaml:"signature"` // Base64 Ed25519 signature of the ApprovalMessage
+}
+
+// Approvers are the Ed25519 public keys of the approvers a worker trusts, by name
+type Approvers map[string]ed25519.PublicKey
+
+// LoadApprovers reads a YAML or JSON file mapping approver names to base64
+// Ed25519 public keys
+func LoadApprovers(path string) (Approvers, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read approvers: %w", err)
+	}
+	var encoded map[string]string
+	if err := yaml.Unmarshal(data, &encoded); err != nil {
+		return nil, fmt.Errorf("parse approvers %s: %w", path, err)
+	}
+
+	approvers := make(Approvers, len(encoded))
+	for name, value := range encoded {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("approvers %s: approver %s: invalid public key", path, name)
+		}
+		approvers[name] = ed25519.PublicKey(key)
+	}
+	return approvers, nil
+}
+
+// ApprovalMessage returns what approvers sign to approve an action on a
+// revision of a unit. It covers the unit's data, so an approval does not
+// carry over to another action, revision or unit.
+func ApprovalMessage(action api.ActionType, space, unit uuid.UUID, revision int64, data []byte) []byte {
+	return []byte(fmt.Sprintf("confighub-actions-bridge-approval\naction=%s\nspace=%s\nunit=%s\nrevision=%d\ndata=%x\n",
+		action, space, unit, revision, sha256.Sum256(data)))
+}
+
+// payloadApprovalMessage returns the message approvers sign for an action on a payload
+func payloadApprovalMessage(action api.ActionType, payload api.BridgeWorkerPayload) []byte {
+	return ApprovalMessage(action, payload.SpaceID, payload.UnitID, payload.RevisionNum, payload.Data)
+}
+
+// Check returns an error unless at least required distinct approvers the
+// worker trusts signed the message. Approvals of unknown approvers and bad
+// signatures are not counted.
+func (a Approvers) Check(required int, message []byte, approvals []Approval) error {
+	if required <= 0 {
+		return nil
+	}
+	if len(a) == 0 {
+		return fmt.Errorf("unit requires %d approval(s), but the worker trusts no approvers", required)
+	}
+
+	approved := make(map[string]bool)
+	for _, approval := range approvals {
+		key, ok := a[approval.Approver]
+		if !ok {
+			continue
+		}
+		signature, err := base64.StdEncoding.DecodeString(approval.Signature)
+		if err != nil || !ed25519.Verify(key, message, signature) {
+			continue
+		}
+		approved[approval.Approver] = true
+	}
+	if len(approved) < required {
+		return fmt.Errorf("unit requires %d approval(s), has %d", required, len(approved))
+	}
+	return nil
+}
+
+// GenerateApprovalKey returns a new private key for signing approvals, as
+// written to a key file, and its public key, as listed in an approvers file
+func GenerateApprovalKey() (private, public string, err error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("generate key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(privateKey.Seed()), base64.StdEncoding.EncodeToString(publicKey), nil
+}
+
+// ParseApprovalKey reads a private key written by GenerateApprovalKey
+func ParseApprovalKey(data []byte) (ed25519.PrivateKey, error) {
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid approval key")
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// SignApproval signs an approval message with an approver's private key
+func SignApproval(approver string, key ed25519.PrivateKey, message []byte) Approval {
+	return Approval{Approver: approver, Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, message))}
+}
+
+// requiredApprovals returns the most approvals any of a unit's configs requires
+func requiredApprovals(configs ...[]byte) (int, error) {
+	required := 0
+	for _, config := range configs {
+		options, err := ParseUnitOptions(unitAnnotations(config))
+		if err != nil {
+			return 0, err
+		}
+		required = max(required, options.RequiredApprovals)
+	}
+	return required, nil
+}
+
+// parseApprovals reads the approvals extra parameter
+func parseApprovals(value interface{}) ([]Approval, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var approvals []Approval
+	if err := json.Unmarshal(data, &approvals); err != nil {
+		return nil, fmt.Errorf("expected a list of approver and signature pairs")
+	}
+	return approvals, nil
+}
@@ -0,0 +1,144 @@
+package bridge
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/confighub/sdk/bridge-worker/api"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// approvedUnit is a unit without teardown jobs that requires one approval
+const approvedUnit = `apiVersion: actions.confighub.com/v1alpha1
+kind: Actions
+metadata:
+  name: web
+  annotations:
+    actions.confighub.com/required-approvals: "1"
+` + lifecycleWorkflow
+
+// approvalKey generates a key pair, returning the private key and the public
+// key as an approvers file lists it
+func approvalKey(t *testing.T) ([]byte, string) {
+	t.Helper()
+	private, public, err := GenerateApprovalKey()
+	require.NoError(t, err)
+	return []byte(private), public
+}
+
+func TestApproversCheck(t *testing.T) {
+	alicePrivate, alicePublic := approvalKey(t)
+	bobPrivate, bobPublic := approvalKey(t)
+	malloryPrivate, _ := approvalKey(t)
+
+	path := filepath.Join(t.TempDir(), "approvers.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("alice: "+alicePublic+"\nbob: "+bobPublic+"\n"), 0600))
+	approvers, err := LoadApprovers(path)
+	require.NoError(t, err)
+	require.Len(t, approvers, 2)
+
+	space, unit := uuid.New(), uuid.New()
+	message := ApprovalMessage(api.ActionApply, space, unit, 3, []byte(approvedUnit))
+	sign := func(name string, private []byte, message []byte) Approval {
+		key, err := ParseApprovalKey(private)
+		require.NoError(t, err)
+		return SignApproval(name, key, message)
+	}
+	alice := sign("alice", alicePrivate, message)
+	bob := sign("bob", bobPrivate, message)
+
+	tests := []struct {
+		name      string
+		required  int
+		approvals []Approval
+		wantErr   string
+	}{
+		{name: "no approvals required", required: 0},
+		{name: "enough approvers", required: 2, approvals: []Approval{alice, bob}},
+		{name: "approvers count once", required: 2, approvals: []Approval{alice, alice}, wantErr: "requires 2 approval(s), has 1"},
+		{name: "untrusted approver", required: 1, approvals: []Approval{sign("mallory", malloryPrivate, message)}, wantErr: "has 0"},
+		{name: "key of another approver", required: 1, approvals: []Approval{sign("alice", malloryPrivate, message)}, wantErr: "has 0"},
+		{name: "another revision", required: 1,
+			approvals: []Approval{sign("alice", alicePrivate, ApprovalMessage(api.ActionApply, space, unit, 4, []byte(approvedUnit)))}, wantErr: "has 0"},
+		{name: "another action", required: 1,
+			approvals: []Approval{sign("alice", alicePrivate, ApprovalMessage(api.ActionDestroy, space, unit, 3, []byte(approvedUnit)))}, wantErr: "has 0"},
+		{name: "malformed signature", required: 1, approvals: []Approval{{Approver: "alice", Signature: "not base64"}}, wantErr: "has 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := approvers.Check(tt.required, message, tt.approvals)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	// A worker trusting nobody rejects units requiring approvals
+	assert.ErrorContains(t, Approvers{}.Check(1, message, []Approval{alice}), "trusts no approvers")
+
+	require.NoError(t, os.WriteFile(path, []byte("alice: c2hvcnQ=\n"), 0600))
+	_, err = LoadApprovers(path)
+	assert.ErrorContains(t, err, "approver alice: invalid public key")
+	_, err = ParseApprovalKey([]byte("c2hvcnQ="))
+	assert.Error(t, err)
+}
+
+func TestApprovalGate(t *testing.T) {
+	private, _ := approvalKey(t)
+	key, err := ParseApprovalKey(private)
+	require.NoError(t, err)
+	approvers := Approvers{"alice": key.Public().(ed25519.PublicKey)}
+	bridge, err := NewActionsBridgeWithConfig(BridgeConfig{BaseDir: t.TempDir(), Approvers: approvers})
+	require.NoError(t, err)
+
+	space, unit := uuid.New(), uuid.New()
+	payload := func(params map[string]interface{}, approvals ...Approval) api.BridgeWorkerPayload {
+		target, _ := json.Marshal(params)
+		extra, _ := json.Marshal(map[string]interface{}{"approvals": approvals})
+		return api.BridgeWorkerPayload{SpaceID: space, UnitID: unit, UnitSlug: "web", RevisionNum: 3,
+			Data: []byte(approvedUnit), TargetParams: target, ExtraParams: extra}
+	}
+	approve := func(action api.ActionType) Approval {
+		return SignApproval("alice", key, ApprovalMessage(action, space, unit, 3, []byte(approvedUnit)))
+	}
+
+	t.Run("apply", func(t *testing.T) {
+		ctx := &statusRecorder{ctx: context.Background()}
+		bridge.Apply(ctx, payload(map[string]interface{}{"dry_run": true}, approve(api.ActionDestroy)))
+		status := ctx.last(t)
+		assert.Equal(t, api.ActionStatusFailed, status.Status)
+		assert.Contains(t, status.Message, "Approval required")
+		assert.Contains(t, status.Message, "requires 1 approval(s), has 0")
+	})
+
+	t.Run("destroy", func(t *testing.T) {
+		history := bridge.actRunner.History()
+		record := &ExecutionRecord{ID: "exec-1", UnitID: "web", Space: space.String(), StartTime: time.Now(), ConfigData: []byte(approvedUnit)}
+		require.NoError(t, history.Save(record))
+		socket := fakeDaemon(t)
+
+		// Approving the Apply does not approve the Destroy
+		ctx := &statusRecorder{ctx: context.Background()}
+		require.NoError(t, bridge.Destroy(ctx, payload(map[string]interface{}{"socket": socket}, approve(api.ActionApply))))
+		status := ctx.last(t)
+		assert.Equal(t, api.ActionResultDestroyFailed, status.Result)
+		assert.Contains(t, status.Message, "Approval required")
+		_, err := history.Latest(space.String(), "web")
+		assert.NoError(t, err, "nothing is removed without approval")
+
+		ctx = &statusRecorder{ctx: context.Background()}
+		require.NoError(t, bridge.Destroy(ctx, payload(map[string]interface{}{"socket": socket}, approve(api.ActionDestroy))))
+		assert.Equal(t, api.ActionResultDestroyCompleted, ctx.last(t).Result)
+		_, err = history.Latest(space.String(), "web")
+		assert.ErrorIs(t, err, ErrNoExecutions)
+	})
+}
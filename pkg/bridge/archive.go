@@ -0,0 +1,296 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ArchiveManifestVersion identifies the schema of archive manifests
+const ArchiveManifestVersion = "actions.confighub.com/v1alpha1"
+
+// Archive stores finalized execution data
+type Archive interface {
+	// Put stores an object under key, replacing any previous one
+	Put(ctx context.Context, object ArchiveObject) error
+	// Location returns the URL of the object stored under key
+	Location(key string) string
+}
+
+// ArchiveObject is one object written to an archive
+type ArchiveObject struct {
+	Key         string // Slash-separated path within the archive
+	Body        io.Reader
+	Size        int64
+	SHA256      string // Hex digest of Body
+	ContentType string
+}
+
+// ArchiveManifest lists what was archived for an execution
+type ArchiveManifest struct {
+	Version     string         `json:"version"`
+	ExecutionID string         `json:"execution_id"`
+	Unit        string         `json:"unit"`
+	Space       string         `json:"space,omitempty"`
+	Revision    int            `json:"revision,omitempty"`
+	Status      string         `json:"status"`
+	ArchivedAt  time.Time      `json:"archived_at"`
+	Location    string         `json:"location"` // URL of the execution's archive prefix
+	Files       []ArchiveEntry `json:"files"`
+}
+
+// ArchiveEntry describes one archived file
+type ArchiveEntry struct {
+	Key    string `json:"key"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// ArchiveResult is what Finalize reports for an archived execution
+type ArchiveResult struct {
+	ExecutionID    string         `json:"execution_id"`
+	Location       string         `json:"location"` // URL of the execution's archive prefix
+	Manifest       string         `json:"manifest"` // URL of the manifest
+	ManifestSHA256 string         `json:"manifest_sha256"`
+	ArchivedAt     time.Time      `json:"archived_at"`
+	Files          []ArchiveEntry `json:"files"`
+}
+
+// ArchiveExecution stores the sanitized logs, LiveState and artifact files
+// of an execution under <unit>/<execution id>/, followed by a manifest with
+// their checksums. The manifest is written last, so its presence marks a
+// complete archive.
+func ArchiveExecution(ctx context.Context, archive Archive, record *ExecutionRecord, artifactsDir string, sanitize func([]string) []string) (*ArchiveResult, error) {
+	prefix := path.Join(url.PathEscape(record.UnitID), record.ID)
+	manifest := &ArchiveManifest{
+		Version:     ArchiveManifestVersion,
+		ExecutionID: record.ID,
+		Unit:        record.UnitID,
+		Space:       record.Space,
+		Revision:    record.Revision,
+		Status:      record.Status,
+		ArchivedAt:  time.Now().UTC(),
+		Location:    archive.Location(prefix),
+		Files:       []ArchiveEntry{},
+	}
+
+	putBytes := func(name, contentType string, data []byte) error {
+		entry, err := putArchiveBytes(ctx, archive, path.Join(prefix, name), contentType, data)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, *entry)
+		return nil
+	}
+
+	logs := record.Logs
+	if sanitize != nil {
+		logs = sanitize(logs)
+	}
+	if err := putBytes("logs.txt", "text/plain; charset=utf-8", []byte(strings.Join(logs, "\n")+"\n")); err != nil {
+		return nil, err
+	}
+	if len(record.OutputData) > 0 {
+		if err := putBytes("live-state.json", "application/json", record.OutputData); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, rel := range record.Artifacts {
+		rel = filepath.ToSlash(rel)
+		if err := validateRelativePath(rel); err != nil {
+			return nil, fmt.Errorf("invalid artifact path: %w", err)
+		}
+		entry, err := putArchiveFile(ctx, archive, path.Join(prefix, "artifacts", rel), filepath.Join(artifactsDir, filepath.FromSlash(rel)))
+		if errors.Is(err, os.ErrNotExist) {
+			// Pruned, or never stored because it was not a regular file
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("archive artifact %s: %w", rel, err)
+		}
+		manifest.Files = append(manifest.Files, *entry)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode archive manifest: %w", err)
+	}
+	manifestKey := path.Join(prefix, "manifest.json")
+	entry, err := putArchiveBytes(ctx, archive, manifestKey, "application/json", data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ArchiveResult{
+		ExecutionID:    record.ID,
+		Location:       manifest.Location,
+		Manifest:       archive.Location(manifestKey),
+		ManifestSHA256: entry.SHA256,
+		ArchivedAt:     manifest.ArchivedAt,
+		Files:          manifest.Files,
+	}, nil
+}
+
+// putArchiveBytes stores data under key
+func putArchiveBytes(ctx context.Context, archive Archive, key, contentType string, data []byte) (*ArchiveEntry, error) {
+	sum := sha256.Sum256(data)
+	entry := &ArchiveEntry{Key: key, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:])}
+	if err := archive.Put(ctx, ArchiveObject{
+		Key:         key,
+		Body:        bytes.NewReader(data),
+		Size:        entry.Size,
+		SHA256:      entry.SHA256,
+		ContentType: contentType,
+	}); err != nil {
+		return nil, fmt.Errorf("archive %s: %w", key, err)
+	}
+	return entry, nil
+}
+
+// putArchiveFile stores a file under key. The file is hashed first, since
+// backends need the digest before the body.
+func putArchiveFile(ctx context.Context, archive Archive, key, file string) (*ArchiveEntry, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	entry := &ArchiveEntry{Key: key, Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))}
+	if err := archive.Put(ctx, ArchiveObject{
+		Key:         key,
+		Body:        f,
+		Size:        size,
+		SHA256:      entry.SHA256,
+		ContentType: "application/octet-stream",
+	}); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// OpenArchive opens an archive from a URL: a local directory given as a
+// path or file:// URL, or an S3 bucket given as s3://bucket/prefix. S3 URLs
+// take endpoint and region query parameters; credentials come from the
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN variables.
+func OpenArchive(rawURL string) (Archive, error) {
+	dir := rawURL
+	if strings.Contains(rawURL, "://") {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("parse archive URL: %w", err)
+		}
+		switch u.Scheme {
+		case "file":
+			dir = u.Path
+		case "s3":
+			return openS3Archive(u)
+		default:
+			return nil, fmt.Errorf("unsupported archive scheme %q (use a path, file:// or s3://)", u.Scheme)
+		}
+	}
+
+	archive, err := NewLocalArchive(dir)
+	if err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// openS3Archive opens an archive from an s3:// URL
+func openS3Archive(u *url.URL) (Archive, error) {
+	query := u.Query()
+	archive, err := NewS3Archive(S3Config{
+		Endpoint:        query.Get("endpoint"),
+		Region:          query.Get("region"),
+		Bucket:          u.Host,
+		Prefix:          strings.Trim(u.Path, "/"),
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// LocalArchive stores archived objects as files below a directory
+type LocalArchive struct {
+	dir string
+}
+
+// NewLocalArchive creates an archive in dir
+func NewLocalArchive(dir string) (*LocalArchive, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("archive directory is required")
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("resolve archive dir: %w", err)
+	}
+	if err := os.MkdirAll(abs, 0755); err != nil {
+		return nil, fmt.Errorf("create archive dir: %w", err)
+	}
+	return &LocalArchive{dir: abs}, nil
+}
+
+// Put writes an object to a temporary file and renames it into place
+func (la *LocalArchive) Put(ctx context.Context, object ArchiveObject) error {
+	if err := validateRelativePath(object.Key); err != nil {
+		return fmt.Errorf("invalid archive key: %w", err)
+	}
+
+	target := filepath.Join(la.dir, filepath.FromSlash(object.Key))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".archive-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), object.Body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if object.SHA256 != "" && hex.EncodeToString(hash.Sum(nil)) != object.SHA256 {
+		return fmt.Errorf("checksum mismatch for %s", object.Key)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+// Location returns the file:// URL of an object
+func (la *LocalArchive) Location(key string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(la.dir, filepath.FromSlash(key)))}).String()
+}
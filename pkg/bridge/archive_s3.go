@@ -0,0 +1,106 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Config configures an S3-compatible archive
+type S3Config struct {
+	Endpoint        string // e.g. http://localhost:9000 for MinIO; defaults to AWS for the region
+	Region          string // Defaults to AWS_REGION or us-east-1
+	Bucket          string
+	Prefix          string // Key prefix within the bucket
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // Optional, for temporary credentials
+	HTTPClient      *http.Client
+}
+
+// S3Archive stores archived objects in an S3-compatible bucket through the
+// MinIO client, with path-style addressing, which MinIO and other
+// S3-compatible stores accept.
+type S3Archive struct {
+	config S3Config
+	client *minio.Client
+}
+
+// NewS3Archive creates an archive in an S3 bucket
+func NewS3Archive(config S3Config) (*S3Archive, error) {
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is required")
+	}
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("S3 credentials are required")
+	}
+	if config.Region == "" {
+		config.Region = os.Getenv("AWS_REGION")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", config.Region)
+	}
+	config.Prefix = strings.Trim(config.Prefix, "/")
+
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || endpoint.Host == "" || strings.Trim(endpoint.Path, "/") != "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", config.Endpoint)
+	}
+
+	options := &minio.Options{
+		Creds:        credentials.NewStaticV4(config.AccessKeyID, config.SecretAccessKey, config.SessionToken),
+		Secure:       endpoint.Scheme == "https",
+		Region:       config.Region,
+		BucketLookup: minio.BucketLookupPath,
+	}
+	if config.HTTPClient != nil {
+		options.Transport = config.HTTPClient.Transport
+	}
+	client, err := minio.New(endpoint.Host, options)
+	if err != nil {
+		return nil, fmt.Errorf("create S3 client: %w", err)
+	}
+
+	return &S3Archive{
+		config: config,
+		client: client,
+	}, nil
+}
+
+// Put uploads an object. Its SHA-256 digest is kept as object metadata.
+func (sa *S3Archive) Put(ctx context.Context, object ArchiveObject) error {
+	if err := validateRelativePath(object.Key); err != nil {
+		return fmt.Errorf("invalid archive key: %w", err)
+	}
+
+	options := minio.PutObjectOptions{ContentType: object.ContentType}
+	if object.SHA256 != "" {
+		options.UserMetadata = map[string]string{"sha256": object.SHA256}
+	}
+	if _, err := sa.client.PutObject(ctx, sa.config.Bucket, sa.objectKey(object.Key), object.Body, object.Size, options); err != nil {
+		return fmt.Errorf("upload %s: %w", object.Key, err)
+	}
+	return nil
+}
+
+// Location returns the s3:// URL of an object
+func (sa *S3Archive) Location(key string) string {
+	return fmt.Sprintf("s3://%s/%s", sa.config.Bucket, sa.objectKey(key))
+}
+
+// objectKey prefixes a key with the configured prefix
+func (sa *S3Archive) objectKey(key string) string {
+	if sa.config.Prefix == "" {
+		return key
+	}
+	return sa.config.Prefix + "/" + key
+}
@@ -0,0 +1,133 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 keeps the objects uploaded to it by path
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	headers map[string]http.Header
+}
+
+func (fs *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut || !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		if body, err = decodeChunked(body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	fs.mu.Lock()
+	fs.objects[r.URL.Path] = body
+	fs.headers[r.URL.Path] = r.Header.Clone()
+	fs.mu.Unlock()
+	w.Header().Set("ETag", `"etag"`)
+}
+
+// decodeChunked decodes a body sent with a streaming signature: chunks of
+// "<hex size>;chunk-signature=<signature>\r\n<data>\r\n", ending with an
+// empty chunk
+func decodeChunked(body []byte) ([]byte, error) {
+	var data []byte
+	for {
+		header, rest, ok := bytes.Cut(body, []byte("\r\n"))
+		if !ok {
+			return nil, fmt.Errorf("truncated chunk header")
+		}
+		sizeHex, _, _ := bytes.Cut(header, []byte(";"))
+		size, err := strconv.ParseInt(string(sizeHex), 16, 64)
+		if err != nil || int64(len(rest)) < size {
+			return nil, fmt.Errorf("invalid chunk %q", header)
+		}
+		if size == 0 {
+			return data, nil
+		}
+		data = append(data, rest[:size]...)
+		body = bytes.TrimPrefix(rest[size:], []byte("\r\n"))
+	}
+}
+
+func TestS3Archive(t *testing.T) {
+	store := &fakeS3{objects: make(map[string][]byte), headers: make(map[string]http.Header)}
+	server := httptest.NewServer(store)
+	defer server.Close()
+
+	archive, err := NewS3Archive(S3Config{
+		Endpoint:        server.URL,
+		Bucket:          "archive",
+		Prefix:          "/bridge/",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "s3://archive/bridge/web/exec-1/logs.txt", archive.Location("web/exec-1/logs.txt"))
+
+	entry, err := putArchiveBytes(context.Background(), archive, "web/exec-1/logs.txt", "text/plain", []byte("done\n"))
+	require.NoError(t, err)
+
+	// Objects are stored path-style below the prefix, with their digest
+	assert.Equal(t, []byte("done\n"), store.objects["/archive/bridge/web/exec-1/logs.txt"])
+	headers := store.headers["/archive/bridge/web/exec-1/logs.txt"]
+	assert.Equal(t, "text/plain", headers.Get("Content-Type"))
+	assert.Equal(t, entry.SHA256, headers.Get("X-Amz-Meta-Sha256"))
+
+	err = archive.Put(context.Background(), ArchiveObject{Key: "../escape", Body: bytes.NewReader(nil)})
+	assert.ErrorContains(t, err, "invalid archive key")
+
+	// Rejected uploads are errors
+	denied, err := NewS3Archive(S3Config{Endpoint: server.URL, Bucket: "archive", AccessKeyID: "OTHER", SecretAccessKey: "secret"})
+	require.NoError(t, err)
+	_, err = putArchiveBytes(context.Background(), denied, "web/exec-1/logs.txt", "text/plain", []byte("done\n"))
+	assert.ErrorContains(t, err, "upload web/exec-1/logs.txt")
+}
+
+func TestNewS3ArchiveConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  S3Config
+		wantErr string
+	}{
+		{name: "no bucket", config: S3Config{AccessKeyID: "AKID", SecretAccessKey: "secret"}, wantErr: "S3 bucket is required"},
+		{name: "no credentials", config: S3Config{Bucket: "archive"}, wantErr: "S3 credentials are required"},
+		{
+			name:    "endpoint with a path",
+			config:  S3Config{Endpoint: "http://localhost:9000/storage", Bucket: "archive", AccessKeyID: "AKID", SecretAccessKey: "secret"},
+			wantErr: `invalid S3 endpoint "http://localhost:9000/storage"`,
+		},
+		{name: "default endpoint", config: S3Config{Region: "eu-west-1", Bucket: "archive", AccessKeyID: "AKID", SecretAccessKey: "secret"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewS3Archive(tt.config)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
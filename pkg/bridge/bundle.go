@@ -0,0 +1,175 @@
+package bridge
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BundleKind is the unit kind of a multi-file workflow bundle
+const BundleKind = "ActionsBundle"
+
+// AnnotationEntrypoint overrides the workflow a bundle runs
+const AnnotationEntrypoint = "actions.confighub.com/entrypoint"
+
+// DefaultWorkflowFile is where a single-workflow unit is written in the workspace
+const DefaultWorkflowFile = ".github/workflows/workflow.yml"
+
+// workflowsDir is the directory act and GitHub read workflows from
+const workflowsDir = ".github/workflows"
+
+// Bundle is a set of files laid out in the workspace before an execution:
+// workflows, reusable workflows, local actions and scripts. Paths are
+// relative to the workspace root and use forward slashes.
+//
+// A bundle unit looks like:
+//
+//	apiVersion: actions.confighub.com/v1alpha1
+//	kind: ActionsBundle
+//	metadata:
+//	  name: deploy
+//	entrypoint: .github/workflows/deploy.yml
+//	files:
+//	  .github/workflows/deploy.yml: |
+//	    ...
+//	  .github/actions/setup/action.yml: |
+//	    ...
+//	  scripts/deploy.sh: |
+//	    #!/bin/sh
+//	    ...
+type Bundle struct {
+	Entrypoint string            `yaml:"entrypoint"`
+	Files      map[string]string `yaml:"files"`
+}
+
+// IsBundle reports whether unit data is a multi-file bundle
+func IsBundle(data []byte) bool {
+	var header struct {
+		Kind string `yaml:"kind"`
+	}
+	if err := yaml.Unmarshal(data, &header); err != nil {
+		return false
+	}
+	return header.Kind == BundleKind
+}
+
+// ParseBundle parses and validates a bundle unit
+func ParseBundle(data []byte) (*Bundle, error) {
+	var bundle Bundle
+	if err := yaml.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("parse bundle: %w", err)
+	}
+	if err := bundle.Validate(); err != nil {
+		return nil, err
+	}
+	return &bundle, nil
+}
+
+// NewWorkflowBundle wraps a single workflow in a bundle
+func NewWorkflowBundle(workflow []byte) *Bundle {
+	return &Bundle{
+		Entrypoint: DefaultWorkflowFile,
+		Files:      map[string]string{DefaultWorkflowFile: string(workflow)},
+	}
+}
+
+// Validate checks file paths and resolves the entrypoint. A bundle with a
+// single workflow may leave the entrypoint empty.
+func (b *Bundle) Validate() error {
+	if len(b.Files) == 0 {
+		return fmt.Errorf("bundle has no files")
+	}
+	for name := range b.Files {
+		if err := validateRelativePath(name); err != nil {
+			return fmt.Errorf("invalid bundle file: %w", err)
+		}
+	}
+
+	workflows := b.Workflows()
+	if len(workflows) == 0 {
+		return fmt.Errorf("bundle has no workflows under %s", workflowsDir)
+	}
+
+	if b.Entrypoint == "" {
+		if len(workflows) > 1 {
+			return fmt.Errorf("bundle has %d workflows, set entrypoint to one of: %s", len(workflows), strings.Join(workflows, ", "))
+		}
+		b.Entrypoint = workflows[0]
+		return nil
+	}
+
+	// Accept a bare workflow file name as well as a full path
+	if !strings.Contains(b.Entrypoint, "/") {
+		b.Entrypoint = path.Join(workflowsDir, b.Entrypoint)
+	}
+	if !isWorkflowPath(b.Entrypoint) {
+		return fmt.Errorf("entrypoint %s is not a workflow under %s", b.Entrypoint, workflowsDir)
+	}
+	if _, ok := b.Files[b.Entrypoint]; !ok {
+		return fmt.Errorf("entrypoint %s not found in bundle", b.Entrypoint)
+	}
+	return nil
+}
+
+// Workflows returns the paths of the workflow files in the bundle, sorted
+func (b *Bundle) Workflows() []string {
+	var workflows []string
+	for name := range b.Files {
+		if isWorkflowPath(name) {
+			workflows = append(workflows, name)
+		}
+	}
+	sort.Strings(workflows)
+	return workflows
+}
+
+// Workflow returns the content of the entrypoint workflow
+func (b *Bundle) Workflow() []byte {
+	return []byte(b.Files[b.Entrypoint])
+}
+
+// ReadFile returns the content of a bundle file, for looking up local
+// actions and reusable workflows
+func (b *Bundle) ReadFile(name string) ([]byte, error) {
+	content, ok := b.Files[path.Clean(name)]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, os.ErrNotExist)
+	}
+	return []byte(content), nil
+}
+
+// isWorkflowPath reports whether a bundle path is a workflow file
+func isWorkflowPath(name string) bool {
+	ext := path.Ext(name)
+	return path.Dir(name) == workflowsDir && (ext == ".yml" || ext == ".yaml")
+}
+
+// isExecutable reports whether bundle file content should be written executable
+func isExecutable(name, content string) bool {
+	return strings.HasPrefix(content, "#!") || path.Ext(name) == ".sh"
+}
+
+// WriteBundle lays out all bundle files in the workspace
+func (ws *Workspace) WriteBundle(bundle *Bundle) error {
+	names := make([]string, 0, len(bundle.Files))
+	for name := range bundle.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		content := bundle.Files[name]
+		perm := os.FileMode(0644)
+		if isExecutable(name, content) {
+			perm = 0755
+		}
+		if err := ws.WriteFile(name, []byte(content), perm); err != nil {
+			return fmt.Errorf("write %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,370 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nektos/act/pkg/exprparser"
+	"github.com/nektos/act/pkg/model"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrConcurrencyCancelled is the cause of runs cancelled by a newer run in
+// the same concurrency group
+var ErrConcurrencyCancelled = errors.New("cancelled by a newer run")
+
+// runtimeContexts are available to job-level groups on GitHub but only known
+// once the run has started, so groups using them are not emulated
+var runtimeContexts = map[string]bool{"matrix": true, "needs": true, "strategy": true}
+
+// ConcurrencyGroup is an evaluated concurrency group of a run
+type ConcurrencyGroup struct {
+	Name             string
+	CancelInProgress bool
+	Job              string // Empty for the workflow-level group
+}
+
+// ConcurrencyGroups emulates GitHub concurrency groups for the runs of a
+// worker, across Apply calls. A group runs one execution at a time and keeps
+// at most one waiting: a newer run cancels the waiting one and, with
+// cancel-in-progress, the running one too. Groups are kept in memory and do
+// not coordinate separate worker processes.
+type ConcurrencyGroups struct {
+	mu      sync.Mutex
+	running map[string]*concurrencyRun
+	pending map[string]*concurrencyRun
+}
+
+// concurrencyRun is a run holding or waiting for its groups
+type concurrencyRun struct {
+	keys   []string
+	names  map[string]string // Group name by key
+	cancel context.CancelCauseFunc
+	ready  chan struct{}
+}
+
+// NewConcurrencyGroups creates an empty set of concurrency groups
+func NewConcurrencyGroups() *ConcurrencyGroups {
+	return &ConcurrencyGroups{
+		running: make(map[string]*concurrencyRun),
+		pending: make(map[string]*concurrencyRun),
+	}
+}
+
+// Acquire waits until a run holds all of its groups. Groups are scoped, so
+// that units using the same group name do not wait for each other. cancel
+// stops the run when a newer run supersedes it; a run superseded while
+// waiting returns an error wrapping ErrConcurrencyCancelled. The returned
+// function releases the groups.
+func (cg *ConcurrencyGroups) Acquire(ctx context.Context, scope string, groups []ConcurrencyGroup, cancel context.CancelCauseFunc) (func(), error) {
+	if len(groups) == 0 {
+		return func() {}, nil
+	}
+
+	run := &concurrencyRun{
+		names:  make(map[string]string),
+		cancel: cancel,
+		ready:  make(chan struct{}),
+	}
+	cancelInProgress := make(map[string]bool)
+	for _, group := range groups {
+		key := scope + "\x00" + group.Name
+		if _, ok := run.names[key]; !ok {
+			run.keys = append(run.keys, key)
+			run.names[key] = group.Name
+		}
+		cancelInProgress[key] = cancelInProgress[key] || group.CancelInProgress
+	}
+
+	cg.mu.Lock()
+	for _, key := range run.keys {
+		if older := cg.pending[key]; older != nil {
+			cg.dequeue(older)
+			older.cancel(fmt.Errorf("%w in concurrency group %s", ErrConcurrencyCancelled, run.names[key]))
+		}
+		if running := cg.running[key]; running != nil && cancelInProgress[key] {
+			running.cancel(fmt.Errorf("%w in concurrency group %s", ErrConcurrencyCancelled, run.names[key]))
+		}
+		cg.pending[key] = run
+	}
+	cg.promote(run)
+	cg.mu.Unlock()
+
+	release := func() { cg.release(run) }
+	select {
+	case <-run.ready:
+		return release, nil
+	case <-ctx.Done():
+	}
+
+	cg.mu.Lock()
+	select {
+	case <-run.ready:
+		// Promoted just as the context was done
+		cg.mu.Unlock()
+		release()
+	default:
+		cg.dequeue(run)
+		cg.mu.Unlock()
+	}
+	if cause := context.Cause(ctx); errors.Is(cause, ErrConcurrencyCancelled) {
+		return nil, cause
+	}
+	return nil, fmt.Errorf("cancelled while waiting for concurrency group: %w", ctx.Err())
+}
+
+// promote starts a waiting run once no other run holds any of its groups
+func (cg *ConcurrencyGroups) promote(run *concurrencyRun) {
+	for _, key := range run.keys {
+		if holder := cg.running[key]; holder != nil && holder != run {
+			return
+		}
+	}
+	for _, key := range run.keys {
+		cg.running[key] = run
+		if cg.pending[key] == run {
+			delete(cg.pending, key)
+		}
+	}
+	close(run.ready)
+}
+
+// dequeue removes a waiting run
+func (cg *ConcurrencyGroups) dequeue(run *concurrencyRun) {
+	for _, key := range run.keys {
+		if cg.pending[key] == run {
+			delete(cg.pending, key)
+		}
+	}
+}
+
+// release frees the groups of a finished run and starts the runs waiting
+// for them
+func (cg *ConcurrencyGroups) release(run *concurrencyRun) {
+	cg.mu.Lock()
+	defer cg.mu.Unlock()
+
+	for _, key := range run.keys {
+		if cg.running[key] == run {
+			delete(cg.running, key)
+		}
+	}
+	for _, key := range run.keys {
+		if next := cg.pending[key]; next != nil {
+			cg.promote(next)
+		}
+	}
+}
+
+// workflowConcurrency evaluates the concurrency groups of a workflow and of
+// the jobs that are planned to run. Job groups that use contexts only known
+// while the workflow runs are left out and described in notes.
+func workflowConcurrency(data []byte, jobs []string, env *exprparser.EvaluationEnvironment) ([]ConcurrencyGroup, []string, error) {
+	root, err := parseWorkflowNode(data)
+	if err != nil || root == nil {
+		return nil, nil, err
+	}
+
+	var groups []ConcurrencyGroup
+	if _, node := mappingValue(root, "concurrency"); node != nil {
+		group, err := evaluateConcurrency(node, env)
+		if err != nil {
+			return nil, nil, fmt.Errorf("workflow concurrency: %w", err)
+		}
+		if group.Name != "" {
+			groups = append(groups, group)
+		}
+	}
+
+	var notes []string
+	_, jobNodes := mappingValue(root, "jobs")
+	for _, id := range jobs {
+		_, job := mappingValue(jobNodes, id)
+		_, node := mappingValue(job, "concurrency")
+		if node == nil {
+			continue
+		}
+		if used := runtimeContextsIn(node); len(used) > 0 {
+			notes = append(notes, fmt.Sprintf("concurrency group of job %s uses %s, which is only known while the workflow runs; the group is not emulated",
+				id, strings.Join(used, ", ")))
+			continue
+		}
+
+		// github.job is the job the group belongs to
+		jobEnv, github := *env, *env.Github
+		github.Job = id
+		jobEnv.Github = &github
+
+		group, err := evaluateConcurrency(node, &jobEnv)
+		if err != nil {
+			return nil, nil, fmt.Errorf("job %s concurrency: %w", id, err)
+		}
+		if group.Name != "" {
+			group.Job = id
+			groups = append(groups, group)
+		}
+	}
+	return groups, notes, nil
+}
+
+// evaluateConcurrency evaluates a concurrency setting: a group name, or a
+// mapping with group and cancel-in-progress
+func evaluateConcurrency(node *yaml.Node, env *exprparser.EvaluationEnvironment) (ConcurrencyGroup, error) {
+	var group ConcurrencyGroup
+	groupNode, cancelNode := node, (*yaml.Node)(nil)
+	if node.Kind == yaml.MappingNode {
+		_, groupNode = mappingValue(node, "group")
+		_, cancelNode = mappingValue(node, "cancel-in-progress")
+	}
+	if groupNode == nil || groupNode.Kind != yaml.ScalarNode {
+		return group, fmt.Errorf("line %d: group must be a string", node.Line)
+	}
+
+	name, err := interpolate(groupNode.Value, env)
+	if err != nil {
+		return group, fmt.Errorf("line %d: group: %w", groupNode.Line, err)
+	}
+	group.Name = strings.TrimSpace(name)
+
+	if cancelNode != nil {
+		if cancelNode.Kind != yaml.ScalarNode {
+			return group, fmt.Errorf("line %d: cancel-in-progress must be a boolean", cancelNode.Line)
+		}
+		if isExpression(cancelNode.Value) {
+			spans, _ := expressionSpans(strings.TrimSpace(cancelNode.Value))
+			value, err := evaluate(spans[0].Source, env)
+			if err != nil {
+				return group, fmt.Errorf("line %d: cancel-in-progress: %w", cancelNode.Line, err)
+			}
+			group.CancelInProgress = exprparser.IsTruthy(value)
+		} else if group.CancelInProgress, err = strconv.ParseBool(cancelNode.Value); err != nil {
+			return group, fmt.Errorf("line %d: cancel-in-progress must be a boolean, got %q", cancelNode.Line, cancelNode.Value)
+		}
+	}
+	return group, nil
+}
+
+// evaluate evaluates the source of an expression, without the ${{ }}, with
+// act's interpreter
+func evaluate(source string, env *exprparser.EvaluationEnvironment) (interface{}, error) {
+	return exprparser.NewInterpeter(env, exprparser.Config{}).Evaluate(source, exprparser.DefaultStatusCheckNone)
+}
+
+// interpolate replaces the ${{ }} expressions in a text with their values.
+// Like act, it rewrites the text into a format() call, so values are
+// converted to strings the way act converts them.
+func interpolate(text string, env *exprparser.EvaluationEnvironment) (string, error) {
+	spans, err := expressionSpans(text)
+	if err != nil || len(spans) == 0 {
+		return text, err
+	}
+
+	// Literal text is a string literal and a format string: quotes and
+	// braces are doubled
+	escape := strings.NewReplacer("'", "''", "{", "{{", "}", "}}")
+	var format strings.Builder
+	args := make([]string, 0, len(spans))
+	last := 0
+	for i, span := range spans {
+		format.WriteString(escape.Replace(text[last:span.Start]))
+		fmt.Fprintf(&format, "{%d}", i)
+		args = append(args, span.Source)
+		last = span.End
+	}
+	format.WriteString(escape.Replace(text[last:]))
+
+	value, err := evaluate(fmt.Sprintf("format('%s', %s)", format.String(), strings.Join(args, ", ")), env)
+	if err != nil {
+		return "", err
+	}
+	result, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("expected a string, got %T", value)
+	}
+	return result, nil
+}
+
+// runtimeContextsIn returns the run-time contexts the expressions below a
+// node refer to
+func runtimeContextsIn(node *yaml.Node) []string {
+	used := make(map[string]bool)
+	var walk func(*yaml.Node)
+	walk = func(node *yaml.Node) {
+		if node.Kind == yaml.ScalarNode {
+			spans, _ := expressionSpans(node.Value)
+			for _, span := range spans {
+				expr, err := parseExpression(span.Source)
+				if err != nil {
+					continue
+				}
+				for _, name := range expr.contexts() {
+					if runtimeContexts[name] {
+						used[name] = true
+					}
+				}
+			}
+		}
+		for _, child := range node.Content {
+			walk(child)
+		}
+	}
+	walk(node)
+	return sortedKeys(used)
+}
+
+// concurrencyEnvironment returns the environment concurrency groups are
+// evaluated in: github as act presents it for the event, inputs and vars
+func concurrencyEnvironment(execCtx *ExecutionContext, eventName, workflowName string, event map[string]interface{}) *exprparser.EvaluationEnvironment {
+	ref, _ := event["ref"].(string)
+	sha := fmt.Sprintf("%040d", execCtx.Metadata.Revision)
+	headRef, baseRef := "", ""
+	if pr, ok := event["pull_request"].(map[string]interface{}); ok {
+		ref = fmt.Sprintf("refs/pull/%v/merge", pr["number"])
+		if head, ok := pr["head"].(map[string]interface{}); ok {
+			headRef, _ = head["ref"].(string)
+		}
+		if base, ok := pr["base"].(map[string]interface{}); ok {
+			baseRef, _ = base["ref"].(string)
+		}
+	}
+	if ref == "" {
+		ref = "refs/heads/" + defaultBranch
+	}
+	refName := ref
+	for _, prefix := range []string{"refs/heads/", "refs/tags/"} {
+		refName = strings.TrimPrefix(refName, prefix)
+	}
+
+	repository := ""
+	if repo, ok := event["repository"].(map[string]interface{}); ok {
+		repository, _ = repo["full_name"].(string)
+	}
+
+	inputs, _ := event["inputs"].(map[string]interface{})
+	if inputs == nil {
+		inputs = map[string]interface{}{}
+	}
+
+	return &exprparser.EvaluationEnvironment{
+		Github: &model.GithubContext{
+			EventName:       eventName,
+			Event:           event,
+			Workflow:        workflowName,
+			Ref:             ref,
+			RefName:         refName,
+			HeadRef:         headRef,
+			BaseRef:         baseRef,
+			Sha:             sha,
+			Repository:      repository,
+			RepositoryOwner: "confighub",
+			Actor:           execCtx.Metadata.Actor,
+		},
+		Env:    map[string]string{},
+		Inputs: inputs,
+		Vars:   map[string]string{},
+	}
+}
@@ -0,0 +1,226 @@
+package bridge
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testExecution is an execution of revision 7 of unit web
+func testExecution() *ExecutionContext {
+	return &ExecutionContext{Metadata: ExecutionMetadata{Unit: "web", Revision: 7, Actor: "alice"}}
+}
+
+func TestInterpolate(t *testing.T) {
+	env := concurrencyEnvironment(testExecution(), "pull_request", "Deploy", map[string]interface{}{
+		"pull_request": map[string]interface{}{
+			"number": 42,
+			"head":   map[string]interface{}{"ref": "feature"},
+			"base":   map[string]interface{}{"ref": "main"},
+		},
+		"inputs": map[string]interface{}{"count": 3.0, "force": true, "empty": nil},
+	})
+
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr string
+	}{
+		{name: "no expressions", text: "deploy", want: "deploy"},
+		{name: "github context", text: "${{ github.workflow }}-${{ github.ref }}", want: "Deploy-refs/pull/42/merge"},
+		{name: "event payload", text: "pr-${{ github.event.pull_request.number }}", want: "pr-42"},
+		{name: "head and base refs", text: "${{ github.head_ref }}->${{ github.base_ref }}", want: "feature->main"},
+		{name: "fallback", text: "${{ github.head_ref || github.run_id }}", want: "feature"},
+		{name: "inputs of every type", text: "${{ inputs.count }} ${{ inputs.force }} [${{ inputs.empty }}]", want: "3 true []"},
+		{name: "quotes and braces are literal", text: "it's {0} ${{ github.actor }}", want: "it's {0} alice"},
+		{name: "string literal with braces", text: "${{ format('{0}-}}', github.event_name) }}", want: "pull_request-}"},
+		{name: "sha from the revision", text: "${{ github.sha }}", want: "0000000000000000000000000000000000000007"},
+		{name: "unknown context", text: "${{ deploy.target }}", wantErr: "deploy"},
+		{name: "unclosed expression", text: "deploy-${{ github.ref", wantErr: "unclosed ${{"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := interpolate(tt.text, env)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	env := concurrencyEnvironment(testExecution(), "push", "ci", map[string]interface{}{"ref": "refs/tags/v1.2.0"})
+
+	tests := []struct {
+		source  string
+		want    interface{}
+		wantErr string
+	}{
+		{source: "github.event_name == 'push'", want: true},
+		{source: "github.ref_name", want: "v1.2.0"},
+		{source: "startsWith(github.ref, 'refs/tags/')", want: true},
+		{source: "github.repository_owner", want: "confighub"},
+		{source: "github.job", want: ""},
+		{source: "vars.ENVIRONMENT || 'staging'", want: "staging"},
+		{source: "fromJSON('[1, 2]')[1]", want: 2.0},
+		{source: "deploy()", wantErr: "deploy"},
+		{source: "github.ref ==", wantErr: "Failed to parse"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			got, err := evaluate(tt.source, env)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWorkflowConcurrency(t *testing.T) {
+	env := concurrencyEnvironment(testExecution(), "push", "Deploy", map[string]interface{}{})
+	workflow := []byte(`name: Deploy
+on: push
+concurrency: deploy-${{ github.ref_name }}
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    concurrency:
+      group: ${{ github.workflow }}-${{ github.job }}
+      cancel-in-progress: ${{ github.event_name == 'push' }}
+    steps:
+      - run: make
+  test:
+    runs-on: ubuntu-latest
+    strategy:
+      matrix:
+        node: [18, 20]
+    concurrency: test-${{ matrix.node }}
+    steps:
+      - run: make test
+  release:
+    runs-on: ubuntu-latest
+    concurrency: release
+    steps:
+      - run: make release
+`)
+
+	// Only planned jobs take part; job groups see their own github.job
+	groups, notes, err := workflowConcurrency(workflow, []string{"build", "test"}, env)
+	require.NoError(t, err)
+	assert.Equal(t, []ConcurrencyGroup{
+		{Name: "deploy-main"},
+		{Name: "Deploy-build", CancelInProgress: true, Job: "build"},
+	}, groups)
+	assert.Equal(t, []string{"concurrency group of job test uses matrix, which is only known while the workflow runs; the group is not emulated"}, notes)
+	assert.Empty(t, env.Github.Job, "the workflow environment is not changed")
+
+	groups, notes, err = workflowConcurrency([]byte("on: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - run: make\n"), []string{"build"}, env)
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+	assert.Empty(t, notes)
+
+	tests := []struct {
+		name     string
+		workflow string
+		wantErr  string
+	}{
+		{name: "group that is not a string", workflow: "on: push\nconcurrency: [deploy]\njobs: {}\n", wantErr: "workflow concurrency: line 2: group must be a string"},
+		{name: "invalid cancel-in-progress", workflow: "on: push\nconcurrency:\n  group: deploy\n  cancel-in-progress: sometimes\njobs: {}\n",
+			wantErr: `workflow concurrency: line 4: cancel-in-progress must be a boolean, got "sometimes"`},
+		{name: "invalid expression", workflow: "on: push\njobs:\n  build:\n    concurrency: ${{ github.ref == }}\n", wantErr: "job build concurrency: line 4: group"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := workflowConcurrency([]byte(tt.workflow), []string{"build"}, env)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestConcurrencyGroups(t *testing.T) {
+	groups := NewConcurrencyGroups()
+	deploy := []ConcurrencyGroup{{Name: "deploy-main"}}
+
+	// acquire starts a run in the background and reports when it holds its groups
+	acquire := func(scope string, group []ConcurrencyGroup) (context.Context, chan func(), chan error) {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		t.Cleanup(func() { cancel(nil) })
+		acquired, failed := make(chan func(), 1), make(chan error, 1)
+		go func() {
+			release, err := groups.Acquire(ctx, scope, group, cancel)
+			if err != nil {
+				failed <- err
+				return
+			}
+			acquired <- release
+		}()
+		return ctx, acquired, failed
+	}
+
+	// waiting waits until a run is queued for the deploy-main group of the unit
+	waiting := func() {
+		t.Helper()
+		require.Eventually(t, func() bool {
+			groups.mu.Lock()
+			defer groups.mu.Unlock()
+			return groups.pending["space/unit\x00deploy-main"] != nil
+		}, time.Second, time.Millisecond)
+	}
+
+	_, first, _ := acquire("space/unit", deploy)
+	releaseFirst := <-first
+
+	// Another unit with the same group name does not wait
+	_, other, _ := acquire("space/other", deploy)
+	(<-other)()
+
+	// A second run waits, and a third replaces it
+	_, second, secondFailed := acquire("space/unit", deploy)
+	waiting()
+	select {
+	case <-second:
+		t.Fatal("second run started while the first holds the group")
+	default:
+	}
+	thirdCtx, third, _ := acquire("space/unit", deploy)
+	err := <-secondFailed
+	assert.ErrorIs(t, err, ErrConcurrencyCancelled)
+	assert.Contains(t, err.Error(), "deploy-main")
+
+	releaseFirst()
+	releaseThird := <-third
+
+	// cancel-in-progress cancels the running run, which then releases the group
+	_, fourth, _ := acquire("space/unit", []ConcurrencyGroup{{Name: "deploy-main", CancelInProgress: true}})
+	<-thirdCtx.Done()
+	assert.ErrorIs(t, context.Cause(thirdCtx), ErrConcurrencyCancelled)
+	waiting()
+	select {
+	case <-fourth:
+		t.Fatal("fourth run started before the third released the group")
+	default:
+	}
+	releaseThird()
+	(<-fourth)()
+
+	// A run whose context ends while waiting leaves the queue
+	_, fifth, _ := acquire("space/unit", deploy)
+	releaseFifth := <-fifth
+	timeout, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	_, err = groups.Acquire(timeout, "space/unit", deploy, func(error) {})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	releaseFifth()
+	assert.Empty(t, groups.pending)
+	assert.Empty(t, groups.running)
+}
@@ -0,0 +1,305 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/client"
+)
+
+// Labels attached to every container act creates for the bridge
+const (
+	LabelExecutionID = "com.confighub.actions-bridge.execution"
+	LabelSpace       = "com.confighub.actions-bridge.space"
+	LabelUnit        = "com.confighub.actions-bridge.unit"
+)
+
+// Container runtimes reported in RuntimeInfo.Name
+const (
+	RuntimeDocker = "docker"
+	RuntimePodman = "podman"
+)
+
+// ContainerRuntime talks to the Docker-compatible engine used by act
+type ContainerRuntime struct {
+	socket string
+}
+
+// RuntimeInfo describes the container engine an execution ran against
+type RuntimeInfo struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	APIVersion string `json:"api_version,omitempty"`
+	OS         string `json:"os,omitempty"`
+	Arch       string `json:"arch,omitempty"`
+	Host       string `json:"host"`
+}
+
+// NewContainerRuntime creates a runtime client for the given socket path.
+// An empty socket falls back to DOCKER_HOST or the default Docker socket.
+func NewContainerRuntime(socket string) *ContainerRuntime {
+	return &ContainerRuntime{
+		socket: socket,
+	}
+}
+
+// Host returns the daemon host URL of the runtime, or "" for the default host
+func (cr *ContainerRuntime) Host() string {
+	return socketHost(cr.socket)
+}
+
+// socketHost turns a socket path into a daemon host URL
+func socketHost(socket string) string {
+	if socket == "" || strings.Contains(socket, "://") {
+		return socket
+	}
+	return "unix://" + socket
+}
+
+// processDockerHost is DOCKER_HOST as the process started with it. The
+// runtime's clients are always given their host, so they never see the
+// DOCKER_HOST executions point act at.
+var processDockerHost = os.Getenv("DOCKER_HOST")
+
+// clientHost returns the daemon host the runtime's clients connect to
+func (cr *ContainerRuntime) clientHost() string {
+	if host := cr.Host(); host != "" {
+		return host
+	}
+	if processDockerHost != "" {
+		return processDockerHost
+	}
+	return client.DefaultDockerHost
+}
+
+// client opens a new API client for the runtime
+func (cr *ContainerRuntime) client() (*client.Client, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithHost(cr.clientHost()), client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("create container client: %w", err)
+	}
+	return cli, nil
+}
+
+// RemoveContainers force-removes all containers carrying the given label and value
+func (cr *ContainerRuntime) RemoveContainers(ctx context.Context, label, value string) (int, error) {
+	cli, err := cr.client()
+	if err != nil {
+		return 0, err
+	}
+	defer cli.Close()
+
+	containers, err := cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", fmt.Sprintf("%s=%s", label, value))),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("list containers: %w", err)
+	}
+
+	removed := 0
+	for _, c := range containers {
+		if err := cli.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true, RemoveVolumes: true}); err != nil {
+			return removed, fmt.Errorf("remove container %s: %w", c.ID, err)
+		}
+		removed++
+	}
+
+	return removed, nil
+}
+
+// RemovedResources counts the container resources removed for a unit
+type RemovedResources struct {
+	Containers int `json:"containers"`
+	Networks   int `json:"networks"`
+	Volumes    int `json:"volumes"`
+}
+
+// RemoveResources force-removes all containers carrying every one of the
+// given labels, then the networks and named volumes they used. act does not
+// label networks and volumes, so they are found through the containers; ones
+// still used by other containers are left alone.
+func (cr *ContainerRuntime) RemoveResources(ctx context.Context, labels map[string]string) (*RemovedResources, error) {
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("no labels to select containers by")
+	}
+
+	cli, err := cr.client()
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	args := filters.NewArgs()
+	for label, value := range labels {
+		args.Add("label", fmt.Sprintf("%s=%s", label, value))
+	}
+	containers, err := cli.ContainerList(ctx, container.ListOptions{All: true, Filters: args})
+	if err != nil {
+		return nil, fmt.Errorf("list containers: %w", err)
+	}
+
+	removed := &RemovedResources{}
+	networks := make(map[string]bool)
+	volumes := make(map[string]bool)
+	for _, c := range containers {
+		if c.NetworkSettings != nil {
+			for name := range c.NetworkSettings.Networks {
+				if !isBuiltinNetwork(name) {
+					networks[name] = true
+				}
+			}
+		}
+		for _, m := range c.Mounts {
+			if m.Type == mount.TypeVolume && m.Name != "" {
+				volumes[m.Name] = true
+			}
+		}
+
+		if err := cli.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true, RemoveVolumes: true}); err != nil {
+			return removed, fmt.Errorf("remove container %s: %w", c.ID, err)
+		}
+		removed.Containers++
+	}
+
+	for name := range networks {
+		if err := cli.NetworkRemove(ctx, name); err == nil {
+			removed.Networks++
+		}
+	}
+	for name := range volumes {
+		if err := cli.VolumeRemove(ctx, name, false); err == nil {
+			removed.Volumes++
+		}
+	}
+
+	return removed, nil
+}
+
+// isBuiltinNetwork reports whether a network is one the engine itself provides
+func isBuiltinNetwork(name string) bool {
+	switch name {
+	case "bridge", "host", "none", "podman":
+		return true
+	}
+	return false
+}
+
+// Info checks that the runtime is reachable and reports its name and version
+func (cr *ContainerRuntime) Info(ctx context.Context) (*RuntimeInfo, error) {
+	cli, err := cr.client()
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	version, err := cli.ServerVersion(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("container runtime at %s is not reachable: %w", cli.DaemonHost(), err)
+	}
+
+	info := &RuntimeInfo{
+		Name:       RuntimeDocker,
+		Version:    version.Version,
+		APIVersion: version.APIVersion,
+		OS:         version.Os,
+		Arch:       version.Arch,
+		Host:       cli.DaemonHost(),
+	}
+
+	// Podman's Docker-compatible API names itself in the platform and components
+	if strings.Contains(strings.ToLower(version.Platform.Name), "podman") {
+		info.Name = RuntimePodman
+	}
+	for _, component := range version.Components {
+		if strings.Contains(strings.ToLower(component.Name), "podman") {
+			info.Name = RuntimePodman
+			info.Version = component.Version
+		}
+	}
+
+	return info, nil
+}
+
+// dockerHostLock shares the process-wide DOCKER_HOST between executions.
+// act v0.2.80 creates its container client from DOCKER_HOST and takes no
+// host option, so executions against the same host run concurrently while
+// a different host waits.
+type dockerHostLock struct {
+	mu       sync.Mutex
+	host     string
+	holders  int
+	released chan struct{} // Closed when the last holder releases the lock
+	restore  func()        // Restores the original DOCKER_HOST
+}
+
+// newDockerHostLock creates an unheld lock
+func newDockerHostLock() *dockerHostLock {
+	return &dockerHostLock{}
+}
+
+// acquire waits until host can be used and points DOCKER_HOST at it. An
+// empty host keeps the environment the process started with. It gives up
+// when ctx is done.
+func (l *dockerHostLock) acquire(ctx context.Context, host string) error {
+	for {
+		l.mu.Lock()
+		if l.holders == 0 {
+			l.host = host
+			l.released = make(chan struct{})
+			if host != "" {
+				l.restore = setDockerHost(host)
+			}
+		}
+		if l.host == host {
+			l.holders++
+			l.mu.Unlock()
+			return nil
+		}
+		released := l.released
+		l.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			if host == "" {
+				host = "default"
+			}
+			return fmt.Errorf("wait for container host %s: %w", host, context.Cause(ctx))
+		}
+	}
+}
+
+// release gives up a hold on the current host
+func (l *dockerHostLock) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.holders--
+	if l.holders == 0 {
+		if l.restore != nil {
+			l.restore()
+			l.restore = nil
+		}
+		close(l.released)
+	}
+}
+
+// setDockerHost points DOCKER_HOST at host and returns a function restoring it
+func setDockerHost(host string) func() {
+	previous, wasSet := os.LookupEnv("DOCKER_HOST")
+	os.Setenv("DOCKER_HOST", host)
+	return func() {
+		if wasSet {
+			os.Setenv("DOCKER_HOST", previous)
+		} else {
+			os.Unsetenv("DOCKER_HOST")
+		}
+	}
+}
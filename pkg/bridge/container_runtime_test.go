@@ -0,0 +1,121 @@
+package bridge
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSocketHost(t *testing.T) {
+	tests := map[string]string{
+		"":                            "",
+		"/run/podman/podman.sock":     "unix:///run/podman/podman.sock",
+		"unix:///var/run/docker.sock": "unix:///var/run/docker.sock",
+		"tcp://10.0.0.5:2375":         "tcp://10.0.0.5:2375",
+	}
+	for socket, want := range tests {
+		assert.Equal(t, want, socketHost(socket), socket)
+	}
+}
+
+func TestRuntimeClientHost(t *testing.T) {
+	// A concurrent execution pointing act elsewhere does not move the clients
+	t.Setenv("DOCKER_HOST", "tcp://elsewhere:2375")
+
+	cli, err := NewContainerRuntime("/run/podman/podman.sock").client()
+	require.NoError(t, err)
+	assert.Equal(t, "unix:///run/podman/podman.sock", cli.DaemonHost())
+	cli.Close()
+
+	cli, err = NewContainerRuntime("").client()
+	require.NoError(t, err)
+	want := processDockerHost
+	if want == "" {
+		want = client.DefaultDockerHost
+	}
+	assert.Equal(t, want, cli.DaemonHost())
+	cli.Close()
+}
+
+func TestDockerHostLock(t *testing.T) {
+	original, wasSet := os.LookupEnv("DOCKER_HOST")
+	lock := newDockerHostLock()
+	ctx := context.Background()
+
+	// Executions against the same host share it
+	require.NoError(t, lock.acquire(ctx, "unix:///run/a.sock"))
+	require.NoError(t, lock.acquire(ctx, "unix:///run/a.sock"))
+	assert.Equal(t, "unix:///run/a.sock", os.Getenv("DOCKER_HOST"))
+
+	// Another host waits until every holder released the lock
+	acquired := make(chan error, 1)
+	go func() {
+		acquired <- lock.acquire(ctx, "unix:///run/b.sock")
+	}()
+	lock.release()
+	select {
+	case <-acquired:
+		t.Fatal("acquired while the lock was held for another host")
+	case <-time.After(20 * time.Millisecond):
+	}
+	lock.release()
+	require.NoError(t, <-acquired)
+	assert.Equal(t, "unix:///run/b.sock", os.Getenv("DOCKER_HOST"))
+
+	// Waiting stops when the execution is cancelled
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	err := lock.acquire(cancelled, "unix:///run/a.sock")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Contains(t, err.Error(), "wait for container host unix:///run/a.sock")
+
+	// The last release restores the environment
+	lock.release()
+	value, set := os.LookupEnv("DOCKER_HOST")
+	assert.Equal(t, wasSet, set)
+	assert.Equal(t, original, value)
+
+	// The default host leaves the environment alone
+	require.NoError(t, lock.acquire(ctx, ""))
+	value, set = os.LookupEnv("DOCKER_HOST")
+	assert.Equal(t, wasSet, set)
+	assert.Equal(t, original, value)
+	lock.release()
+}
+
+func TestRemoveResources(t *testing.T) {
+	// An empty engine that reports the filters containers are listed with
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	listed := make(chan string, 1)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("API-Version", "1.41")
+		if strings.HasSuffix(r.URL.Path, "/containers/json") {
+			listed <- r.URL.Query().Get("filters")
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte("[]"))
+		}
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	// Containers must carry every label, so a unit's slug in another space does not match
+	runtime := NewContainerRuntime(socket)
+	removed, err := runtime.RemoveResources(context.Background(), map[string]string{LabelSpace: "platform", LabelUnit: "web"})
+	require.NoError(t, err)
+	assert.Equal(t, &RemovedResources{}, removed)
+	assert.JSONEq(t, `{"label": {"com.confighub.actions-bridge.space=platform": true, "com.confighub.actions-bridge.unit=web": true}}`, <-listed)
+
+	_, err = runtime.RemoveResources(context.Background(), nil)
+	assert.ErrorContains(t, err, "no labels")
+}
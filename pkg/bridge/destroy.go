@@ -0,0 +1,35 @@
+package bridge
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AnnotationDestroyJob names the jobs Destroy runs to tear down what a unit
+// deployed, comma-separated. The jobs are left out of Apply runs.
+const AnnotationDestroyJob = "actions.confighub.com/destroy-job"
+
+// EventDestroy is the pseudo-event of teardown runs. A workflow with this
+// trigger is run by Destroy instead of Apply.
+const EventDestroy = "destroy"
+
+// DestroySummary reports what Destroy ran and removed for a unit
+type DestroySummary struct {
+	TeardownExecutionID string `json:"teardown_execution_id,omitempty"`
+	TeardownWorkflow    string `json:"teardown_workflow,omitempty"`
+	Executions          int    `json:"executions"`
+	Workspaces          int    `json:"workspaces"`
+	Artifacts           int    `json:"artifacts"`
+	RemovedResources
+}
+
+// String describes the summary in one line
+func (ds *DestroySummary) String() string {
+	var parts []string
+	if ds.TeardownExecutionID != "" {
+		parts = append(parts, fmt.Sprintf("ran teardown %s", ds.TeardownWorkflow))
+	}
+	parts = append(parts, fmt.Sprintf("removed %d execution(s), %d workspace(s), %d artifact(s), %d container(s), %d network(s), %d volume(s)",
+		ds.Executions, ds.Workspaces, ds.Artifacts, ds.Containers, ds.Networks, ds.Volumes))
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,206 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/confighub/sdk/bridge-worker/api"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// statusRecorder is a worker context that keeps the statuses sent to it
+type statusRecorder struct {
+	api.BridgeWorkerContext
+	ctx      context.Context
+	statuses []*api.ActionResult
+}
+
+func (sr *statusRecorder) Context() context.Context {
+	return sr.ctx
+}
+
+func (sr *statusRecorder) SendStatus(status *api.ActionResult) error {
+	sr.statuses = append(sr.statuses, status)
+	return nil
+}
+
+// last returns the final status sent
+func (sr *statusRecorder) last(t *testing.T) *api.ActionResult {
+	t.Helper()
+	require.NotEmpty(t, sr.statuses)
+	return sr.statuses[len(sr.statuses)-1]
+}
+
+// fakeDaemon serves an empty container engine on a unix socket and returns
+// the socket path
+func fakeDaemon(t *testing.T) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("API-Version", "1.41")
+		if strings.HasSuffix(r.URL.Path, "/containers/json") {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte("[]"))
+		}
+	})}
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+	return socket
+}
+
+func TestDestroySummary(t *testing.T) {
+	summary := &DestroySummary{Executions: 3, Workspaces: 1, Artifacts: 2, RemovedResources: RemovedResources{Containers: 4, Networks: 1}}
+	assert.Equal(t, "removed 3 execution(s), 1 workspace(s), 2 artifact(s), 4 container(s), 1 network(s), 0 volume(s)", summary.String())
+
+	summary.TeardownExecutionID = "exec-1"
+	summary.TeardownWorkflow = ".github/workflows/teardown.yml"
+	assert.True(t, strings.HasPrefix(summary.String(), "ran teardown .github/workflows/teardown.yml; removed 3 execution(s)"))
+
+	data, err := json.Marshal(summary)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"teardown_execution_id": "exec-1", "teardown_workflow": ".github/workflows/teardown.yml",
+		"executions": 3, "workspaces": 1, "artifacts": 2, "containers": 4, "networks": 1, "volumes": 0}`, string(data))
+}
+
+func TestDestroy(t *testing.T) {
+	bridge, err := NewActionsBridge(t.TempDir())
+	require.NoError(t, err)
+	history := bridge.actRunner.History()
+	space := uuid.New()
+
+	// An applied unit without teardown jobs, with a workspace kept after a failure
+	setup := func(t *testing.T) {
+		t.Helper()
+		record := &ExecutionRecord{ID: "exec-1", UnitID: "web", Space: space.String(), StartTime: time.Now(), ConfigData: []byte(lifecycleWorkflow)}
+		require.NoError(t, history.Save(record))
+		_, err := bridge.workspaceManager.CreateUnitWorkspace("op-1", space.String(), "web")
+		require.NoError(t, err)
+	}
+	payload := func(socket string) api.BridgeWorkerPayload {
+		params, _ := json.Marshal(map[string]string{"socket": socket})
+		return api.BridgeWorkerPayload{SpaceID: space, UnitSlug: "web", TargetParams: params}
+	}
+
+	t.Run("releases the unit", func(t *testing.T) {
+		setup(t)
+		// A unit of another space with the same slug is left alone
+		other, err := bridge.workspaceManager.CreateUnitWorkspace("op-2", uuid.New().String(), "web")
+		require.NoError(t, err)
+		defer bridge.workspaceManager.RemoveWorkspace(other.ID)
+
+		ctx := &statusRecorder{ctx: context.Background()}
+		require.NoError(t, bridge.Destroy(ctx, payload(fakeDaemon(t))))
+
+		status := ctx.last(t)
+		assert.Equal(t, api.ActionStatusCompleted, status.Status)
+		assert.Equal(t, api.ActionResultDestroyCompleted, status.Result)
+		var state LiveState
+		require.NoError(t, json.Unmarshal(status.LiveState, &state))
+		assert.Equal(t, "destroyed", state.Status)
+		assert.Equal(t, &DestroySummary{Executions: 1, Workspaces: 1}, state.Destroyed)
+
+		_, err = history.Latest(space.String(), "web")
+		assert.ErrorIs(t, err, ErrNoExecutions)
+		assert.Empty(t, bridge.workspaceManager.UnitWorkspaces(space.String(), "web"))
+		assert.Len(t, bridge.workspaceManager.UnitWorkspaces(other.Space, "web"), 1)
+	})
+
+	t.Run("keeps everything when containers cannot be removed", func(t *testing.T) {
+		setup(t)
+		ctx := &statusRecorder{ctx: context.Background()}
+		require.NoError(t, bridge.Destroy(ctx, payload(filepath.Join(t.TempDir(), "missing.sock"))))
+
+		status := ctx.last(t)
+		assert.Equal(t, api.ActionStatusFailed, status.Status)
+		assert.Equal(t, api.ActionResultDestroyFailed, status.Result)
+		assert.Contains(t, status.Message, "Failed to remove containers")
+		_, err := history.Latest(space.String(), "web")
+		assert.NoError(t, err)
+	})
+
+	t.Run("waits for the unit's other operations", func(t *testing.T) {
+		unlock, err := bridge.unitLocks.acquire(context.Background(), unitKey(payload("")), false)
+		require.NoError(t, err)
+		defer unlock()
+
+		cancelled, cancel := context.WithCancel(context.Background())
+		cancel()
+		ctx := &statusRecorder{ctx: cancelled}
+		require.NoError(t, bridge.Destroy(ctx, payload(fakeDaemon(t))))
+
+		status := ctx.last(t)
+		assert.Equal(t, api.ActionResultDestroyFailed, status.Result)
+		assert.Contains(t, status.Message, "Failed to lock unit")
+		_, err = history.Latest(space.String(), "web")
+		assert.NoError(t, err, "history is kept while the unit is in use")
+		_, err = os.Stat(history.Dir())
+		assert.NoError(t, err)
+	})
+}
+
+func TestDestroyDuringApply(t *testing.T) {
+	bridge, err := NewActionsBridgeWithConfig(BridgeConfig{BaseDir: t.TempDir(), MaxConcurrent: 1})
+	require.NoError(t, err)
+	space := uuid.New()
+
+	// The teardown takes the only execution slot; its unmapped runner label
+	// ends it before any container starts
+	unit := strings.Replace(teardownWorkflow, "ubuntu-latest", "gpu-unmapped", 1)
+	record := &ExecutionRecord{ID: "exec-1", UnitID: "web", Space: space.String(), StartTime: time.Now(), ConfigData: []byte(unit)}
+	require.NoError(t, bridge.actRunner.History().Save(record))
+	params, _ := json.Marshal(map[string]string{"socket": fakeDaemon(t)})
+	payload := api.BridgeWorkerPayload{SpaceID: space, UnitSlug: "web", TargetParams: params}
+
+	// A Refresh holds the unit, so Destroy waits for it before Apply arrives
+	unlock, err := bridge.unitLocks.acquire(context.Background(), unitKey(payload), false)
+	require.NoError(t, err)
+	destroyCtx := &statusRecorder{ctx: context.Background()}
+	destroyed := make(chan error, 1)
+	go func() { destroyed <- bridge.Destroy(destroyCtx, payload) }()
+	require.Eventually(t, func() bool {
+		bridge.unitLocks.mu.Lock()
+		defer bridge.unitLocks.mu.Unlock()
+		lock := bridge.unitLocks.units[unitKey(payload)]
+		return lock != nil && lock.waiting == 1
+	}, time.Second, time.Millisecond)
+
+	// Apply gets as far as waiting for the unit; it is cancelled once the
+	// unit is destroyed
+	applyContext, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dryRun, _ := json.Marshal(map[string]bool{"dry_run": true})
+	applied := make(chan error, 1)
+	go func() {
+		applied <- bridge.Apply(&statusRecorder{ctx: applyContext}, api.BridgeWorkerPayload{
+			SpaceID: space, UnitSlug: "web", Data: []byte(lifecycleWorkflow), TargetParams: dryRun,
+		})
+	}()
+	time.Sleep(20 * time.Millisecond)
+	unlock()
+
+	// Apply must not hold the slot the teardown needs while it waits for the unit
+	select {
+	case <-destroyed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Destroy waited for the execution slot held by Apply")
+	}
+	assert.Contains(t, destroyCtx.last(t).Message, "Teardown failed")
+	cancel()
+	select {
+	case <-applied:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Apply did not return")
+	}
+}
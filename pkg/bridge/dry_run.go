@@ -0,0 +1,258 @@
+package bridge
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/nektos/act/pkg/model"
+	"github.com/nektos/act/pkg/runner"
+)
+
+// ExecutionPlan describes what an execution would do, produced by dry runs
+type ExecutionPlan struct {
+	Event  string      `json:"event"`
+	Stages []PlanStage `json:"stages"`
+}
+
+// PlanStage is a set of jobs that would run in parallel
+type PlanStage struct {
+	Jobs []PlannedJob `json:"jobs"`
+}
+
+// PlannedJob describes a job that would run. Secrets and env are names only.
+type PlannedJob struct {
+	ID             string                   `json:"id"`
+	Name           string                   `json:"name"`
+	Needs          []string                 `json:"needs,omitempty"`
+	RunsOn         []string                 `json:"runs_on"`
+	Image          string                   `json:"image,omitempty"`
+	Matrix         []map[string]interface{} `json:"matrix,omitempty"`
+	Steps          []string                 `json:"steps"`
+	Actions        []string                 `json:"actions,omitempty"`
+	Secrets        []string                 `json:"secrets,omitempty"`
+	MissingSecrets []string                 `json:"missing_secrets,omitempty"`
+	Env            []string                 `json:"env,omitempty"`
+}
+
+// buildExecutionPlan describes an act plan from the job runs act reported
+// in dry-run mode. Jobs act did not run, such as jobs whose if condition is
+// false, are described from the workflow.
+func buildExecutionPlan(plan *model.Plan, config *runner.Config, workflowPath string, execCtx *ExecutionContext, jobs []JobResult) (*ExecutionPlan, error) {
+	data, err := os.ReadFile(workflowPath)
+	if err != nil {
+		return nil, fmt.Errorf("read workflow: %w", err)
+	}
+	referenced, err := referencedSecrets(data)
+	if err != nil {
+		return nil, err
+	}
+
+	execPlan := &ExecutionPlan{
+		Event:  config.EventName,
+		Stages: []PlanStage{},
+	}
+
+	for _, stage := range plan.Stages {
+		planStage := PlanStage{Jobs: []PlannedJob{}}
+
+		for _, run := range stage.Runs {
+			job := run.Job()
+			if job == nil {
+				continue
+			}
+
+			planned := PlannedJob{
+				ID:     run.JobID,
+				Name:   run.String(),
+				Needs:  job.Needs(),
+				RunsOn: job.RunsOn(),
+				Image:  resolvePlatformImage(config.Platforms, job.RunsOn()),
+				Steps:  []string{},
+			}
+
+			// Matrix combinations and step names as act evaluated them
+			var started *JobResult
+			for i := range jobs {
+				if jobs[i].ID != run.JobID || jobs[i].StartedAt == nil {
+					continue
+				}
+				if started == nil {
+					started = &jobs[i]
+				}
+				if jobs[i].Matrix != nil {
+					planned.Matrix = append(planned.Matrix, jobs[i].Matrix)
+				}
+			}
+			if started == nil && job.Strategy != nil && len(job.Matrix()) > 0 {
+				if matrixes, err := job.GetMatrixes(); err == nil {
+					planned.Matrix = filterMatrixes(matrixes, config.Matrix)
+				}
+			}
+
+			env := make(map[string]bool)
+			for name := range execCtx.Environment {
+				env[name] = true
+			}
+			if run.Workflow != nil {
+				for name := range run.Workflow.Env {
+					env[name] = true
+				}
+			}
+			for name := range job.Environment() {
+				env[name] = true
+			}
+
+			actions := make(map[string]bool)
+			if job.Uses != "" && !strings.HasPrefix(job.Uses, "./") {
+				actions[job.Uses] = true
+			}
+
+			for _, step := range job.Steps {
+				if step == nil {
+					continue
+				}
+				for name := range step.Environment() {
+					env[name] = true
+				}
+				if step.Uses != "" && !strings.HasPrefix(step.Uses, "./") {
+					actions[step.Uses] = true
+				}
+			}
+			if started != nil {
+				for _, step := range started.Steps {
+					planned.Steps = append(planned.Steps, step.Name)
+				}
+			} else {
+				for _, step := range job.Steps {
+					if step != nil {
+						planned.Steps = append(planned.Steps, step.String())
+					}
+				}
+			}
+
+			// Workflow-level expressions, such as the workflow env, apply to every job
+			secrets := make(map[string]bool)
+			for _, scope := range []string{"", run.JobID} {
+				for name := range referenced[scope] {
+					secrets[name] = true
+				}
+			}
+			for name := range secrets {
+				if _, ok := execCtx.Secrets[name]; !ok && name != "GITHUB_TOKEN" {
+					planned.MissingSecrets = append(planned.MissingSecrets, name)
+				}
+			}
+			sort.Strings(planned.MissingSecrets)
+
+			planned.Env = sortedKeys(env)
+			planned.Actions = sortedKeys(actions)
+			planned.Secrets = sortedKeys(secrets)
+			planStage.Jobs = append(planStage.Jobs, planned)
+		}
+
+		execPlan.Stages = append(execPlan.Stages, planStage)
+	}
+
+	return execPlan, nil
+}
+
+// referencedSecrets returns the secrets the expressions of a workflow refer
+// to, by job. Expressions outside any job are under the empty job ID.
+func referencedSecrets(data []byte) (map[string]map[string]bool, error) {
+	_, root, err := parseWorkflowTree(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse workflow: %w", err)
+	}
+
+	secrets := make(map[string]map[string]bool)
+	if root == nil {
+		return secrets, nil
+	}
+	eachReference(&LintWorkflow{Root: root, Source: data}, func(x *workflowExpression, ref exprReference) {
+		if ref.context != "secrets" || len(ref.path) == 0 {
+			return
+		}
+		job := ""
+		if x.job != nil {
+			job = x.job.ID
+		}
+		if secrets[job] == nil {
+			secrets[job] = make(map[string]bool)
+		}
+		secrets[job][ref.path[0]] = true
+	})
+	return secrets, nil
+}
+
+// JobCount returns the number of jobs in the plan
+func (p *ExecutionPlan) JobCount() int {
+	count := 0
+	for _, stage := range p.Stages {
+		count += len(stage.Jobs)
+	}
+	return count
+}
+
+// resolvePlatformImage returns the image act would use for a runs-on label set
+func resolvePlatformImage(platforms map[string]string, labels []string) string {
+	for _, label := range labels {
+		if image, ok := platforms[strings.ToLower(label)]; ok {
+			return image
+		}
+	}
+	return ""
+}
+
+// Summary renders the plan as human readable lines
+func (p *ExecutionPlan) Summary() []string {
+	lines := []string{fmt.Sprintf("Dry run plan for event %s:", p.Event)}
+
+	for i, stage := range p.Stages {
+		lines = append(lines, fmt.Sprintf("Stage %d:", i+1))
+		for _, job := range stage.Jobs {
+			image := job.Image
+			if image == "" {
+				image = "no image mapped"
+			}
+			lines = append(lines, fmt.Sprintf("  Job %s (%s) on %s [%s]", job.ID, job.Name, strings.Join(job.RunsOn, ","), image))
+			if len(job.Needs) > 0 {
+				lines = append(lines, fmt.Sprintf("    needs: %s", strings.Join(job.Needs, ", ")))
+			}
+			if len(job.Matrix) > 0 {
+				lines = append(lines, fmt.Sprintf("    matrix: %d combinations", len(job.Matrix)))
+			}
+			for _, step := range job.Steps {
+				lines = append(lines, fmt.Sprintf("    - %s", step))
+			}
+			if len(job.Actions) > 0 {
+				lines = append(lines, fmt.Sprintf("    actions: %s", strings.Join(job.Actions, ", ")))
+			}
+			if len(job.Secrets) > 0 {
+				lines = append(lines, fmt.Sprintf("    secrets: %s", strings.Join(job.Secrets, ", ")))
+			}
+			if len(job.MissingSecrets) > 0 {
+				lines = append(lines, fmt.Sprintf("    missing secrets: %s", strings.Join(job.MissingSecrets, ", ")))
+			}
+			if len(job.Env) > 0 {
+				lines = append(lines, fmt.Sprintf("    env: %s", strings.Join(job.Env, ", ")))
+			}
+		}
+	}
+
+	return lines
+}
+
+// sortedKeys returns the keys of a set in sorted order
+func sortedKeys(set map[string]bool) []string {
+	if len(set) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
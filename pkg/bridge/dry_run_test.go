@@ -0,0 +1,117 @@
+package bridge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nektos/act/pkg/runner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const dryRunWorkflow = `name: deploy
+on: push
+env:
+  REGISTRY_TOKEN: ${{ secrets.REGISTRY_TOKEN }}
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    env:
+      NPM_TOKEN: ${{ secrets.NPM_TOKEN }}
+    strategy:
+      matrix:
+        node: [18, 20]
+    steps:
+      - name: Build on ${{ matrix.node }}
+        run: echo "secrets.NOT_A_REFERENCE"
+  deploy:
+    needs: build
+    if: github.ref == 'refs/heads/main'
+    runs-on: [self-hosted, linux]
+    steps:
+      - uses: actions/checkout@v4
+      - run: ./deploy.sh
+        env:
+          KEY: ${{ secrets.DEPLOY_KEY }}
+          TOKEN: ${{ secrets.GITHUB_TOKEN }}
+`
+
+func TestReferencedSecrets(t *testing.T) {
+	secrets, err := referencedSecrets([]byte(dryRunWorkflow))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]bool{
+		"":       {"REGISTRY_TOKEN": true},
+		"build":  {"NPM_TOKEN": true},
+		"deploy": {"DEPLOY_KEY": true, "GITHUB_TOKEN": true},
+	}, secrets)
+
+	secrets, err = referencedSecrets([]byte("# nothing yet\n"))
+	require.NoError(t, err)
+	assert.Empty(t, secrets)
+
+	_, err = referencedSecrets([]byte("jobs: [\n"))
+	assert.Error(t, err)
+}
+
+func TestBuildExecutionPlan(t *testing.T) {
+	workflowPath := filepath.Join(t.TempDir(), "deploy.yml")
+	require.NoError(t, os.WriteFile(workflowPath, []byte(dryRunWorkflow), 0644))
+	plan := planWorkflow(t, dryRunWorkflow)
+	config := &runner.Config{
+		EventName: "push",
+		Platforms: map[string]string{"ubuntu-latest": "node:16-bullseye"},
+		Matrix:    matrixFilter(map[string][]string{"node": {"20"}}),
+	}
+	execCtx := &ExecutionContext{
+		Secrets:     map[string]string{"NPM_TOKEN": "npm"},
+		Environment: map[string]string{"STAGE": "prod"},
+	}
+
+	// act ran the selected matrix combination of build and skipped deploy
+	started := time.Now()
+	jobs := []JobResult{
+		{
+			ID: "build", Name: "build (20)", Matrix: map[string]interface{}{"node": 20},
+			Conclusion: ConclusionSuccess, StartedAt: &started,
+			Steps: []StepResult{{Name: "Build on 20", Conclusion: ConclusionSuccess}},
+		},
+		{ID: "deploy", Name: "deploy", Conclusion: ConclusionSkipped, Steps: []StepResult{}},
+	}
+
+	execPlan, err := buildExecutionPlan(plan, config, workflowPath, execCtx, jobs)
+	require.NoError(t, err)
+	require.Len(t, execPlan.Stages, 2)
+	assert.Equal(t, 2, execPlan.JobCount())
+
+	build := execPlan.Stages[0].Jobs[0]
+	assert.Equal(t, "build", build.ID)
+	assert.Equal(t, "node:16-bullseye", build.Image)
+	assert.Equal(t, []map[string]interface{}{{"node": 20}}, build.Matrix)
+	assert.Equal(t, []string{"Build on 20"}, build.Steps)
+	assert.Equal(t, []string{"NPM_TOKEN", "REGISTRY_TOKEN"}, build.Secrets)
+	assert.Equal(t, []string{"REGISTRY_TOKEN"}, build.MissingSecrets)
+	assert.Equal(t, []string{"NPM_TOKEN", "REGISTRY_TOKEN", "STAGE"}, build.Env)
+
+	deploy := execPlan.Stages[1].Jobs[0]
+	assert.Equal(t, []string{"build"}, deploy.Needs)
+	assert.Equal(t, []string{"self-hosted", "linux"}, deploy.RunsOn)
+	assert.Empty(t, deploy.Image)
+	assert.Equal(t, []string{"actions/checkout@v4", "./deploy.sh"}, deploy.Steps)
+	assert.Equal(t, []string{"actions/checkout@v4"}, deploy.Actions)
+	assert.Equal(t, []string{"DEPLOY_KEY", "GITHUB_TOKEN", "REGISTRY_TOKEN"}, deploy.Secrets)
+	assert.Equal(t, []string{"DEPLOY_KEY", "REGISTRY_TOKEN"}, deploy.MissingSecrets)
+	assert.Equal(t, []string{"KEY", "REGISTRY_TOKEN", "STAGE", "TOKEN"}, deploy.Env)
+
+	summary := execPlan.Summary()
+	assert.Equal(t, "Dry run plan for event push:", summary[0])
+	assert.Contains(t, summary, "  Job deploy (deploy) on self-hosted,linux [no image mapped]")
+	assert.Contains(t, summary, "    missing secrets: DEPLOY_KEY, REGISTRY_TOKEN")
+
+	// Without act's runs the matrix is expanded and filtered from the workflow
+	execPlan, err = buildExecutionPlan(plan, config, workflowPath, execCtx, nil)
+	require.NoError(t, err)
+	assert.Len(t, execPlan.Stages[0].Jobs[0].Matrix, 1)
+	assert.Equal(t, []string{"Build on ${{ matrix.node }}"}, execPlan.Stages[0].Jobs[0].Steps)
+}
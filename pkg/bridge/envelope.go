@@ -0,0 +1,270 @@
+package bridge
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNoEnvelope is returned for unit data without a ConfigHub header, such
+// as a bare workflow
+var ErrNoEnvelope = errors.New("no ConfigHub envelope")
+
+// UnitAPIVersion is the apiVersion of the ConfigHub header of a unit
+const UnitAPIVersion = "actions.confighub.com/v1alpha1"
+
+// WorkflowKind is the unit kind of a single workflow
+const WorkflowKind = "Actions"
+
+// Envelope is a unit as stored in ConfigHub: a header with apiVersion, kind
+// and metadata, and the workflow or bundle it carries. In an Actions unit the
+// workflow follows the header:
+//
+//	apiVersion: actions.confighub.com/v1alpha1
+//	kind: Actions
+//	metadata:
+//	  name: hello-world
+//	  labels:
+//	    team: platform
+//	# The workflow follows
+//	name: Hello World
+//	on: push
+//	jobs:
+//	  ...
+//
+// An ActionsBundle unit has entrypoint and files instead; see Bundle.
+type Envelope struct {
+	APIVersion string
+	Kind       string
+	Metadata   EnvelopeMetadata
+	Workflow   []byte  // Actions units: the workflow text, comments included
+	Bundle     *Bundle // ActionsBundle units
+}
+
+// EnvelopeMetadata is the metadata of a unit
+type EnvelopeMetadata struct {
+	Name        string            `yaml:"name"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// envelopeHeader is the YAML layout of the header
+type envelopeHeader struct {
+	APIVersion string           `yaml:"apiVersion"`
+	Kind       string           `yaml:"kind"`
+	Metadata   EnvelopeMetadata `yaml:"metadata"`
+}
+
+// envelopeKeys are the top-level keys of the header
+var envelopeKeys = map[string]bool{"apiVersion": true, "kind": true, "metadata": true}
+
+// ParseEnvelope parses the ConfigHub header of a unit and the workflow or
+// bundle below it. Data without apiVersion and kind returns ErrNoEnvelope.
+func ParseEnvelope(data []byte) (*Envelope, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	var doc yaml.Node
+	if err := decoder.Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, ErrNoEnvelope
+		}
+		return nil, fmt.Errorf("parse unit: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, ErrNoEnvelope
+	}
+	root := doc.Content[0]
+
+	fields := make(map[string]*yaml.Node)
+	lines := make(map[string]int)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if key := root.Content[i].Value; envelopeKeys[key] {
+			fields[key] = root.Content[i+1]
+			lines[key] = root.Content[i].Line
+		}
+	}
+	if fields["apiVersion"] == nil && fields["kind"] == nil {
+		return nil, ErrNoEnvelope
+	}
+
+	var extra yaml.Node
+	if err := decoder.Decode(&extra); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unit header: unit must be a single YAML document")
+	}
+
+	envelope := &Envelope{}
+	switch node := fields["apiVersion"]; {
+	case node == nil:
+		return nil, fmt.Errorf("unit header: apiVersion is required (expected %s)", UnitAPIVersion)
+	case node.Kind != yaml.ScalarNode || node.Value != UnitAPIVersion:
+		return nil, fmt.Errorf("unit header: unsupported apiVersion %q at line %d (expected %s)", node.Value, lines["apiVersion"], UnitAPIVersion)
+	default:
+		envelope.APIVersion = node.Value
+	}
+
+	switch node := fields["kind"]; {
+	case node == nil:
+		return nil, fmt.Errorf("unit header: kind is required (expected %s or %s)", WorkflowKind, BundleKind)
+	case node.Kind != yaml.ScalarNode || (node.Value != WorkflowKind && node.Value != BundleKind):
+		return nil, fmt.Errorf("unit header: unsupported kind %q at line %d (expected %s or %s)", node.Value, lines["kind"], WorkflowKind, BundleKind)
+	default:
+		envelope.Kind = node.Value
+	}
+
+	switch node := fields["metadata"]; {
+	case node == nil:
+		return nil, fmt.Errorf("unit header: metadata is required")
+	case node.Kind != yaml.MappingNode:
+		return nil, fmt.Errorf("unit header: metadata at line %d must be a mapping", lines["metadata"])
+	default:
+		if err := node.Decode(&envelope.Metadata); err != nil {
+			return nil, fmt.Errorf("unit header: metadata at line %d: %w", lines["metadata"], err)
+		}
+	}
+	if envelope.Metadata.Name == "" {
+		return nil, fmt.Errorf("unit header: metadata.name is required")
+	}
+
+	if envelope.Kind == BundleKind {
+		var bundle Bundle
+		if err := root.Decode(&bundle); err != nil {
+			return nil, fmt.Errorf("parse bundle: %w", err)
+		}
+		if err := bundle.Validate(); err != nil {
+			return nil, err
+		}
+		envelope.Bundle = &bundle
+		return envelope, nil
+	}
+
+	workflow, err := workflowText(data, root)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(workflow)) == 0 {
+		return nil, fmt.Errorf("unit %s has no workflow below its header", envelope.Metadata.Name)
+	}
+	envelope.Workflow = workflow
+	return envelope, nil
+}
+
+// Files returns the files of the unit as a bundle: the workflow of an Actions
+// unit, or the files of a bundle with the entrypoint annotation applied
+func (e *Envelope) Files() (*Bundle, error) {
+	if e.Bundle == nil {
+		return NewWorkflowBundle(e.Workflow), nil
+	}
+	if entrypoint, ok := e.Metadata.Annotations[AnnotationEntrypoint]; ok {
+		e.Bundle.Entrypoint = entrypoint
+		if err := e.Bundle.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return e.Bundle, nil
+}
+
+// Encode renders the envelope: the header followed by the workflow text of
+// an Actions unit, or by the entrypoint and files of a bundle
+func (e *Envelope) Encode() ([]byte, error) {
+	header := envelopeHeader{
+		APIVersion: e.APIVersion,
+		Kind:       e.Kind,
+		Metadata:   e.Metadata,
+	}
+	if header.APIVersion == "" {
+		header.APIVersion = UnitAPIVersion
+	}
+
+	if e.Bundle != nil {
+		header.Kind = BundleKind
+		return encodeYAML(struct {
+			envelopeHeader `yaml:",inline"`
+			Bundle         `yaml:",inline"`
+		}{header, *e.Bundle})
+	}
+
+	header.Kind = WorkflowKind
+	data, err := encodeYAML(header)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, bytes.TrimPrefix(e.Workflow, []byte("---\n"))...), nil
+}
+
+// workflowText removes the header from the text of an Actions unit, keeping
+// the lines of the workflow as they are
+func workflowText(data []byte, root *yaml.Node) ([]byte, error) {
+	if root.Style&yaml.FlowStyle != 0 {
+		// JSON or a flow mapping has no lines to keep; re-encode the rest
+		workflow := &yaml.Node{Kind: yaml.MappingNode}
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if !envelopeKeys[root.Content[i].Value] {
+				workflow.Content = append(workflow.Content, root.Content[i], root.Content[i+1])
+			}
+		}
+		if len(workflow.Content) == 0 {
+			return nil, nil
+		}
+		clearStyle(workflow)
+		return encodeYAML(workflow)
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	drop := make([]bool, len(lines))
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if !envelopeKeys[root.Content[i].Value] {
+			continue
+		}
+		// A header field runs up to the next key, less the comments and
+		// blank lines right above it, which belong to the workflow
+		start, end := root.Content[i].Line-1, len(lines)
+		if i+2 < len(root.Content) {
+			end = root.Content[i+2].Line - 1
+		}
+		for end > start+1 && isBlankOrComment(lines[end-1]) {
+			end--
+		}
+		for l := start; l < end; l++ {
+			drop[l] = true
+		}
+	}
+
+	var workflow strings.Builder
+	for i, line := range lines {
+		if !drop[i] {
+			workflow.WriteString(line)
+		}
+	}
+	return []byte(strings.TrimPrefix(workflow.String(), "---\n")), nil
+}
+
+// clearStyle resets a node tree to the default block style
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
+
+// isBlankOrComment reports whether a line holds nothing but a comment
+func isBlankOrComment(line string) bool {
+	line = strings.TrimSpace(line)
+	return line == "" || strings.HasPrefix(line, "#")
+}
+
+// encodeYAML marshals a value with two-space indentation
+func encodeYAML(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,253 @@
+package bridge
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nektos/act/pkg/model"
+	"gopkg.in/yaml.v3"
+)
+
+// AnnotationEvent selects the GitHub event a unit is run with
+const AnnotationEvent = "actions.confighub.com/event"
+
+// GitHub events the bridge can simulate
+const (
+	EventWorkflowDispatch   = "workflow_dispatch"
+	EventPush               = "push"
+	EventPullRequest        = "pull_request"
+	EventRelease            = "release"
+	EventRepositoryDispatch = "repository_dispatch"
+	EventSchedule           = "schedule"
+	EventWorkflowCall       = "workflow_call"
+)
+
+// SupportedEvents lists the events the bridge synthesizes payloads for,
+// in the order they are preferred when a workflow does not name one
+var SupportedEvents = []string{
+	EventWorkflowDispatch,
+	EventPush,
+	EventPullRequest,
+	EventRelease,
+	EventRepositoryDispatch,
+	EventSchedule,
+	EventWorkflowCall,
+}
+
+// defaultBranch is the branch synthesized events refer to
+const defaultBranch = "main"
+
+// IsSupportedEvent reports whether the bridge can simulate an event
+func IsSupportedEvent(name string) bool {
+	for _, event := range SupportedEvents {
+		if event == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateEvent returns an error for events the bridge cannot simulate.
+// An empty name is valid and means the event is picked from the workflow.
+func ValidateEvent(name string) error {
+	if name == "" || IsSupportedEvent(name) {
+		return nil
+	}
+	return fmt.Errorf("unsupported event %q (supported: %s)", name, strings.Join(SupportedEvents, ", "))
+}
+
+// selectEvent returns the event to run a workflow with. A requested event must
+// be one the workflow triggers on; otherwise the workflow's first trigger in
+// the order of SupportedEvents is used. A workflow that triggers on none of
+// them cannot run.
+func selectEvent(planner model.WorkflowPlanner, requested string) (string, error) {
+	// Pseudo-events are only ever requested by Refresh and Destroy themselves
+	if !isLifecycleEvent(requested) {
+		if err := ValidateEvent(requested); err != nil {
+			return "", err
+		}
+	}
+
+	triggers := planner.GetEvents()
+	if requested != "" {
+		for _, trigger := range triggers {
+			if trigger == requested {
+				return requested, nil
+			}
+		}
+		return "", fmt.Errorf("workflow does not trigger on %s (triggers: %s)", requested, strings.Join(triggers, ", "))
+	}
+
+	for _, event := range SupportedEvents {
+		for _, trigger := range triggers {
+			if trigger == event {
+				return event, nil
+			}
+		}
+	}
+	if len(triggers) == 0 {
+		return "", fmt.Errorf("workflow has no triggers (supported: %s)", strings.Join(SupportedEvents, ", "))
+	}
+	return "", fmt.Errorf("workflow triggers on none of the supported events (triggers: %s; supported: %s)",
+		strings.Join(triggers, ", "), strings.Join(SupportedEvents, ", "))
+}
+
+// buildEventPayload synthesizes a webhook payload for an event, shaped like
+// the one GitHub would deliver for the unit's current revision
+func buildEventPayload(eventName string, meta ExecutionMetadata) map[string]interface{} {
+	sha := fmt.Sprintf("%040d", meta.Revision)
+	ref := "refs/heads/" + defaultBranch
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	repository := map[string]interface{}{
+		"name":           meta.Unit,
+		"full_name":      fmt.Sprintf("confighub/%s/%s", meta.Space, meta.Unit),
+		"default_branch": defaultBranch,
+		"private":        true,
+		"owner": map[string]interface{}{
+			"login": "confighub",
+		},
+	}
+	sender := map[string]interface{}{
+		"login": meta.Actor,
+		"type":  "User",
+	}
+	inputs := map[string]interface{}{
+		"space":    meta.Space,
+		"unit":     meta.Unit,
+		"revision": fmt.Sprintf("%d", meta.Revision),
+	}
+
+	event := map[string]interface{}{
+		"repository": repository,
+		"sender":     sender,
+	}
+
+	switch eventName {
+	case EventPush:
+		commit := map[string]interface{}{
+			"id":        sha,
+			"message":   fmt.Sprintf("ConfigHub revision %d of %s", meta.Revision, meta.Unit),
+			"timestamp": now,
+			"author": map[string]interface{}{
+				"name":     meta.Actor,
+				"username": meta.Actor,
+			},
+		}
+		event["ref"] = ref
+		event["before"] = fmt.Sprintf("%040d", 0)
+		event["after"] = sha
+		event["created"] = false
+		event["deleted"] = false
+		event["forced"] = false
+		event["head_commit"] = commit
+		event["commits"] = []interface{}{commit}
+		event["pusher"] = map[string]interface{}{
+			"name": meta.Actor,
+		}
+
+	case EventPullRequest:
+		event["action"] = "synchronize"
+		event["number"] = meta.Revision
+		event["pull_request"] = map[string]interface{}{
+			"number": meta.Revision,
+			"state":  "open",
+			"title":  fmt.Sprintf("ConfigHub revision %d of %s", meta.Revision, meta.Unit),
+			"draft":  false,
+			"merged": false,
+			"user":   sender,
+			"head": map[string]interface{}{
+				"ref":  fmt.Sprintf("confighub/%s", meta.Unit),
+				"sha":  sha,
+				"repo": repository,
+			},
+			"base": map[string]interface{}{
+				"ref":  defaultBranch,
+				"sha":  fmt.Sprintf("%040d", 0),
+				"repo": repository,
+			},
+		}
+
+	case EventRelease:
+		tag := fmt.Sprintf("v%d", meta.Revision)
+		event["action"] = "published"
+		event["release"] = map[string]interface{}{
+			"tag_name":         tag,
+			"name":             tag,
+			"target_commitish": defaultBranch,
+			"draft":            false,
+			"prerelease":       false,
+			"created_at":       now,
+			"published_at":     now,
+			"author":           sender,
+		}
+		event["ref"] = "refs/tags/" + tag
+
+	case EventRepositoryDispatch:
+		event["action"] = "confighub"
+		event["branch"] = defaultBranch
+		event["client_payload"] = inputs
+
+	case EventSchedule:
+		event["schedule"] = ""
+
+	case EventWorkflowCall:
+		event["ref"] = ref
+		event["inputs"] = inputs
+
+	case EventConfigHubRefresh, EventDestroy:
+		event["action"] = strings.TrimPrefix(eventName, "confighub-")
+		event["ref"] = ref
+		event["inputs"] = inputs
+		event["sha"] = sha
+
+	default:
+		event["action"] = EventWorkflowDispatch
+		event["ref"] = ref
+		event["inputs"] = inputs
+		event["sha"] = sha
+	}
+
+	return event
+}
+
+// mergeEventPayload applies custom payload fields on top of a synthesized
+// event. Inputs and client payloads are merged rather than replaced.
+func mergeEventPayload(event, custom map[string]interface{}) {
+	for k, v := range custom {
+		if k == "inputs" || k == "client_payload" {
+			base, baseOK := event[k].(map[string]interface{})
+			extra, extraOK := v.(map[string]interface{})
+			if baseOK && extraOK {
+				for name, value := range extra {
+					base[name] = value
+				}
+				continue
+			}
+		}
+		event[k] = v
+	}
+}
+
+// firstSchedule returns the first cron expression of a workflow's schedule trigger
+func firstSchedule(workflowPath string) (string, error) {
+	data, err := os.ReadFile(workflowPath)
+	if err != nil {
+		return "", fmt.Errorf("read workflow: %w", err)
+	}
+
+	var workflow struct {
+		On struct {
+			Schedule []struct {
+				Cron string `yaml:"cron"`
+			} `yaml:"schedule"`
+		} `yaml:"on"`
+	}
+	// Triggers given as a string or list have no cron expressions
+	if err := yaml.Unmarshal(data, &workflow); err != nil || len(workflow.On.Schedule) == 0 {
+		return "", nil
+	}
+	return workflow.On.Schedule[0].Cron, nil
+}
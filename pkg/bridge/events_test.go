@@ -0,0 +1,59 @@
+package bridge
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nektos/act/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectEvent(t *testing.T) {
+	tests := []struct {
+		name      string
+		on        string
+		requested string
+		want      string
+		wantErr   string
+	}{
+		{name: "requested trigger", on: "[push, pull_request]", requested: "pull_request", want: "pull_request"},
+		{name: "workflow_dispatch first", on: "[push, workflow_dispatch]", want: "workflow_dispatch"},
+		{name: "supported order", on: "[release, pull_request, push]", want: "push"},
+		{name: "unsupported triggers are passed over", on: "[issues, schedule]", want: "schedule"},
+		{name: "lifecycle event", on: "[push, confighub-refresh]", requested: EventConfigHubRefresh, want: EventConfigHubRefresh},
+		{
+			name:      "requested event the workflow does not trigger on",
+			on:        "[push, pull_request]",
+			requested: "release",
+			wantErr:   "workflow does not trigger on release (triggers: pull_request, push)",
+		},
+		{
+			name:      "requested event the bridge cannot simulate",
+			on:        "[issues]",
+			requested: "issues",
+			wantErr:   `unsupported event "issues"`,
+		},
+		{
+			name:    "no supported trigger",
+			on:      "[issues, destroy]",
+			wantErr: "workflow triggers on none of the supported events (triggers: destroy, issues; supported: workflow_dispatch,",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workflow := "on: " + tt.on + "\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - run: make\n"
+			planner, err := model.NewSingleWorkflowPlanner("workflow.yml", strings.NewReader(workflow))
+			require.NoError(t, err)
+
+			event, err := selectEvent(planner, tt.requested)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, event)
+		})
+	}
+}
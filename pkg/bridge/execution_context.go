@@ -1,14 +1,27 @@
 package bridge
 
+import "time"
+
 // ExecutionContext holds all the context for a workflow execution
 type ExecutionContext struct {
-	Workspace    *Workspace
-	ConfigData   []byte
-	Metadata     ExecutionMetadata
-	Secrets      map[string]string
-	Environment  map[string]string
-	EventPayload map[string]interface{}
-	DryRun       bool
+	Workspace       *Workspace
+	ConfigData      []byte
+	WorkflowFile    string // Entrypoint relative to the workspace root
+	Metadata        ExecutionMetadata
+	Secrets         map[string]string
+	Environment     map[string]string
+	EventName       string // Empty picks an event the workflow triggers on
+	EventPayload    map[string]interface{}
+	Jobs            []string            // Run only these jobs and the jobs they need
+	SkipJobs        []string            // Leave these jobs out of the run
+	Matrix          map[string][]string // Run only matrix combinations with these values
+	RunnerImages    RunnerImages        // Per-execution runs-on label mappings
+	ContainerSocket string              // Empty uses DOCKER_HOST or the default Docker socket
+	Platform        string              // Container architecture; empty uses the runner's platform
+	Network         string              // Network of job containers; empty uses act's default
+	DryRun          bool
+	ReadOnly        bool          // Verification run: sets CONFIGHUB_READ_ONLY and is not recorded
+	Timeout         time.Duration // Zero means DefaultExecutionTimeout
 }
 
 // ExecutionMetadata contains metadata about the execution
@@ -0,0 +1,247 @@
+package bridge
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ExportedFile is a repository file restored from a unit
+type ExportedFile struct {
+	Path       string // Slash-separated, relative to the repository root
+	Unit       string // Name of the unit the file came from
+	Entrypoint bool   // The unit's workflow, rather than a file it uses
+	Content    []byte
+}
+
+// ExportCheck is the compatibility check of an exported workflow
+type ExportCheck struct {
+	Path     string
+	Unit     string
+	Warnings []Warning
+}
+
+// documentSeparator matches a YAML document separator line
+var documentSeparator = regexp.MustCompile(`(?m)^---[ \t]*(#.*)?$`)
+
+// SplitUnits splits a multi-document YAML stream, such as the output of
+// import, into unit documents. Empty documents are dropped.
+func SplitUnits(data []byte) [][]byte {
+	var docs [][]byte
+	for _, doc := range documentSeparator.Split(string(data), -1) {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+		docs = append(docs, []byte(strings.TrimPrefix(doc, "\n")))
+	}
+	return docs
+}
+
+// ExportUnits restores the repository files of units: the workflow of an
+// Actions unit is written to its source-path annotation, or to
+// .github/workflows/<name>.yml, and a bundle is written file by file. Files
+// shared by several units must have the same content. Files are sorted by path.
+func ExportUnits(units ...[]byte) ([]ExportedFile, error) {
+	byPath := make(map[string]ExportedFile)
+	for i, data := range units {
+		name, entrypoint, files, err := exportUnit(data)
+		if err != nil {
+			if name == "" {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+			return nil, fmt.Errorf("export unit %s: %w", name, err)
+		}
+
+		for file, content := range files {
+			if existing, ok := byPath[file]; ok {
+				if !bytes.Equal(existing.Content, content) {
+					return nil, fmt.Errorf("%s is exported by both unit %s and unit %s with different content", file, existing.Unit, name)
+				}
+				if file == entrypoint && !existing.Entrypoint {
+					byPath[file] = ExportedFile{Path: file, Unit: name, Entrypoint: true, Content: content}
+				}
+				continue
+			}
+			byPath[file] = ExportedFile{Path: file, Unit: name, Entrypoint: file == entrypoint, Content: content}
+		}
+	}
+
+	files := make([]ExportedFile, 0, len(byPath))
+	for _, file := range byPath {
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files, nil
+}
+
+// exportUnit returns the name of a unit, the path of its workflow and its
+// files by repository path
+func exportUnit(data []byte) (string, string, map[string][]byte, error) {
+	envelope, err := ParseEnvelope(data)
+	if errors.Is(err, ErrNoEnvelope) {
+		return "", "", nil, fmt.Errorf("no %s header; export needs the unit name", UnitAPIVersion)
+	}
+	if err != nil {
+		return "", "", nil, err
+	}
+	name := envelope.Metadata.Name
+
+	if envelope.Bundle != nil {
+		files := make(map[string][]byte, len(envelope.Bundle.Files))
+		for file, content := range envelope.Bundle.Files {
+			files[file] = []byte(content)
+		}
+		return name, envelope.Bundle.Entrypoint, files, nil
+	}
+
+	file := envelope.Metadata.Annotations[AnnotationSourcePath]
+	if file == "" {
+		file = path.Join(workflowsDir, name+".yml")
+	}
+	if err := validateRelativePath(file); err != nil {
+		return name, "", nil, fmt.Errorf("invalid %s: %w", AnnotationSourcePath, err)
+	}
+	if !isWorkflowPath(file) {
+		return name, "", nil, fmt.Errorf("%s %s is not a workflow under %s", AnnotationSourcePath, file, workflowsDir)
+	}
+
+	workflow := envelope.Workflow
+	if !bytes.HasSuffix(workflow, []byte("\n")) {
+		workflow = append(workflow, '\n')
+	}
+	return name, file, map[string][]byte{file: workflow}, nil
+}
+
+// WriteExport writes exported files below root. Every unit's workflow must
+// run locally and survive a round trip: it is written to a scratch tree,
+// imported from there again and exported once more, and must come back as
+// the same files and the same workflow as in the unit. Nothing is written
+// below root unless every workflow passes.
+func WriteExport(root string, files []ExportedFile) ([]ExportCheck, error) {
+	scratch, err := os.MkdirTemp("", "actions-export-")
+	if err != nil {
+		return nil, fmt.Errorf("create scratch dir: %w", err)
+	}
+	defer os.RemoveAll(scratch)
+
+	if err := writeExportedFiles(scratch, files); err != nil {
+		return nil, err
+	}
+	checks, err := checkExport(scratch, files)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeExportedFiles(root, files); err != nil {
+		return nil, err
+	}
+	return checks, nil
+}
+
+// writeExportedFiles writes files below root
+func writeExportedFiles(root string, files []ExportedFile) error {
+	for _, file := range files {
+		if err := validateRelativePath(file.Path); err != nil {
+			return fmt.Errorf("invalid export path: %w", err)
+		}
+		target := filepath.Join(root, filepath.FromSlash(file.Path))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("create %s: %w", path.Dir(file.Path), err)
+		}
+		perm := os.FileMode(0644)
+		if isExecutable(file.Path, string(file.Content)) {
+			perm = 0755
+		}
+		if err := os.WriteFile(target, file.Content, perm); err != nil {
+			return fmt.Errorf("write %s: %w", file.Path, err)
+		}
+	}
+	return nil
+}
+
+// checkExport checks the unit workflows among files, written below root
+func checkExport(root string, files []ExportedFile) ([]ExportCheck, error) {
+	exported := make(map[string][]byte, len(files))
+	for _, file := range files {
+		exported[file.Path] = file.Content
+	}
+
+	checker := NewCompatibilityChecker()
+	var checks []ExportCheck
+	for _, file := range files {
+		if !file.Entrypoint {
+			continue
+		}
+
+		if _, err := parseWorkflowDocument(string(file.Content)); err != nil {
+			return nil, fmt.Errorf("workflow %s of unit %s does not parse: %w", file.Path, file.Unit, err)
+		}
+		if supported, reason := checker.IsWorkflowSupported(file.Content); !supported {
+			return nil, fmt.Errorf("workflow %s of unit %s cannot run locally: %s", file.Path, file.Unit, reason)
+		}
+		warnings := checker.CheckWorkflow(file.Content)
+
+		// Import the written workflow as the import command would
+		unit, err := importWorkflow(root, file.Path)
+		if err != nil {
+			return nil, fmt.Errorf("exported workflow %s does not import: %w", file.Path, err)
+		}
+		unit.Name = file.Unit
+		unit.Annotations[AnnotationSourcePath] = file.Path
+		data, err := unit.encode(root)
+		if err != nil {
+			return nil, fmt.Errorf("exported workflow %s does not import: %w", file.Path, err)
+		}
+
+		// Exporting the imported unit gives back the unit's workflow and
+		// exactly the exported files it uses, so the checker's verdict and
+		// warnings carry over to the repository
+		_, entrypoint, roundTrip, err := exportUnit(data)
+		if err != nil {
+			return nil, fmt.Errorf("exported workflow %s does not export again: %w", file.Path, err)
+		}
+		if entrypoint != file.Path {
+			return nil, fmt.Errorf("exported workflow %s does not round-trip: it comes back as %s", file.Path, entrypoint)
+		}
+		for p, content := range roundTrip {
+			if original, ok := exported[p]; !ok || !bytes.Equal(original, content) {
+				return nil, fmt.Errorf("exported workflow %s does not round-trip: %s differs from unit %s", file.Path, p, file.Unit)
+			}
+		}
+
+		checks = append(checks, ExportCheck{Path: file.Path, Unit: file.Unit, Warnings: warnings})
+	}
+	return checks, nil
+}
+
+// sameWarnings compares two sets of warnings regardless of order
+func sameWarnings(a, b []Warning) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortWarnings(a)
+	sortWarnings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// sortWarnings orders warnings by line, then message
+func sortWarnings(warnings []Warning) {
+	sort.Slice(warnings, func(i, j int) bool {
+		if warnings[i].Line != warnings[j].Line {
+			return warnings[i].Line < warnings[j].Line
+		}
+		return warnings[i].Message < warnings[j].Message
+	})
+}
@@ -0,0 +1,509 @@
+package bridge
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// exprNode is a node of a parsed ${{ }} expression. Offsets are byte offsets
+// into the expression source.
+type exprNode interface {
+	offset() int
+}
+
+type (
+	exprLiteral struct {
+		at    int
+		value interface{}
+	}
+	// exprContext is a named value such as github or matrix
+	exprContext struct {
+		at   int
+		name string
+	}
+	// exprProperty is target.name or target['name']
+	exprProperty struct {
+		at     int
+		target exprNode
+		name   string
+	}
+	exprIndex struct {
+		at     int
+		target exprNode
+		index  exprNode
+	}
+	// exprFilter is target.*
+	exprFilter struct {
+		at     int
+		target exprNode
+	}
+	exprCall struct {
+		at   int
+		name string // Lowercase
+		args []exprNode
+	}
+	exprNot struct {
+		at      int
+		operand exprNode
+	}
+	exprBinary struct {
+		at          int
+		op          string
+		left, right exprNode
+	}
+)
+
+func (n *exprLiteral) offset() int  { return n.at }
+func (n *exprContext) offset() int  { return n.at }
+func (n *exprProperty) offset() int { return n.at }
+func (n *exprIndex) offset() int    { return n.at }
+func (n *exprFilter) offset() int   { return n.at }
+func (n *exprCall) offset() int     { return n.at }
+func (n *exprNot) offset() int      { return n.at }
+func (n *exprBinary) offset() int   { return n.at }
+
+// expressionFunction is the number of arguments a function takes; max is -1
+// for variadic functions
+type expressionFunction struct {
+	min, max int
+}
+
+// expressionFunctions are the functions of the GitHub expression language,
+// by lowercase name
+var expressionFunctions = map[string]expressionFunction{
+	"contains":   {2, 2},
+	"startswith": {2, 2},
+	"endswith":   {2, 2},
+	"format":     {1, -1},
+	"join":       {1, 2},
+	"tojson":     {1, 1},
+	"fromjson":   {1, 1},
+	"hashfiles":  {1, -1},
+	"success":    {0, 0},
+	"always":     {0, 0},
+	"cancelled":  {0, 0},
+	"failure":    {0, 0},
+}
+
+// ExpressionError is an error in an expression at a byte offset of its source
+type ExpressionError struct {
+	Offset  int
+	Message string
+}
+
+func (e *ExpressionError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Message, e.Offset+1)
+}
+
+// expression is a parsed ${{ }} expression
+type expression struct {
+	source string
+	root   exprNode
+}
+
+// parseExpression parses the source of an expression, without the ${{ }}
+func parseExpression(source string) (*expression, error) {
+	tokens, err := lexExpression(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, &ExpressionError{Offset: tok.at, Message: fmt.Sprintf("unexpected %q", tok.value)}
+	}
+	return &expression{source: source, root: root}, nil
+}
+
+// expressionSpan is a ${{ }} expression within a text. Start and End are the
+// byte offsets of ${{ and of the end of }}.
+type expressionSpan struct {
+	Start, End int
+	Source     string // Between ${{ and }}
+}
+
+// expressionSpans finds the ${{ }} expressions in a text. A }} inside a
+// string literal does not end an expression.
+func expressionSpans(text string) ([]expressionSpan, error) {
+	var spans []expressionSpan
+	for from := 0; ; {
+		i := strings.Index(text[from:], "${{")
+		if i < 0 {
+			return spans, nil
+		}
+		start := from + i
+		end := -1
+		inString := false
+		for j := start + 3; j < len(text); j++ {
+			switch {
+			case text[j] == '\'':
+				inString = !inString
+			case !inString && strings.HasPrefix(text[j:], "}}"):
+				end = j
+			}
+			if end >= 0 {
+				break
+			}
+		}
+		if end < 0 {
+			return spans, &ExpressionError{Offset: start, Message: "unclosed ${{"}
+		}
+		spans = append(spans, expressionSpan{Start: start, End: end + 2, Source: text[start+3 : end]})
+		from = end + 2
+	}
+}
+
+// isExpression reports whether a text is a single ${{ }} expression and
+// nothing else
+func isExpression(text string) bool {
+	text = strings.TrimSpace(text)
+	spans, err := expressionSpans(text)
+	return err == nil && len(spans) == 1 && spans[0].Start == 0 && spans[0].End == len(text)
+}
+
+// contexts returns the named values an expression refers to, sorted
+func (e *expression) contexts() []string {
+	seen := make(map[string]bool)
+	walkExpression(e.root, func(node exprNode) {
+		if c, ok := node.(*exprContext); ok {
+			seen[c.name] = true
+		}
+	})
+	return sortedKeys(seen)
+}
+
+// walkExpression calls fn for every node of an expression tree, parents first
+func walkExpression(node exprNode, fn func(exprNode)) {
+	fn(node)
+	switch n := node.(type) {
+	case *exprProperty:
+		walkExpression(n.target, fn)
+	case *exprIndex:
+		walkExpression(n.target, fn)
+		walkExpression(n.index, fn)
+	case *exprFilter:
+		walkExpression(n.target, fn)
+	case *exprCall:
+		for _, arg := range n.args {
+			walkExpression(arg, fn)
+		}
+	case *exprNot:
+		walkExpression(n.operand, fn)
+	case *exprBinary:
+		walkExpression(n.left, fn)
+		walkExpression(n.right, fn)
+	}
+}
+
+// Tokens of the expression language
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenPunct
+)
+
+type exprToken struct {
+	kind  tokenKind
+	value string // Unquoted for strings
+	at    int
+}
+
+// exprPunctuation lists operators, longest first
+var exprPunctuation = []string{"==", "!=", "<=", ">=", "&&", "||", "(", ")", "[", "]", ",", ".", "!", "<", ">", "*"}
+
+// lexExpression splits an expression into tokens
+func lexExpression(source string) ([]exprToken, error) {
+	var tokens []exprToken
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '\'':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(source); j++ {
+				if source[j] == '\'' {
+					if j+1 < len(source) && source[j+1] == '\'' {
+						b.WriteByte('\'')
+						j++
+						continue
+					}
+					break
+				}
+				b.WriteByte(source[j])
+			}
+			if j >= len(source) {
+				return nil, &ExpressionError{Offset: i, Message: "unterminated string"}
+			}
+			tokens = append(tokens, exprToken{kind: tokenString, value: b.String(), at: i})
+			i = j + 1
+
+		case isDigit(c) || (c == '-' && i+1 < len(source) && isDigit(source[i+1])):
+			j := i + 1
+			for j < len(source) && (isIdentChar(source[j]) || source[j] == '.' ||
+				((source[j] == '+' || source[j] == '-') && (source[j-1] == 'e' || source[j-1] == 'E'))) {
+				j++
+			}
+			tokens = append(tokens, exprToken{kind: tokenNumber, value: source[i:j], at: i})
+			i = j
+
+		case isIdentStart(c):
+			j := i + 1
+			for j < len(source) && isIdentChar(source[j]) {
+				j++
+			}
+			tokens = append(tokens, exprToken{kind: tokenIdent, value: source[i:j], at: i})
+			i = j
+
+		default:
+			matched := false
+			for _, punct := range exprPunctuation {
+				if strings.HasPrefix(source[i:], punct) {
+					tokens = append(tokens, exprToken{kind: tokenPunct, value: punct, at: i})
+					i += len(punct)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, &ExpressionError{Offset: i, Message: fmt.Sprintf("unexpected character %q", c)}
+			}
+		}
+	}
+	return append(tokens, exprToken{kind: tokenEOF, at: len(source)}), nil
+}
+
+func isDigit(c byte) bool      { return '0' <= c && c <= '9' }
+func isIdentStart(c byte) bool { return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') }
+func isIdentChar(c byte) bool  { return isIdentStart(c) || isDigit(c) || c == '-' }
+
+// exprParser is a recursive descent parser over the tokens of an expression.
+// Precedence from low to high: ||, &&, == !=, < <= > >=, !, property access.
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is one of the punctuation values
+func (p *exprParser) accept(values ...string) (exprToken, bool) {
+	tok := p.peek()
+	if tok.kind != tokenPunct {
+		return tok, false
+	}
+	for _, value := range values {
+		if tok.value == value {
+			return p.next(), true
+		}
+	}
+	return tok, false
+}
+
+func (p *exprParser) expect(value string) error {
+	if _, ok := p.accept(value); !ok {
+		tok := p.peek()
+		if tok.kind == tokenEOF {
+			return &ExpressionError{Offset: tok.at, Message: fmt.Sprintf("expected %q", value)}
+		}
+		return &ExpressionError{Offset: tok.at, Message: fmt.Sprintf("expected %q, got %q", value, tok.value)}
+	}
+	return nil
+}
+
+// parseBinary parses a left-associative level of binary operators
+func (p *exprParser) parseBinary(operand func() (exprNode, error), ops ...string) (exprNode, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.accept(ops...)
+		if !ok {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &exprBinary{at: tok.at, op: tok.value, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	return p.parseBinary(p.parseAnd, "||")
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	return p.parseBinary(p.parseEquality, "&&")
+}
+
+func (p *exprParser) parseEquality() (exprNode, error) {
+	return p.parseBinary(p.parseComparison, "==", "!=")
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	return p.parseBinary(p.parseUnary, "<", "<=", ">", ">=")
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if tok, ok := p.accept("!"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &exprNot{at: tok.at, operand: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+// parsePostfix parses a primary value followed by .name, .* and [index]
+func (p *exprParser) parsePostfix() (exprNode, error) {
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if tok, ok := p.accept("."); ok {
+			if star, ok := p.accept("*"); ok {
+				node = &exprFilter{at: star.at, target: node}
+				continue
+			}
+			name := p.next()
+			if name.kind != tokenIdent {
+				return nil, &ExpressionError{Offset: tok.at, Message: "expected a property name after '.'"}
+			}
+			node = &exprProperty{at: name.at, target: node, name: name.value}
+			continue
+		}
+		if tok, ok := p.accept("["); ok {
+			if _, ok := p.accept("*"); ok {
+				node = &exprFilter{at: tok.at, target: node}
+			} else {
+				index, err := p.parseOr()
+				if err != nil {
+					return nil, err
+				}
+				if literal, ok := index.(*exprLiteral); ok {
+					if name, ok := literal.value.(string); ok {
+						index = nil
+						node = &exprProperty{at: literal.at, target: node, name: name}
+					}
+				}
+				if index != nil {
+					node = &exprIndex{at: tok.at, target: node, index: index}
+				}
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return node, nil
+	}
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenString:
+		return &exprLiteral{at: tok.at, value: tok.value}, nil
+
+	case tokenNumber:
+		if n, err := strconv.ParseFloat(tok.value, 64); err == nil {
+			return &exprLiteral{at: tok.at, value: n}, nil
+		}
+		if n, err := strconv.ParseInt(tok.value, 0, 64); err == nil {
+			return &exprLiteral{at: tok.at, value: float64(n)}, nil
+		}
+		return nil, &ExpressionError{Offset: tok.at, Message: fmt.Sprintf("invalid number %q", tok.value)}
+
+	case tokenIdent:
+		switch tok.value {
+		case "true":
+			return &exprLiteral{at: tok.at, value: true}, nil
+		case "false":
+			return &exprLiteral{at: tok.at, value: false}, nil
+		case "null":
+			return &exprLiteral{at: tok.at, value: nil}, nil
+		}
+		if _, ok := p.accept("("); ok {
+			return p.parseCall(tok)
+		}
+		return &exprContext{at: tok.at, name: strings.ToLower(tok.value)}, nil
+
+	case tokenPunct:
+		if tok.value == "(" {
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		}
+		return nil, &ExpressionError{Offset: tok.at, Message: fmt.Sprintf("unexpected %q", tok.value)}
+	}
+	return nil, &ExpressionError{Offset: tok.at, Message: "unexpected end of expression"}
+}
+
+// parseCall parses the arguments of a function call and checks their number
+func (p *exprParser) parseCall(name exprToken) (exprNode, error) {
+	call := &exprCall{at: name.at, name: strings.ToLower(name.value)}
+	if _, ok := p.accept(")"); !ok {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if _, ok := p.accept(","); !ok {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+
+	function, ok := expressionFunctions[call.name]
+	if !ok {
+		return nil, &ExpressionError{Offset: name.at, Message: fmt.Sprintf("unrecognized function: '%s'", name.value)}
+	}
+	if len(call.args) < function.min || (function.max >= 0 && len(call.args) > function.max) {
+		return nil, &ExpressionError{Offset: name.at, Message: fmt.Sprintf("%s() takes %s, got %d", name.value, function.arguments(), len(call.args))}
+	}
+	return call, nil
+}
+
+// arguments describes the number of arguments of a function
+func (f expressionFunction) arguments() string {
+	switch {
+	case f.max < 0:
+		return fmt.Sprintf("at least %d argument(s)", f.min)
+	case f.min == f.max:
+		return fmt.Sprintf("%d argument(s)", f.min)
+	}
+	return fmt.Sprintf("%d to %d arguments", f.min, f.max)
+}
@@ -0,0 +1,450 @@
+package bridge
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoExecutions is returned when a unit has no recorded executions
+var ErrNoExecutions = errors.New("no executions recorded")
+
+// Default retention of the execution history
+const (
+	DefaultHistoryMaxPerUnit = 20
+	DefaultHistoryMaxAge     = 30 * 24 * time.Hour
+)
+
+// HistoryRetention limits how much history is kept. Zero values disable a limit.
+type HistoryRetention struct {
+	MaxPerUnit int           // Executions kept per unit
+	MaxAge     time.Duration // Executions older than this are removed
+}
+
+// HistoryStore keeps execution records on disk, one JSON file per execution
+// under <dir>/<space>/<unit>/. Units are scoped to their space, as slugs are
+// only unique within one. File names sort by start time.
+type HistoryStore struct {
+	dir       string
+	retention HistoryRetention
+	mu        sync.Mutex
+}
+
+// HistoryUnit names a unit with recorded executions
+type HistoryUnit struct {
+	Space string // Empty for executions run outside a space
+	Unit  string
+}
+
+// String returns space/unit, or the unit alone outside a space
+func (u HistoryUnit) String() string {
+	if u.Space == "" {
+		return u.Unit
+	}
+	return u.Space + "/" + u.Unit
+}
+
+// ArtifactEntry describes one artifact file of an execution
+type ArtifactEntry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// NewHistoryStore opens or creates a history store in dir
+func NewHistoryStore(dir string, retention HistoryRetention) (*HistoryStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create history dir: %w", err)
+	}
+
+	return &HistoryStore{
+		dir:       dir,
+		retention: retention,
+	}, nil
+}
+
+// Dir returns the directory of the store
+func (hs *HistoryStore) Dir() string {
+	return hs.dir
+}
+
+// Save stores an execution record and applies retention to its unit
+func (hs *HistoryStore) Save(record *ExecutionRecord) error {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	if err := validateFilename(record.ID); err != nil {
+		return fmt.Errorf("invalid execution id: %w", err)
+	}
+
+	unitDir, err := hs.unitDir(record.Space, record.UnitID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(unitDir, 0755); err != nil {
+		return fmt.Errorf("create unit history dir: %w", err)
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode execution record: %w", err)
+	}
+
+	// Write to a temporary file first so a crash never leaves a partial record
+	path := filepath.Join(unitDir, recordFileName(record))
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write execution record: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write execution record: %w", err)
+	}
+
+	return hs.pruneUnit(record.Space, record.UnitID, time.Now())
+}
+
+// SaveArtifacts copies the artifact files of an execution next to its record,
+// so they outlive the workspace until the record is pruned. Only regular
+// files are copied.
+func (hs *HistoryStore) SaveArtifacts(record *ExecutionRecord, srcDir string) error {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	dstDir, err := hs.ArtifactsDir(record)
+	if err != nil {
+		return err
+	}
+	for _, rel := range record.Artifacts {
+		if err := validateRelativePath(filepath.ToSlash(rel)); err != nil {
+			return fmt.Errorf("invalid artifact path: %w", err)
+		}
+		if err := copyRegularFile(filepath.Join(srcDir, rel), filepath.Join(dstDir, rel)); err != nil {
+			return fmt.Errorf("store artifact %s: %w", rel, err)
+		}
+	}
+	return nil
+}
+
+// ArtifactsDir returns the directory holding the stored artifact files of an execution
+func (hs *HistoryStore) ArtifactsDir(record *ExecutionRecord) (string, error) {
+	unitDir, err := hs.unitDir(record.Space, record.UnitID)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(unitDir, strings.TrimSuffix(recordFileName(record), ".json")+".artifacts"), nil
+}
+
+// Latest returns the most recent execution of a unit in a space
+func (hs *HistoryStore) Latest(space, unit string) (*ExecutionRecord, error) {
+	records, err := hs.List(space, unit, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("unit %s: %w", HistoryUnit{space, unit}, ErrNoExecutions)
+	}
+	return records[0], nil
+}
+
+// List returns up to limit executions of a unit in a space, newest first. A
+// limit of zero or less returns all of them.
+func (hs *HistoryStore) List(space, unit string, limit int) ([]*ExecutionRecord, error) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	files, err := hs.recordFiles(space, unit)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*ExecutionRecord
+	for i := len(files) - 1; i >= 0; i-- {
+		if limit > 0 && len(records) == limit {
+			break
+		}
+		record, err := readRecord(files[i])
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// Get returns one execution of a unit in a space by ID
+func (hs *HistoryStore) Get(space, unit, id string) (*ExecutionRecord, error) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	files, err := hs.recordFiles(space, unit)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if strings.HasSuffix(file, "-"+id+".json") {
+			return readRecord(file)
+		}
+	}
+	return nil, fmt.Errorf("execution %s of unit %s: %w", id, HistoryUnit{space, unit}, ErrNoExecutions)
+}
+
+// Units returns the units with recorded executions, sorted by space and unit
+func (hs *HistoryStore) Units() ([]HistoryUnit, error) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	spaces, err := os.ReadDir(hs.dir)
+	if err != nil {
+		return nil, fmt.Errorf("read history dir: %w", err)
+	}
+
+	var units []HistoryUnit
+	for _, spaceEntry := range spaces {
+		space, ok := unescapeHistoryName(spaceEntry.Name())
+		if !spaceEntry.IsDir() || !ok {
+			continue
+		}
+		entries, err := os.ReadDir(filepath.Join(hs.dir, spaceEntry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read history dir: %w", err)
+		}
+		for _, entry := range entries {
+			unit, ok := unescapeHistoryName(entry.Name())
+			if !entry.IsDir() || !ok || unit == "" {
+				continue
+			}
+			units = append(units, HistoryUnit{Space: space, Unit: unit})
+		}
+	}
+	sort.Slice(units, func(i, j int) bool {
+		if units[i].Space != units[j].Space {
+			return units[i].Space < units[j].Space
+		}
+		return units[i].Unit < units[j].Unit
+	})
+	return units, nil
+}
+
+// DeleteUnit removes all executions of a unit in a space and returns how many
+// there were. Units of the same name in other spaces are kept.
+func (hs *HistoryStore) DeleteUnit(space, unit string) (int, error) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	unitDir, err := hs.unitDir(space, unit)
+	if err != nil {
+		return 0, err
+	}
+	files, err := hs.recordFiles(space, unit)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.RemoveAll(unitDir); err != nil {
+		return 0, fmt.Errorf("delete unit history: %w", err)
+	}
+	// Drop the space's directory once its last unit is gone
+	os.Remove(filepath.Dir(unitDir))
+	return len(files), nil
+}
+
+// Prune applies retention to every unit and returns the number of records removed
+func (hs *HistoryStore) Prune() (int, error) {
+	units, err := hs.Units()
+	if err != nil {
+		return 0, err
+	}
+
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	removed := 0
+	now := time.Now()
+	for _, unit := range units {
+		before, err := hs.recordFiles(unit.Space, unit.Unit)
+		if err != nil {
+			return removed, err
+		}
+		if err := hs.pruneUnit(unit.Space, unit.Unit, now); err != nil {
+			return removed, err
+		}
+		after, err := hs.recordFiles(unit.Space, unit.Unit)
+		if err != nil {
+			return removed, err
+		}
+		removed += len(before) - len(after)
+	}
+	return removed, nil
+}
+
+// pruneUnit removes records beyond the retention limits. The caller must hold hs.mu.
+func (hs *HistoryStore) pruneUnit(space, unit string, now time.Time) error {
+	files, err := hs.recordFiles(space, unit)
+	if err != nil {
+		return err
+	}
+
+	for i, file := range files {
+		keep := len(files) - i
+		expired := false
+		if hs.retention.MaxAge > 0 {
+			if started, ok := recordFileTime(file); ok && now.Sub(started) > hs.retention.MaxAge {
+				expired = true
+			}
+		}
+		// Always keep the latest execution so Refresh has something to compare with
+		overLimit := hs.retention.MaxPerUnit > 0 && keep > hs.retention.MaxPerUnit
+		if (expired || overLimit) && keep > 1 {
+			if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("remove execution record: %w", err)
+			}
+			if err := os.RemoveAll(strings.TrimSuffix(file, ".json") + ".artifacts"); err != nil {
+				return fmt.Errorf("remove execution artifacts: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// recordFiles returns the record files of a unit, oldest first. The caller must hold hs.mu.
+func (hs *HistoryStore) recordFiles(space, unit string) ([]string, error) {
+	unitDir, err := hs.unitDir(space, unit)
+	if err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(unitDir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("list executions: %w", err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// unitDir returns the directory of a unit in a space. Both names are escaped
+// to stay one path element each, so a unit's directory is always two levels
+// below the store.
+func (hs *HistoryStore) unitDir(space, unit string) (string, error) {
+	if unit == "" {
+		return "", fmt.Errorf("empty unit name")
+	}
+	spaceName, unitName := escapeHistoryName(space), escapeHistoryName(unit)
+
+	dir := filepath.Join(hs.dir, spaceName, unitName)
+	if filepath.Dir(filepath.Dir(dir)) != filepath.Clean(hs.dir) || filepath.Base(dir) != unitName {
+		return "", fmt.Errorf("unit name escapes the history dir: %s", HistoryUnit{space, unit})
+	}
+	return dir, nil
+}
+
+// escapeHistoryName escapes a space or unit name into one path element. "."
+// and ".." have their dots escaped, and "_", which stands for the empty
+// space, is escaped in every other name.
+func escapeHistoryName(name string) string {
+	if name == "" {
+		return "_"
+	}
+	escaped := strings.ReplaceAll(url.PathEscape(name), "_", "%5F")
+	if escaped == "." || escaped == ".." {
+		escaped = strings.ReplaceAll(escaped, ".", "%2E")
+	}
+	return escaped
+}
+
+// unescapeHistoryName reverses escapeHistoryName
+func unescapeHistoryName(escaped string) (string, bool) {
+	if escaped == "_" {
+		return "", true
+	}
+	name, err := url.PathUnescape(escaped)
+	return name, err == nil
+}
+
+// recordFileName names a record file so that names sort by start time
+func recordFileName(record *ExecutionRecord) string {
+	return fmt.Sprintf("%020d-%s.json", record.StartTime.UnixNano(), record.ID)
+}
+
+// recordFileTime reads the start time encoded in a record file name
+func recordFileTime(file string) (time.Time, bool) {
+	var nanos int64
+	if _, err := fmt.Sscanf(filepath.Base(file), "%020d-", &nanos); err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, nanos), true
+}
+
+// copyRegularFile copies src to dst, creating directories; anything but a
+// regular file is skipped
+func copyRegularFile(src, dst string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// readRecord decodes a record file
+func readRecord(file string) (*ExecutionRecord, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read execution record: %w", err)
+	}
+	var record ExecutionRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("decode execution record %s: %w", filepath.Base(file), err)
+	}
+	return &record, nil
+}
+
+// configHash returns the SHA-256 of unit config data
+func configHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// artifactManifest describes the artifact files below dir
+func artifactManifest(dir string, paths []string) []ArtifactEntry {
+	manifest := make([]ArtifactEntry, 0, len(paths))
+	for _, rel := range paths {
+		entry := ArtifactEntry{Path: filepath.ToSlash(rel)}
+		if f, err := os.Open(filepath.Join(dir, rel)); err == nil {
+			hash := sha256.New()
+			entry.Size, _ = io.Copy(hash, f)
+			entry.SHA256 = hex.EncodeToString(hash.Sum(nil))
+			f.Close()
+		}
+		manifest = append(manifest, entry)
+	}
+	return manifest
+}
@@ -0,0 +1,112 @@
+package bridge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// historyUnits names units of one space
+func historyUnits(space string, units ...string) []HistoryUnit {
+	named := make([]HistoryUnit, len(units))
+	for i, unit := range units {
+		named[i] = HistoryUnit{Space: space, Unit: unit}
+	}
+	return named
+}
+
+func TestHistoryStoreUnitNames(t *testing.T) {
+	root := t.TempDir()
+	sibling := filepath.Join(root, "sibling")
+	require.NoError(t, os.MkdirAll(sibling, 0755))
+	store, err := NewHistoryStore(filepath.Join(root, "history"), HistoryRetention{})
+	require.NoError(t, err)
+
+	units := []string{"_", ".", "..", "a/b", "%2E", "deploy"}
+	for i, unit := range units {
+		record := &ExecutionRecord{ID: "exec-" + string(rune('a'+i)), UnitID: unit, StartTime: time.Now()}
+		require.NoError(t, store.Save(record), unit)
+
+		dir, err := store.unitDir("", unit)
+		require.NoError(t, err)
+		assert.Equal(t, store.Dir(), filepath.Dir(filepath.Dir(dir)), unit)
+	}
+
+	// Every name reads back as itself, without collisions
+	listed, err := store.Units()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, historyUnits("", units...), listed)
+	for _, unit := range units {
+		records, err := store.List("", unit, 0)
+		require.NoError(t, err)
+		require.Len(t, records, 1, unit)
+		assert.Equal(t, unit, records[0].UnitID)
+	}
+
+	// Dot names delete only their own records
+	removed, err := store.DeleteUnit("", "..")
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	removed, err = store.DeleteUnit("", ".")
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.DirExists(t, sibling)
+	assert.DirExists(t, store.Dir())
+	listed, err = store.Units()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, historyUnits("", "_", "a/b", "%2E", "deploy"), listed)
+
+	// There is no directory for an unnamed unit
+	_, err = store.DeleteUnit("", "")
+	assert.Error(t, err)
+	assert.Error(t, store.Save(&ExecutionRecord{ID: "exec-z", StartTime: time.Now()}))
+	assert.DirExists(t, store.Dir())
+}
+
+func TestHistoryStoreSpaces(t *testing.T) {
+	store, err := NewHistoryStore(t.TempDir(), HistoryRetention{MaxPerUnit: 1})
+	require.NoError(t, err)
+
+	// Two spaces with a unit of the same slug, and a run outside any space
+	start := time.Now()
+	for i, space := range []string{"platform", "payments", ""} {
+		record := &ExecutionRecord{ID: "exec-" + string(rune('a'+i)), UnitID: "web", Space: space, StartTime: start}
+		require.NoError(t, store.Save(record))
+	}
+	for i, space := range []string{"platform", "payments", ""} {
+		latest, err := store.Latest(space, "web")
+		require.NoError(t, err, space)
+		assert.Equal(t, "exec-"+string(rune('a'+i)), latest.ID, "retention of one space keeps the other's record")
+		assert.Equal(t, space, latest.Space)
+	}
+	_, err = store.Get("payments", "web", "exec-a")
+	assert.ErrorIs(t, err, ErrNoExecutions)
+
+	units, err := store.Units()
+	require.NoError(t, err)
+	assert.Equal(t, []HistoryUnit{{Unit: "web"}, {Space: "payments", Unit: "web"}, {Space: "platform", Unit: "web"}}, units)
+	assert.Equal(t, "payments/web", units[1].String())
+
+	// Deleting a unit leaves the unit of the same slug in another space
+	removed, err := store.DeleteUnit("platform", "web")
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	_, err = store.Latest("platform", "web")
+	assert.ErrorIs(t, err, ErrNoExecutions)
+	latest, err := store.Latest("payments", "web")
+	require.NoError(t, err)
+	assert.Equal(t, "exec-b", latest.ID)
+
+	// The empty space does not collide with a space named "_"
+	require.NoError(t, store.Save(&ExecutionRecord{ID: "exec-d", UnitID: "web", Space: "_", StartTime: start}))
+	latest, err = store.Latest("", "web")
+	require.NoError(t, err)
+	assert.Equal(t, "exec-c", latest.ID)
+	units, err = store.Units()
+	require.NoError(t, err)
+	assert.Equal(t, []HistoryUnit{{Unit: "web"}, {Space: "_", Unit: "web"}, {Space: "payments", Unit: "web"}}, units)
+}
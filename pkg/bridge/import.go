@@ -0,0 +1,442 @@
+package bridge
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Annotations recording where an imported unit came from
+const (
+	AnnotationSourcePath       = "actions.confighub.com/source-path"
+	AnnotationSourceRepository = "actions.confighub.com/source-repository"
+	AnnotationSourceRevision   = "actions.confighub.com/source-revision"
+)
+
+// Labels set on imported units
+const (
+	UnitLabelRepository = "repository"
+	UnitLabelWorkflow   = "workflow"
+)
+
+// ImportedUnit is a unit created from a workflow file of a repository checkout
+type ImportedUnit struct {
+	Name        string
+	SourcePath  string // Workflow path relative to the repository root
+	Labels      map[string]string
+	Annotations map[string]string
+	Files       []string // Repository files in the unit, the workflow first
+	Data        []byte   // Unit YAML including its ConfigHub header
+}
+
+// ImportRepository creates one unit per workflow in the .github/workflows
+// directory of a repository checkout. Workflows that use local actions or
+// local reusable workflows become bundles carrying those files; the others
+// become plain Actions units.
+func ImportRepository(root string) ([]*ImportedUnit, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("resolve repository: %w", err)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(root, filepath.FromSlash(workflowsDir)))
+	if err != nil {
+		return nil, fmt.Errorf("read workflows: %w", err)
+	}
+
+	repository, revision := gitSource(root)
+	repoLabel := repositoryName(repository)
+	if repoLabel == "" {
+		repoLabel = slugify(filepath.Base(root))
+	}
+
+	var units []*ImportedUnit
+	names := make(map[string]bool)
+	for _, entry := range entries {
+		source := path.Join(workflowsDir, entry.Name())
+		if !entry.Type().IsRegular() || !isWorkflowPath(source) {
+			continue
+		}
+
+		unit, err := importWorkflow(root, source)
+		if err != nil {
+			return nil, fmt.Errorf("import %s: %w", source, err)
+		}
+
+		// ci.yml and ci.yaml would both be named ci
+		name := unit.Name
+		for i := 2; names[name]; i++ {
+			name = fmt.Sprintf("%s-%d", unit.Name, i)
+		}
+		names[name] = true
+		unit.Name = name
+
+		unit.Labels[UnitLabelRepository] = repoLabel
+		unit.Annotations[AnnotationSourcePath] = source
+		if repository != "" {
+			unit.Annotations[AnnotationSourceRepository] = repository
+		}
+		if revision != "" {
+			unit.Annotations[AnnotationSourceRevision] = revision
+		}
+
+		if unit.Data, err = unit.encode(root); err != nil {
+			return nil, fmt.Errorf("import %s: %w", source, err)
+		}
+		units = append(units, unit)
+	}
+
+	if len(units) == 0 {
+		return nil, fmt.Errorf("no workflows found in %s", filepath.Join(root, filepath.FromSlash(workflowsDir)))
+	}
+	return units, nil
+}
+
+// JoinUnits joins unit data into one multi-document YAML stream
+func JoinUnits(units []*ImportedUnit) []byte {
+	var buf bytes.Buffer
+	for i, unit := range units {
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		buf.Write(unit.Data)
+		if !bytes.HasSuffix(unit.Data, []byte("\n")) {
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes()
+}
+
+// selectImportedUnit returns the unit named workflow, or imported from the
+// workflow at that path
+func selectImportedUnit(units []*ImportedUnit, workflow string) (*ImportedUnit, error) {
+	names := make([]string, len(units))
+	for i, unit := range units {
+		if unit.Name == workflow || unit.SourcePath == workflow {
+			return unit, nil
+		}
+		names[i] = unit.Name
+	}
+	return nil, fmt.Errorf("no workflow %s in the repository (found %s)", workflow, strings.Join(names, ", "))
+}
+
+// resolveBelow resolves a relative path below root, following symlinks, and
+// fails if the result is outside root
+func resolveBelow(root, relPath string) (string, error) {
+	if err := validateRelativePath(relPath); err != nil {
+		return "", err
+	}
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(filepath.Join(root, filepath.FromSlash(relPath)))
+	if err != nil {
+		return "", err
+	}
+	if rel, err := filepath.Rel(root, resolved); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside %s", relPath, root)
+	}
+	return resolved, nil
+}
+
+// importWorkflow reads a workflow and collects the local files it uses
+func importWorkflow(root, source string) (*ImportedUnit, error) {
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(source)))
+	if err != nil {
+		return nil, err
+	}
+	workflow, err := parseWorkflowDocument(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("parse workflow: %w", err)
+	}
+	if _, ok := workflow["jobs"]; !ok {
+		return nil, fmt.Errorf("workflow has no jobs")
+	}
+
+	files, err := localFiles(root, source)
+	if err != nil {
+		return nil, err
+	}
+
+	name := slugify(strings.TrimSuffix(path.Base(source), path.Ext(source)))
+	if name == "" {
+		name = "workflow"
+	}
+	label := name
+	if title, ok := workflow["name"].(string); ok && slugify(title) != "" {
+		label = slugify(title)
+	}
+
+	return &ImportedUnit{
+		Name:        name,
+		SourcePath:  source,
+		Labels:      map[string]string{UnitLabelWorkflow: label},
+		Annotations: map[string]string{},
+		Files:       files,
+	}, nil
+}
+
+// encode renders the unit with its ConfigHub header. A single workflow keeps
+// its text, comments included, below the header.
+func (u *ImportedUnit) encode(root string) ([]byte, error) {
+	contents := make(map[string][]byte, len(u.Files))
+	for _, file := range u.Files {
+		data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(file)))
+		if err != nil {
+			return nil, err
+		}
+		contents[file] = data
+	}
+
+	envelope := &Envelope{
+		APIVersion: UnitAPIVersion,
+		Kind:       WorkflowKind,
+		Metadata: EnvelopeMetadata{
+			Name:        u.Name,
+			Labels:      u.Labels,
+			Annotations: u.Annotations,
+		},
+		Workflow: contents[u.SourcePath],
+	}
+	if len(u.Files) > 1 {
+		envelope.Kind = BundleKind
+		envelope.Bundle = &Bundle{Entrypoint: u.SourcePath, Files: make(map[string]string, len(contents))}
+		for file, content := range contents {
+			envelope.Bundle.Files[file] = string(content)
+		}
+	}
+	return envelope.Encode()
+}
+
+// localFiles returns the workflow followed by the repository files it uses:
+// local actions, local reusable workflows and whatever those use in turn
+func localFiles(root, workflow string) ([]string, error) {
+	files := []string{workflow}
+	seen := map[string]bool{workflow: true}
+
+	for i := 0; i < len(files); i++ {
+		file := files[i]
+		if ext := path.Ext(file); ext != ".yml" && ext != ".yaml" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(file)))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, uses := range localUses(data) {
+			target := path.Clean(strings.TrimPrefix(uses, "./"))
+			if err := validateRelativePath(target); err != nil {
+				return nil, fmt.Errorf("%s uses %s: %w", file, uses, err)
+			}
+
+			found, err := repositoryFiles(root, target)
+			if err != nil {
+				return nil, fmt.Errorf("%s uses %s: %w", file, uses, err)
+			}
+			for _, f := range found {
+				if !seen[f] {
+					seen[f] = true
+					files = append(files, f)
+				}
+			}
+		}
+	}
+	return files, nil
+}
+
+// localUses returns the local references in the uses of a workflow's jobs
+// and steps, or of a composite action's steps
+func localUses(data []byte) []string {
+	var doc struct {
+		Jobs map[string]struct {
+			Uses  string `yaml:"uses"`
+			Steps []struct {
+				Uses string `yaml:"uses"`
+			} `yaml:"steps"`
+		} `yaml:"jobs"`
+		Runs struct {
+			Steps []struct {
+				Uses string `yaml:"uses"`
+			} `yaml:"steps"`
+		} `yaml:"runs"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil
+	}
+
+	var uses []string
+	for _, job := range doc.Jobs {
+		uses = append(uses, job.Uses)
+		for _, step := range job.Steps {
+			uses = append(uses, step.Uses)
+		}
+	}
+	for _, step := range doc.Runs.Steps {
+		uses = append(uses, step.Uses)
+	}
+
+	var local []string
+	for _, ref := range uses {
+		if strings.HasPrefix(ref, "./") {
+			local = append(local, ref)
+		}
+	}
+	sort.Strings(local)
+	return local
+}
+
+// repositoryFiles returns target if it is a file, or the regular files below
+// it if it is a directory, as sorted slash-separated repository paths
+func repositoryFiles(root, target string) ([]string, error) {
+	// Symlinks must not pull files from outside the checkout into a unit
+	if _, err := resolveBelow(root, target); err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(root, filepath.FromSlash(target))
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{target}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// gitSource returns the origin URL and checked-out commit of a repository
+// checkout, or empty strings where they cannot be read
+func gitSource(root string) (repository, revision string) {
+	gitDir := filepath.Join(root, ".git")
+	// Worktrees and submodules have a .git file pointing at the git directory
+	if data, err := os.ReadFile(gitDir); err == nil {
+		dir := strings.TrimSpace(strings.TrimPrefix(string(data), "gitdir:"))
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(root, dir)
+		}
+		gitDir = dir
+	}
+	commonDir := gitDir
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir = filepath.Join(gitDir, strings.TrimSpace(string(data)))
+	}
+
+	repository = gitRemoteURL(filepath.Join(commonDir, "config"), "origin")
+	// Never copy credentials embedded in an https remote into a unit
+	if u, err := url.Parse(repository); err == nil && u.User != nil {
+		u.User = nil
+		repository = u.String()
+	}
+	return repository, gitHead(gitDir, commonDir)
+}
+
+// gitRemoteURL reads the URL of a remote from a git config file
+func gitRemoteURL(configFile, remote string) string {
+	f, err := os.Open(configFile)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	section := fmt.Sprintf(`[remote "%s"]`, remote)
+	inSection := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inSection = line == section
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok && inSection && strings.TrimSpace(key) == "url" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// gitHead resolves HEAD to a commit through loose or packed refs
+func gitHead(gitDir, commonDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return ""
+	}
+	head := strings.TrimSpace(string(data))
+	ref, ok := strings.CutPrefix(head, "ref: ")
+	if !ok {
+		return head // Detached
+	}
+
+	if data, err := os.ReadFile(filepath.Join(commonDir, filepath.FromSlash(ref))); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	if data, err := os.ReadFile(filepath.Join(commonDir, "packed-refs")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if sha, name, ok := strings.Cut(strings.TrimSpace(line), " "); ok && name == ref {
+				return sha
+			}
+		}
+	}
+	return ""
+}
+
+// repositoryName returns the slug of the repository name in a remote URL
+func repositoryName(remote string) string {
+	remote = strings.TrimSuffix(strings.TrimSuffix(remote, "/"), ".git")
+	if i := strings.LastIndexAny(remote, "/:"); i >= 0 {
+		remote = remote[i+1:]
+	}
+	return slugify(remote)
+}
+
+// slugify turns a name into a lowercase slug that is valid as a unit name
+// and label value
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if ('a' <= r && r <= 'z') || ('0' <= r && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	slug := b.String()
+	if len(slug) > 63 {
+		slug = strings.TrimRight(slug[:63], "-")
+	}
+	return slug
+}
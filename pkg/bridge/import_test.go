@@ -0,0 +1,84 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/confighub/sdk/bridge-worker/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportAction(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"web-app/.github/workflows/ci.yml":     "on: push\njobs:\n  test:\n    runs-on: ubuntu-latest\n    steps:\n      - run: make test\n",
+		"web-app/.github/workflows/deploy.yml": "on: workflow_dispatch\njobs:\n  deploy:\n    runs-on: ubuntu-latest\n    steps:\n      - run: make deploy\n",
+		"leaky/.github/workflows/deploy.yml":   "on: workflow_dispatch\njobs:\n  deploy:\n    runs-on: ubuntu-latest\n    steps:\n      - uses: ./.github/actions/leak\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	outside := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "action.yml"), []byte("runs:\n  using: composite\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "leaky/.github/actions"), 0755))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "leaky/.github/actions/leak")))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "elsewhere")))
+
+	bridge, err := NewActionsBridgeWithConfig(BridgeConfig{BaseDir: t.TempDir(), ImportRoot: root})
+	require.NoError(t, err)
+
+	importUnit := func(bridge *ActionsBridge, unit string, params map[string]string) *api.ActionResult {
+		data, err := json.Marshal(params)
+		require.NoError(t, err)
+		ctx := &statusRecorder{ctx: context.Background()}
+		require.NoError(t, bridge.Import(ctx, api.BridgeWorkerPayload{UnitSlug: unit, TargetParams: data}))
+		return ctx.last(t)
+	}
+
+	// The unit gets the one workflow named after it, as a single envelope
+	status := importUnit(bridge, "ci", map[string]string{"repo_path": "web-app"})
+	require.Equal(t, api.ActionStatusCompleted, status.Status, status.Message)
+	assert.Equal(t, api.ActionResultImportCompleted, status.Result)
+	envelope, err := ParseEnvelope(status.Data)
+	require.NoError(t, err)
+	assert.Equal(t, "ci", envelope.Metadata.Name)
+	assert.Equal(t, files["web-app/.github/workflows/ci.yml"], string(envelope.Workflow))
+
+	status = importUnit(bridge, "tests", map[string]string{"repo_path": "web-app", "workflow": ".github/workflows/ci.yml"})
+	require.Equal(t, api.ActionStatusCompleted, status.Status, status.Message)
+
+	tests := []struct {
+		name    string
+		unit    string
+		params  map[string]string
+		wantMsg string
+	}{
+		{name: "unknown workflow", unit: "web", params: map[string]string{"repo_path": "web-app"}, wantMsg: "no workflow web in the repository (found ci, deploy)"},
+		{name: "symlinked action outside the checkout", unit: "deploy", params: map[string]string{"repo_path": "leaky"}, wantMsg: ".github/actions/leak is outside"},
+		{name: "absolute path", unit: "ci", params: map[string]string{"repo_path": filepath.Join(root, "web-app")}, wantMsg: "path must be relative"},
+		{name: "parent directory", unit: "ci", params: map[string]string{"repo_path": "../web-app"}, wantMsg: "path contains invalid element"},
+		{name: "symlink outside the root", unit: "ci", params: map[string]string{"repo_path": "elsewhere"}, wantMsg: "elsewhere is outside"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := importUnit(bridge, tt.unit, tt.params)
+			assert.Equal(t, api.ActionStatusFailed, status.Status)
+			assert.Equal(t, api.ActionResultImportFailed, status.Result)
+			assert.Contains(t, status.Message, tt.wantMsg)
+			assert.Empty(t, status.Data)
+		})
+	}
+
+	// Without an import root, workers do not read checkouts at all
+	disabled, err := NewActionsBridge(t.TempDir())
+	require.NoError(t, err)
+	status = importUnit(disabled, "ci", map[string]string{"repo_path": "web-app"})
+	assert.Equal(t, api.ActionResultImportFailed, status.Result)
+	assert.Contains(t, status.Message, "repository imports are disabled")
+}
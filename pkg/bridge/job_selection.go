@@ -0,0 +1,188 @@
+package bridge
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nektos/act/pkg/model"
+)
+
+// selectJobs narrows a plan to the given jobs and the jobs they need,
+// directly or transitively. Stages left empty are dropped.
+func selectJobs(plan *model.Plan, jobIDs []string) (*model.Plan, error) {
+	if len(jobIDs) == 0 {
+		return plan, nil
+	}
+
+	planned := make(map[string]*model.Run)
+	for _, stage := range plan.Stages {
+		for _, run := range stage.Runs {
+			planned[run.JobID] = run
+		}
+	}
+
+	// Walk needs from the selected jobs
+	selected := make(map[string]bool)
+	queue := append([]string{}, jobIDs...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if selected[id] {
+			continue
+		}
+
+		run, ok := planned[id]
+		if !ok {
+			return nil, fmt.Errorf("job %s not found in plan (jobs: %s)", id, strings.Join(plannedJobIDs(planned), ", "))
+		}
+		selected[id] = true
+
+		if job := run.Job(); job != nil {
+			queue = append(queue, job.Needs()...)
+		}
+	}
+
+	filtered := &model.Plan{}
+	for _, stage := range plan.Stages {
+		var runs []*model.Run
+		for _, run := range stage.Runs {
+			if selected[run.JobID] {
+				runs = append(runs, run)
+			}
+		}
+		if len(runs) > 0 {
+			filtered.Stages = append(filtered.Stages, &model.Stage{Runs: runs})
+		}
+	}
+	return filtered, nil
+}
+
+// skipJobs removes jobs from a plan. Skipping a job another planned job
+// needs is an error.
+func skipJobs(plan *model.Plan, jobIDs []string) (*model.Plan, error) {
+	if len(jobIDs) == 0 {
+		return plan, nil
+	}
+
+	skipped := make(map[string]bool, len(jobIDs))
+	for _, id := range jobIDs {
+		skipped[id] = true
+	}
+
+	filtered := &model.Plan{}
+	for _, stage := range plan.Stages {
+		var runs []*model.Run
+		for _, run := range stage.Runs {
+			if skipped[run.JobID] {
+				continue
+			}
+			if job := run.Job(); job != nil {
+				for _, need := range job.Needs() {
+					if skipped[need] {
+						return nil, fmt.Errorf("job %s needs skipped job %s", run.JobID, need)
+					}
+				}
+			}
+			runs = append(runs, run)
+		}
+		if len(runs) > 0 {
+			filtered.Stages = append(filtered.Stages, &model.Stage{Runs: runs})
+		}
+	}
+	return filtered, nil
+}
+
+// plannedJobIDs returns the sorted IDs of the planned jobs
+func plannedJobIDs(planned map[string]*model.Run) []string {
+	ids := make([]string, 0, len(planned))
+	for id := range planned {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ParseMatrixSelector parses matrix selectors such as "os=ubuntu-22.04,node=20".
+// Repeating a key, in one selector or across several, allows each value.
+func ParseMatrixSelector(selectors []string) (map[string][]string, error) {
+	matrix := make(map[string][]string)
+	for _, selector := range selectors {
+		for _, pair := range strings.Split(selector, ",") {
+			pair = strings.TrimSpace(pair)
+			if pair == "" {
+				continue
+			}
+			parts := strings.SplitN(pair, "=", 2)
+			if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+				return nil, fmt.Errorf("invalid matrix selector %q (expected key=value)", pair)
+			}
+			key := strings.TrimSpace(parts[0])
+			matrix[key] = append(matrix[key], strings.TrimSpace(parts[1]))
+		}
+	}
+	return matrix, nil
+}
+
+// matrixFilter converts a matrix selector to the form act expects
+func matrixFilter(matrix map[string][]string) map[string]map[string]bool {
+	if len(matrix) == 0 {
+		return nil
+	}
+	filter := make(map[string]map[string]bool, len(matrix))
+	for key, values := range matrix {
+		filter[key] = make(map[string]bool, len(values))
+		for _, value := range values {
+			filter[key][value] = true
+		}
+	}
+	return filter
+}
+
+// filterMatrixes keeps the combinations act would run for a matrix filter.
+// Like act, keys the filter does not mention are not constrained.
+func filterMatrixes(matrixes []map[string]interface{}, filter map[string]map[string]bool) []map[string]interface{} {
+	if len(filter) == 0 {
+		return matrixes
+	}
+
+	var kept []map[string]interface{}
+	for _, combination := range matrixes {
+		keep := true
+		for key, value := range combination {
+			if allowed, ok := filter[key]; ok && !allowed[fmt.Sprint(value)] {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			kept = append(kept, combination)
+		}
+	}
+	return kept
+}
+
+// checkMatrixSelection returns an error when the filter leaves a matrix job
+// of the plan with no combination to run
+func checkMatrixSelection(plan *model.Plan, filter map[string]map[string]bool) error {
+	if len(filter) == 0 {
+		return nil
+	}
+
+	for _, stage := range plan.Stages {
+		for _, run := range stage.Runs {
+			job := run.Job()
+			if job == nil || job.Strategy == nil || len(job.Matrix()) == 0 {
+				continue
+			}
+			matrixes, err := job.GetMatrixes()
+			if err != nil {
+				return fmt.Errorf("expand matrix of job %s: %w", run.JobID, err)
+			}
+			if len(filterMatrixes(matrixes, filter)) == 0 {
+				return fmt.Errorf("matrix selector matches no combination of job %s", run.JobID)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+package bridge
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/nektos/act/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const selectionWorkflow = `name: pipeline
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make
+  test:
+    needs: build
+    runs-on: ${{ matrix.os }}
+    strategy:
+      matrix:
+        os: [ubuntu-latest, windows-latest]
+        node: [18, 20]
+        exclude:
+          - os: windows-latest
+            node: 18
+    steps:
+      - run: make test
+  lint:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make lint
+  deploy:
+    needs: [test, lint]
+    runs-on: ubuntu-latest
+    steps:
+      - run: make deploy
+  docs:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make docs
+`
+
+// stageJobs returns the sorted job IDs of each stage of a plan
+func stageJobs(plan *model.Plan) [][]string {
+	stages := [][]string{}
+	for _, stage := range plan.Stages {
+		var ids []string
+		for _, run := range stage.Runs {
+			ids = append(ids, run.JobID)
+		}
+		sort.Strings(ids)
+		stages = append(stages, ids)
+	}
+	return stages
+}
+
+func TestSelectJobs(t *testing.T) {
+	tests := []struct {
+		name    string
+		jobs    []string
+		want    [][]string
+		wantErr string
+	}{
+		{
+			name: "no selection keeps the plan",
+			want: [][]string{{"build", "docs", "lint"}, {"test"}, {"deploy"}},
+		},
+		{
+			name: "job without needs",
+			jobs: []string{"docs"},
+			want: [][]string{{"docs"}},
+		},
+		{
+			name: "direct needs",
+			jobs: []string{"test"},
+			want: [][]string{{"build"}, {"test"}},
+		},
+		{
+			name: "transitive needs",
+			jobs: []string{"deploy"},
+			want: [][]string{{"build", "lint"}, {"test"}, {"deploy"}},
+		},
+		{
+			name: "overlapping selections",
+			jobs: []string{"test", "build", "test"},
+			want: [][]string{{"build"}, {"test"}},
+		},
+		{
+			name:    "unknown job",
+			jobs:    []string{"test", "release"},
+			wantErr: "job release not found in plan (jobs: build, deploy, docs, lint, test)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := selectJobs(planWorkflow(t, selectionWorkflow), tt.jobs)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, stageJobs(plan))
+		})
+	}
+}
+
+func TestSkipJobs(t *testing.T) {
+	tests := []struct {
+		name    string
+		jobs    []string
+		want    [][]string
+		wantErr string
+	}{
+		{
+			name: "job nothing needs",
+			jobs: []string{"docs"},
+			want: [][]string{{"build", "lint"}, {"test"}, {"deploy"}},
+		},
+		{
+			name: "job and everything needing it",
+			jobs: []string{"test", "deploy"},
+			want: [][]string{{"build", "docs", "lint"}},
+		},
+		{
+			name: "unknown jobs are ignored",
+			jobs: []string{"release"},
+			want: [][]string{{"build", "docs", "lint"}, {"test"}, {"deploy"}},
+		},
+		{
+			name:    "job another job needs",
+			jobs:    []string{"lint"},
+			wantErr: "job deploy needs skipped job lint",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := skipJobs(planWorkflow(t, selectionWorkflow), tt.jobs)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, stageJobs(plan))
+		})
+	}
+}
+
+func TestParseMatrixSelector(t *testing.T) {
+	tests := []struct {
+		name      string
+		selectors []string
+		want      map[string][]string
+		wantErr   bool
+	}{
+		{name: "none", want: map[string][]string{}},
+		{
+			name:      "one selector",
+			selectors: []string{"os=ubuntu-latest,node=20"},
+			want:      map[string][]string{"os": {"ubuntu-latest"}, "node": {"20"}},
+		},
+		{
+			name:      "repeated keys",
+			selectors: []string{"node=18,node=20", "node=22"},
+			want:      map[string][]string{"node": {"18", "20", "22"}},
+		},
+		{
+			name:      "spaces and empty pairs",
+			selectors: []string{" os = ubuntu-latest ,, "},
+			want:      map[string][]string{"os": {"ubuntu-latest"}},
+		},
+		{
+			name:      "values with equals signs",
+			selectors: []string{"flags=a=b"},
+			want:      map[string][]string{"flags": {"a=b"}},
+		},
+		{name: "missing value", selectors: []string{"os"}, wantErr: true},
+		{name: "missing key", selectors: []string{"=ubuntu-latest"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMatrixSelector(tt.selectors)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMatrixSelection(t *testing.T) {
+	tests := []struct {
+		name    string
+		matrix  map[string][]string
+		want    []string // os/node of the kept combinations of job test
+		wantErr string
+	}{
+		{
+			name: "no filter",
+			want: []string{"ubuntu-latest/18", "ubuntu-latest/20", "windows-latest/20"},
+		},
+		{
+			name:   "one key",
+			matrix: map[string][]string{"os": {"ubuntu-latest"}},
+			want:   []string{"ubuntu-latest/18", "ubuntu-latest/20"},
+		},
+		{
+			name:   "numbers match their text",
+			matrix: map[string][]string{"node": {"20"}},
+			want:   []string{"ubuntu-latest/20", "windows-latest/20"},
+		},
+		{
+			name:   "several values of a key",
+			matrix: map[string][]string{"os": {"ubuntu-latest", "windows-latest"}, "node": {"18"}},
+			want:   []string{"ubuntu-latest/18"},
+		},
+		{
+			name:   "keys the matrix lacks do not constrain",
+			matrix: map[string][]string{"region": {"eu"}},
+			want:   []string{"ubuntu-latest/18", "ubuntu-latest/20", "windows-latest/20"},
+		},
+		{
+			name:    "unknown value",
+			matrix:  map[string][]string{"os": {"macos-latest"}},
+			wantErr: "matrix selector matches no combination of job test",
+		},
+		{
+			name:    "only an excluded combination",
+			matrix:  map[string][]string{"os": {"windows-latest"}, "node": {"18"}},
+			wantErr: "matrix selector matches no combination of job test",
+		},
+	}
+
+	plan := planWorkflow(t, selectionWorkflow)
+	var job *model.Job
+	for _, stage := range plan.Stages {
+		for _, run := range stage.Runs {
+			if run.JobID == "test" {
+				job = run.Job()
+			}
+		}
+	}
+	require.NotNil(t, job)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := matrixFilter(tt.matrix)
+			err := checkMatrixSelection(plan, filter)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			matrixes, err := job.GetMatrixes()
+			require.NoError(t, err)
+			var kept []string
+			for _, combination := range filterMatrixes(matrixes, filter) {
+				kept = append(kept, fmt.Sprintf("%v/%v", combination["os"], combination["node"]))
+			}
+			sort.Strings(kept)
+			assert.Equal(t, tt.want, kept)
+		})
+	}
+}
+
+func TestParseMatrixParam(t *testing.T) {
+	got, err := parseMatrixParam("os=ubuntu-latest,node=20")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"os": {"ubuntu-latest"}, "node": {"20"}}, got)
+
+	got, err = parseMatrixParam(map[string]interface{}{"os": "ubuntu-latest", "node": []interface{}{float64(18), float64(20)}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"os": {"ubuntu-latest"}, "node": {"18", "20"}}, got)
+
+	_, err = parseMatrixParam(42)
+	assert.Error(t, err)
+}
@@ -0,0 +1,118 @@
+package bridge
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LifecycleRun describes a run of unit jobs outside Apply, such as the
+// verification run of Refresh or the teardown run of Destroy
+type LifecycleRun struct {
+	Workflow  string   // Bundle path of the workflow to run
+	EventName string   // Empty picks an event the workflow triggers on
+	Jobs      []string // Jobs to run; empty means all jobs of the event
+}
+
+// lifecycleAnnotations maps each pseudo-event to the annotation naming its jobs
+var lifecycleAnnotations = map[string]string{
+	EventConfigHubRefresh: AnnotationRefreshJob,
+	EventDestroy:          AnnotationDestroyJob,
+}
+
+// isLifecycleEvent reports whether an event is a bridge pseudo-event
+func isLifecycleEvent(name string) bool {
+	_, ok := lifecycleAnnotations[name]
+	return ok
+}
+
+// findLifecycleRun returns the run a unit declares for a pseudo-event, or nil
+// when it declares none. The event's annotation names jobs of the entrypoint;
+// otherwise the first workflow, entrypoint first, triggering on the event is used.
+func findLifecycleRun(bundle *Bundle, annotations map[string]string, event string) (*LifecycleRun, error) {
+	annotation := lifecycleAnnotations[event]
+	if value, ok := annotations[annotation]; ok {
+		jobs, err := parseStringList(value)
+		if err != nil || len(jobs) == 0 {
+			return nil, fmt.Errorf("%s annotation must name at least one job", annotation)
+		}
+
+		workflow, err := parseWorkflowDocument(bundle.Files[bundle.Entrypoint])
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", bundle.Entrypoint, err)
+		}
+		defined := asMap(workflow["jobs"])
+		for _, job := range jobs {
+			if _, ok := defined[job]; !ok {
+				return nil, fmt.Errorf("%s job %s not found in %s", event, job, bundle.Entrypoint)
+			}
+		}
+
+		run := &LifecycleRun{Workflow: bundle.Entrypoint, Jobs: jobs}
+		if _, ok := normalizeTriggers(workflow["on"])[event]; ok {
+			run.EventName = event
+		}
+		return run, nil
+	}
+
+	names := []string{bundle.Entrypoint}
+	for _, name := range bundle.Workflows() {
+		if name != bundle.Entrypoint {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		workflow, err := parseWorkflowDocument(bundle.Files[name])
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", name, err)
+		}
+		if _, ok := normalizeTriggers(workflow["on"])[event]; ok {
+			return &LifecycleRun{Workflow: name, EventName: event}, nil
+		}
+	}
+	return nil, nil
+}
+
+// validateLifecycleRuns checks the lifecycle runs a unit declares
+func validateLifecycleRuns(bundle *Bundle, annotations map[string]string) error {
+	for event := range lifecycleAnnotations {
+		if _, err := findLifecycleRun(bundle, annotations, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseWorkflowDocument decodes a workflow into generic YAML values
+func parseWorkflowDocument(content string) (map[string]interface{}, error) {
+	var workflow map[string]interface{}
+	if err := yaml.Unmarshal([]byte(content), &workflow); err != nil {
+		return nil, err
+	}
+	return workflow, nil
+}
+
+// skippedLifecycleJobs returns the annotated refresh and teardown jobs, which
+// Apply leaves out. Jobs that were selected explicitly still run.
+func skippedLifecycleJobs(data []byte, selected []string) []string {
+	explicit := make(map[string]bool, len(selected))
+	for _, id := range selected {
+		explicit[id] = true
+	}
+
+	annotations := unitAnnotations(data)
+	var skipped []string
+	for _, annotation := range []string{AnnotationRefreshJob, AnnotationDestroyJob} {
+		value, ok := annotations[annotation]
+		if !ok {
+			continue
+		}
+		jobs, _ := parseStringList(value)
+		for _, job := range jobs {
+			if !explicit[job] {
+				skipped = append(skipped, job)
+			}
+		}
+	}
+	return skipped
+}
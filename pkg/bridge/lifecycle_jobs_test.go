@@ -0,0 +1,124 @@
+package bridge
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const lifecycleWorkflow = `name: deploy
+on: workflow_dispatch
+jobs:
+  deploy:
+    runs-on: ubuntu-latest
+    steps:
+      - run: ./deploy.sh
+  verify:
+    runs-on: ubuntu-latest
+    steps:
+      - run: ./verify.sh
+  teardown:
+    runs-on: ubuntu-latest
+    steps:
+      - run: ./teardown.sh
+`
+
+const teardownWorkflow = `on: destroy
+jobs:
+  cleanup:
+    runs-on: ubuntu-latest
+    steps:
+      - run: ./cleanup.sh
+`
+
+func TestFindLifecycleRun(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       map[string]string
+		annotations map[string]string
+		event       string
+		want        *LifecycleRun
+		wantErr     string
+	}{
+		{
+			name:  "nothing declared",
+			files: map[string]string{DefaultWorkflowFile: lifecycleWorkflow},
+			event: EventDestroy,
+		},
+		{
+			name:        "annotated jobs of the entrypoint",
+			files:       map[string]string{DefaultWorkflowFile: lifecycleWorkflow},
+			annotations: map[string]string{AnnotationRefreshJob: "verify"},
+			event:       EventConfigHubRefresh,
+			want:        &LifecycleRun{Workflow: DefaultWorkflowFile, Jobs: []string{"verify"}},
+		},
+		{
+			name:        "annotation of the other event",
+			files:       map[string]string{DefaultWorkflowFile: lifecycleWorkflow},
+			annotations: map[string]string{AnnotationRefreshJob: "verify"},
+			event:       EventDestroy,
+		},
+		{
+			name: "annotated jobs of an entrypoint triggering on the event",
+			files: map[string]string{DefaultWorkflowFile: "on: [push, destroy]\njobs:\n  teardown:\n    runs-on: ubuntu-latest\n" +
+				"    steps:\n      - run: ./teardown.sh\n"},
+			annotations: map[string]string{AnnotationDestroyJob: "teardown"},
+			event:       EventDestroy,
+			want:        &LifecycleRun{Workflow: DefaultWorkflowFile, EventName: EventDestroy, Jobs: []string{"teardown"}},
+		},
+		{
+			name: "workflow triggering on the event",
+			files: map[string]string{
+				DefaultWorkflowFile:                lifecycleWorkflow,
+				".github/workflows/teardown.yml":   teardownWorkflow,
+				".github/workflows/unrelated.yaml": "on: push\njobs: {}\n",
+			},
+			event: EventDestroy,
+			want:  &LifecycleRun{Workflow: ".github/workflows/teardown.yml", EventName: EventDestroy},
+		},
+		{
+			name:        "empty annotation",
+			files:       map[string]string{DefaultWorkflowFile: lifecycleWorkflow},
+			annotations: map[string]string{AnnotationDestroyJob: " , "},
+			event:       EventDestroy,
+			wantErr:     AnnotationDestroyJob + " annotation must name at least one job",
+		},
+		{
+			name:        "unknown annotated job",
+			files:       map[string]string{DefaultWorkflowFile: lifecycleWorkflow},
+			annotations: map[string]string{AnnotationDestroyJob: "teardown,purge"},
+			event:       EventDestroy,
+			wantErr:     "destroy job purge not found in " + DefaultWorkflowFile,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bundle := &Bundle{Entrypoint: DefaultWorkflowFile, Files: tt.files}
+			run, err := findLifecycleRun(bundle, tt.annotations, tt.event)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				assert.Error(t, validateLifecycleRuns(bundle, tt.annotations))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, run)
+			assert.NoError(t, validateLifecycleRuns(bundle, tt.annotations))
+		})
+	}
+}
+
+func TestSkippedLifecycleJobs(t *testing.T) {
+	unit := []byte(`apiVersion: actions.confighub.com/v1alpha1
+kind: Actions
+metadata:
+  name: deploy
+  annotations:
+    actions.confighub.com/refresh-job: verify
+    actions.confighub.com/destroy-job: teardown
+` + lifecycleWorkflow)
+
+	assert.Equal(t, []string{"verify", "teardown"}, skippedLifecycleJobs(unit, nil))
+	assert.Equal(t, []string{"verify"}, skippedLifecycleJobs(unit, []string{"deploy", "teardown"}))
+	assert.Empty(t, skippedLifecycleJobs([]byte(lifecycleWorkflow), nil))
+}
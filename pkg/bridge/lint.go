@@ -0,0 +1,576 @@
+package bridge
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severities of lint findings, reported as the Level of a Warning
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// RuleInfo describes a lint rule
+type RuleInfo struct {
+	ID          string
+	Severity    string // Level of the rule's findings: error, warning or info
+	Description string
+	Fix         string // How to fix a finding
+	DocURL      string
+}
+
+// Rule checks workflows for one kind of issue. Rules report findings at
+// nodes of the parsed workflow; the Linter turns them into warnings.
+type Rule interface {
+	Info() RuleInfo
+	Check(workflow *LintWorkflow, report func(Finding))
+}
+
+// Finding is an issue a rule found in a workflow
+type Finding struct {
+	Node    *yaml.Node
+	Text    string // Part of a scalar Node the finding points at; empty for the node
+	Offset  int    // Byte offset into Text the finding points at
+	Job     string
+	Step    string
+	Message string
+	Action  string
+}
+
+// funcRule is a rule backed by a check function
+type funcRule struct {
+	info  RuleInfo
+	check func(*LintWorkflow, func(Finding))
+}
+
+// NewRule creates a rule from its description and a check function
+func NewRule(info RuleInfo, check func(workflow *LintWorkflow, report func(Finding))) Rule {
+	return &funcRule{info: info, check: check}
+}
+
+func (r *funcRule) Info() RuleInfo { return r.info }
+
+func (r *funcRule) Check(workflow *LintWorkflow, report func(Finding)) {
+	if r.check != nil {
+		r.check(workflow, report)
+	}
+}
+
+// LintWorkflow is a parsed workflow as rules see it
+type LintWorkflow struct {
+	Root     *yaml.Node // Root mapping; keys and values carry their line and column
+	Source   []byte
+	Secrets  []string                          // Secrets the run provides; nil when unknown
+	ReadFile func(name string) ([]byte, error) // Reads files of the repository; nil when unavailable
+}
+
+// LintOptions describes the run a workflow is linted for
+type LintOptions struct {
+	Secrets []string // Names of the secrets the run provides; nil when unknown
+	// ReadFile reads a file by its path from the repository root, such as
+	// the action.yml of a local action. Nil skips the checks that need it.
+	ReadFile func(name string) ([]byte, error)
+}
+
+// LintJob is a job of a workflow
+type LintJob struct {
+	ID   string
+	Key  *yaml.Node
+	Node *yaml.Node
+}
+
+// LintStep is a step of a job
+type LintStep struct {
+	Job   string
+	Name  string // Name, id or action of the step, or #N
+	Index int
+	Node  *yaml.Node
+}
+
+// Jobs returns the jobs of the workflow in order
+func (w *LintWorkflow) Jobs() []LintJob {
+	var jobs []LintJob
+	_, node := mappingValue(w.Root, "jobs")
+	eachPair(node, func(key, value *yaml.Node) {
+		jobs = append(jobs, LintJob{ID: key.Value, Key: key, Node: value})
+	})
+	return jobs
+}
+
+// Steps returns the steps of all jobs in order
+func (w *LintWorkflow) Steps() []LintStep {
+	var steps []LintStep
+	for _, job := range w.Jobs() {
+		_, node := mappingValue(job.Node, "steps")
+		if node == nil || node.Kind != yaml.SequenceNode {
+			continue
+		}
+		for i, step := range node.Content {
+			steps = append(steps, LintStep{Job: job.ID, Name: stepLabel(step, i), Index: i, Node: resolveNode(step)})
+		}
+	}
+	return steps
+}
+
+// Values calls fn with every scalar value of the workflow, the key it
+// belongs to, and its job and step. Keys and comments are not values, and
+// aliases are passed where they are anchored.
+func (w *LintWorkflow) Values(fn func(key, value *yaml.Node, job, step string)) {
+	w.values(func(key, value *yaml.Node, job *LintJob, step *LintStep) {
+		jobID, stepName := "", ""
+		if job != nil {
+			jobID = job.ID
+		}
+		if step != nil {
+			stepName = step.Name
+		}
+		fn(key, value, jobID, stepName)
+	})
+}
+
+// values calls fn with every scalar value and the job and step it belongs
+// to, which are nil outside jobs and steps
+func (w *LintWorkflow) values(fn func(key, value *yaml.Node, job *LintJob, step *LintStep)) {
+	var walk func(key, node *yaml.Node, job *LintJob, step *LintStep)
+	walk = func(key, node *yaml.Node, job *LintJob, step *LintStep) {
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				walk(node.Content[i], node.Content[i+1], job, step)
+			}
+		case yaml.SequenceNode:
+			for _, item := range node.Content {
+				walk(key, item, job, step)
+			}
+		case yaml.ScalarNode:
+			fn(key, node, job, step)
+		}
+	}
+
+	eachPair(w.Root, func(key, value *yaml.Node) {
+		if key.Value != "jobs" || value.Kind != yaml.MappingNode {
+			walk(key, value, nil, nil)
+			return
+		}
+		eachPair(value, func(id, node *yaml.Node) {
+			job := &LintJob{ID: id.Value, Key: id, Node: node}
+			eachPair(node, func(key, value *yaml.Node) {
+				if key.Value != "steps" || value.Kind != yaml.SequenceNode {
+					walk(key, value, job, nil)
+					return
+				}
+				for i, item := range value.Content {
+					step := &LintStep{Job: job.ID, Name: stepLabel(item, i), Index: i, Node: resolveNode(item)}
+					eachPair(item, func(key, value *yaml.Node) {
+						walk(key, value, job, step)
+					})
+				}
+			})
+		})
+	})
+}
+
+// position finds text in the source lines of a scalar node, and offset
+// bytes into it. Text that is not in the source as written, such as folded
+// or escaped text, is reported at the node.
+func (w *LintWorkflow) position(node *yaml.Node, text string, offset int) (int, int) {
+	if text == "" {
+		return node.Line, node.Column
+	}
+	lines := strings.Split(string(w.Source), "\n")
+	// A block scalar starts on the line after its indicator
+	last := node.Line + strings.Count(node.Value, "\n") + 1
+	for line := node.Line; line <= last && line <= len(lines); line++ {
+		from := 0
+		if line == node.Line {
+			from = node.Column - 1
+		}
+		if source := lines[line-1]; from <= len(source) {
+			if i := strings.Index(source[from:], text); i >= 0 {
+				return line, from + i + offset + 1
+			}
+		}
+	}
+	return node.Line, node.Column
+}
+
+// Linter runs lint rules over workflows
+type Linter struct {
+	rules    []Rule
+	disabled map[string]bool
+	severity map[string]string // Severity overrides by rule ID
+}
+
+// ruleIDPattern matches valid rule IDs
+var ruleIDPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+// NewLinter creates a linter with the built-in rules
+func NewLinter() *Linter {
+	l := &Linter{
+		disabled: make(map[string]bool),
+		severity: make(map[string]string),
+	}
+	if err := l.Register(slices.Concat(builtinRules(), expressionRules(), schemaRules())...); err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// Register adds rules. Rule IDs must be unique. Either all rules are added
+// or, on error, none.
+func (l *Linter) Register(rules ...Rule) error {
+	ids := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		info := rule.Info()
+		if !ruleIDPattern.MatchString(info.ID) {
+			return fmt.Errorf("invalid rule ID %q", info.ID)
+		}
+		if err := validateSeverity(info.Severity); err != nil {
+			return fmt.Errorf("rule %s: %w", info.ID, err)
+		}
+		if _, ok := l.rule(info.ID); ok || ids[info.ID] {
+			return fmt.Errorf("rule %s is already registered", info.ID)
+		}
+		ids[info.ID] = true
+	}
+	l.rules = append(l.rules, rules...)
+	return nil
+}
+
+// Configure applies a lint configuration: its custom rules are registered,
+// then rules are turned on or off and their severities overridden. The whole
+// configuration is checked first, so an invalid one changes nothing.
+func (l *Linter) Configure(config *LintConfig) error {
+	custom := make([]Rule, 0, len(config.Custom))
+	customIDs := make(map[string]bool, len(config.Custom))
+	for _, c := range config.Custom {
+		rule, err := c.rule()
+		if err != nil {
+			return err
+		}
+		custom = append(custom, rule)
+		customIDs[rule.Info().ID] = true
+	}
+
+	var unknown []string
+	for id, setting := range config.Rules {
+		if _, ok := l.rule(id); !ok && !customIDs[id] {
+			unknown = append(unknown, id)
+			continue
+		}
+		if setting.Severity != "" {
+			if err := validateSeverity(setting.Severity); err != nil {
+				return fmt.Errorf("rule %s: %w", id, err)
+			}
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown rule(s) %s", strings.Join(unknown, ", "))
+	}
+
+	if err := l.Register(custom...); err != nil {
+		return err
+	}
+	for id, setting := range config.Rules {
+		if setting.Enabled != nil {
+			l.disabled[id] = !*setting.Enabled
+		}
+		if setting.Severity != "" {
+			l.severity[id] = setting.Severity
+		}
+	}
+	return nil
+}
+
+// Rules describes the registered rules, with configured severities, by ID
+func (l *Linter) Rules() []RuleInfo {
+	infos := make([]RuleInfo, 0, len(l.rules))
+	for _, rule := range l.rules {
+		infos = append(infos, l.info(rule))
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ID < infos[j].ID
+	})
+	return infos
+}
+
+// Rule describes a registered rule
+func (l *Linter) Rule(id string) (RuleInfo, bool) {
+	rule, ok := l.rule(id)
+	if !ok {
+		return RuleInfo{}, false
+	}
+	return l.info(rule), true
+}
+
+// Enabled reports whether a rule is registered and turned on
+func (l *Linter) Enabled(id string) bool {
+	_, ok := l.rule(id)
+	return ok && !l.disabled[id]
+}
+
+func (l *Linter) rule(id string) (Rule, bool) {
+	for _, rule := range l.rules {
+		if rule.Info().ID == id {
+			return rule, true
+		}
+	}
+	return nil, false
+}
+
+// info returns the description of a rule with its configured severity
+func (l *Linter) info(rule Rule) RuleInfo {
+	info := rule.Info()
+	if severity, ok := l.severity[info.ID]; ok {
+		info.Severity = severity
+	}
+	return info
+}
+
+// Lint checks a workflow with the enabled rules
+func (l *Linter) Lint(data []byte) []Warning {
+	return l.LintWith(data, LintOptions{})
+}
+
+// LintWith checks a workflow for a run with the enabled rules. Findings
+// suppressed by an actions-bridge-ignore comment are left out. A rule that
+// panics is reported as an error of that rule.
+func (l *Linter) LintWith(data []byte, options LintOptions) []Warning {
+	warnings := []Warning{}
+	doc, root, err := parseWorkflowTree(data)
+	if err != nil {
+		if l.Enabled(RuleSyntax) {
+			info, _ := l.Rule(RuleSyntax)
+			warning := Warning{Rule: RuleSyntax, Level: info.Severity, Message: fmt.Sprintf("Workflow does not parse: %v", err)}
+			if match := yamlErrorLine.FindStringSubmatch(err.Error()); match != nil {
+				warning.Line, _ = strconv.Atoi(match[1])
+			}
+			warnings = append(warnings, warning)
+		}
+		return warnings
+	}
+	if root == nil {
+		return warnings
+	}
+
+	workflow := &LintWorkflow{Root: root, Source: data, Secrets: options.Secrets, ReadFile: options.ReadFile}
+	suppressions := collectSuppressions(doc)
+	for _, rule := range l.rules {
+		info := l.info(rule)
+		if l.disabled[info.ID] {
+			continue
+		}
+		report := func(finding Finding) {
+			warning := Warning{
+				Rule:    info.ID,
+				Level:   info.Severity,
+				Message: finding.Message,
+				Action:  finding.Action,
+				Job:     finding.Job,
+				Step:    finding.Step,
+			}
+			if finding.Node != nil {
+				warning.Line, warning.Column = workflow.position(finding.Node, finding.Text, finding.Offset)
+			}
+			if !suppressions.suppressed(info.ID, warning.Line) {
+				warnings = append(warnings, warning)
+			}
+		}
+		l.check(rule, info, workflow, report)
+	}
+	return warnings
+}
+
+// check runs a rule, reporting a panic as a finding of the rule
+func (l *Linter) check(rule Rule, info RuleInfo, workflow *LintWorkflow, report func(Finding)) {
+	defer func() {
+		if r := recover(); r != nil {
+			report(Finding{Message: fmt.Sprintf("Rule %s failed: %v", info.ID, r)})
+		}
+	}()
+	rule.Check(workflow, report)
+}
+
+// yamlErrorLine matches the line number in a YAML syntax error
+var yamlErrorLine = regexp.MustCompile(`^yaml: line (\d+):`)
+
+// suppressionComment matches an inline suppression such as
+// "# actions-bridge-ignore: AB012, AB013"
+var suppressionComment = regexp.MustCompile(`#\s*actions-bridge-ignore:\s*([A-Za-z0-9_,\s-]+)`)
+
+// suppression turns rules off for a range of lines
+type suppression struct {
+	from, to int
+	rules    map[string]bool
+}
+
+type suppressions []suppression
+
+func (s suppressions) suppressed(id string, line int) bool {
+	for _, sup := range s {
+		if sup.rules[id] && line >= sup.from && line <= sup.to {
+			return true
+		}
+	}
+	return false
+}
+
+// collectSuppressions reads the actions-bridge-ignore comments of a
+// workflow. A comment covers the node it is attached to: the key and value
+// it is on or above, or the sequence item it is above. A comment at the top
+// of the file, separated by a blank line, covers the whole file.
+func collectSuppressions(doc *yaml.Node) suppressions {
+	var result suppressions
+	add := func(comments string, from, to int) {
+		rules := make(map[string]bool)
+		for _, match := range suppressionComment.FindAllStringSubmatch(comments, -1) {
+			for _, id := range strings.FieldsFunc(match[1], func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' }) {
+				rules[id] = true
+			}
+		}
+		if len(rules) > 0 {
+			result = append(result, suppression{from: from, to: to, rules: rules})
+		}
+	}
+
+	var walk func(node *yaml.Node)
+	walk = func(node *yaml.Node) {
+		add(node.HeadComment+"\n"+node.LineComment, node.Line, nodeEnd(node))
+		if node.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, value := node.Content[i], node.Content[i+1]
+				add(key.HeadComment+"\n"+key.LineComment, key.Line, nodeEnd(value))
+				walk(value)
+			}
+			return
+		}
+		for _, child := range node.Content {
+			walk(child)
+		}
+	}
+	if doc != nil {
+		add(doc.HeadComment, 1, math.MaxInt)
+		for _, child := range doc.Content {
+			walk(child)
+		}
+	}
+	return result
+}
+
+// nodeEnd returns the last line of a node, including the lines of block scalars
+func nodeEnd(node *yaml.Node) int {
+	end := node.Line
+	if node.Kind == yaml.ScalarNode && (node.Style&(yaml.LiteralStyle|yaml.FoldedStyle)) != 0 {
+		end += strings.Count(strings.TrimSuffix(node.Value, "\n"), "\n") + 1
+	}
+	for _, child := range node.Content {
+		if childEnd := nodeEnd(child); childEnd > end {
+			end = childEnd
+		}
+	}
+	return end
+}
+
+// LintConfig turns rules on or off, overrides their severity and adds
+// pattern rules. In YAML:
+//
+//	rules:
+//	  AB015: off
+//	  AB013: error
+//	  AB016:
+//	    enabled: true
+//	    severity: warning
+//	custom:
+//	  - id: ACME001
+//	    severity: error
+//	    description: Deployments use the v3 deploy action
+//	    uses: acme/deploy@v2
+//	    fix: Use acme/deploy@v3
+type LintConfig struct {
+	Rules  map[string]RuleSetting `yaml:"rules"`
+	Custom []PatternRuleConfig    `yaml:"custom"`
+}
+
+// RuleSetting turns a rule on or off and overrides its severity. In YAML it
+// is on or off, a severity, or a mapping with enabled and severity.
+type RuleSetting struct {
+	Enabled  *bool  `yaml:"enabled"`
+	Severity string `yaml:"severity"`
+}
+
+// UnmarshalYAML accepts the short forms of a rule setting
+func (s *RuleSetting) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		enabled := true
+		switch value := strings.ToLower(node.Value); value {
+		case "on", "true", "yes", "enabled":
+		case "off", "false", "no", "disabled":
+			enabled = false
+		default:
+			if err := validateSeverity(value); err != nil {
+				return fmt.Errorf("line %d: expected on, off or a severity, got %q", node.Line, node.Value)
+			}
+			s.Severity = value
+		}
+		s.Enabled = &enabled
+		return nil
+	}
+
+	type plain RuleSetting
+	if err := node.Decode((*plain)(s)); err != nil {
+		return err
+	}
+	if s.Severity != "" {
+		if err := validateSeverity(s.Severity); err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+	}
+	return nil
+}
+
+// LoadLintConfig reads a lint configuration from a YAML file
+func LoadLintConfig(path string) (*LintConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read lint config: %w", err)
+	}
+	var config LintConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parse lint config %s: %w", path, err)
+	}
+	return &config, nil
+}
+
+// severityRank orders severities from info to error
+var severityRank = map[string]int{SeverityInfo: 1, SeverityWarning: 2, SeverityError: 3}
+
+// WarningsAtLeast returns the warnings of a severity or above
+func WarningsAtLeast(warnings []Warning, severity string) []Warning {
+	var result []Warning
+	for _, w := range warnings {
+		if severityRank[w.Level] >= severityRank[severity] {
+			result = append(result, w)
+		}
+	}
+	return result
+}
+
+// validateSeverity accepts error, warning and info
+func validateSeverity(severity string) error {
+	switch severity {
+	case SeverityError, SeverityWarning, SeverityInfo:
+		return nil
+	}
+	return fmt.Errorf("invalid severity %q (expected %s, %s or %s)", severity, SeverityError, SeverityWarning, SeverityInfo)
+}
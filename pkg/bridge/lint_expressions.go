@@ -0,0 +1,445 @@
+package bridge
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// expressionContextNames are the contexts of the expression language
+var expressionContextNames = map[string]bool{
+	"github": true, "env": true, "vars": true, "job": true, "jobs": true, "steps": true,
+	"runner": true, "secrets": true, "strategy": true, "matrix": true, "needs": true, "inputs": true,
+}
+
+// jobContextNames are the contexts only available within jobs
+var jobContextNames = map[string]bool{
+	"job": true, "steps": true, "runner": true, "strategy": true, "matrix": true, "needs": true,
+}
+
+// expressionRules returns the rules checking ${{ }} expressions
+func expressionRules() []Rule {
+	return []Rule{
+		NewRule(builtinInfo("AB022", SeverityError, "Expression does not parse",
+			"Fix the syntax, function name or number of arguments"), checkExpressionSyntax),
+		NewRule(builtinInfo("AB023", SeverityWarning, "Unknown context or context not available here",
+			"Use one of github, env, vars, job, jobs, steps, runner, secrets, strategy, matrix, needs or inputs"), checkExpressionContexts),
+		NewRule(builtinInfo("AB024", SeverityWarning, "Matrix key is not defined by the job",
+			"Add the key to strategy.matrix or fix its name"), checkMatrixReferences),
+		NewRule(builtinInfo("AB025", SeverityWarning, "Job is not in needs",
+			"Add the job to needs or fix its name"), checkNeedsReferences),
+		NewRule(builtinInfo("AB026", SeverityWarning, "Input is not declared",
+			"Declare the input under workflow_dispatch or workflow_call, or fix its name"), checkInputReferences),
+		NewRule(builtinInfo("AB027", SeverityWarning, "Job output is not declared",
+			"Declare the output under the job's outputs or fix its name"), checkOutputReferences),
+		NewRule(builtinInfo("AB028", SeverityWarning, "Step is not defined before this point",
+			"Give the step an id and refer to it from later steps only"), checkStepReferences),
+		NewRule(builtinInfo("AB029", SeverityWarning, "Secret is not provided to the run",
+			"Add the secret to the unit's secrets or fix its name"), checkSecretReferences),
+	}
+}
+
+// workflowExpression is a ${{ }} expression, or an if condition, in a value
+type workflowExpression struct {
+	expr *expression // Nil if it does not parse
+	node *yaml.Node
+	text string // Text of the expression in node, ${{ }} included
+	base int    // Offset of the expression source in text
+	job  *LintJob
+	step *LintStep
+}
+
+// finding reports an issue at an offset of the expression source
+func (x *workflowExpression) finding(offset int, message string) Finding {
+	finding := Finding{Node: x.node, Text: x.text, Offset: x.base + offset, Message: message}
+	if x.job != nil {
+		finding.Job = x.job.ID
+	}
+	if x.step != nil {
+		finding.Step = x.step.Name
+	}
+	return finding
+}
+
+// eachExpression calls fn with every expression of a workflow. Job and step
+// if conditions are expressions without ${{ }} too. Expressions that do not
+// parse are passed with their error.
+func eachExpression(workflow *LintWorkflow, fn func(x *workflowExpression, err error)) {
+	conditions := make(map[*yaml.Node]bool)
+	for _, job := range workflow.Jobs() {
+		if _, condition := mappingValue(job.Node, "if"); condition != nil {
+			conditions[condition] = true
+		}
+	}
+	for _, step := range workflow.Steps() {
+		if _, condition := mappingValue(step.Node, "if"); condition != nil {
+			conditions[condition] = true
+		}
+	}
+
+	workflow.values(func(key, value *yaml.Node, job *LintJob, step *LintStep) {
+		if conditions[value] && !strings.Contains(value.Value, "${{") {
+			x := &workflowExpression{node: value, text: value.Value, job: job, step: step}
+			var err error
+			x.expr, err = parseExpression(value.Value)
+			fn(x, err)
+			return
+		}
+
+		spans, err := expressionSpans(value.Value)
+		for _, span := range spans {
+			text, base := expressionLine(value.Value, span.Start, span.End)
+			x := &workflowExpression{node: value, text: text, base: base + 3, job: job, step: step}
+			var err error
+			x.expr, err = parseExpression(span.Source)
+			fn(x, err)
+		}
+		var exprErr *ExpressionError
+		if errors.As(err, &exprErr) {
+			// The unclosed ${{ runs to the end of the value
+			text, base := expressionLine(value.Value, exprErr.Offset, len(value.Value))
+			fn(&workflowExpression{node: value, text: text, base: base, job: job, step: step}, &ExpressionError{Message: exprErr.Message})
+		}
+	})
+}
+
+// expressionLine returns the line of a value holding the text from start to
+// end, and the offset of start in it. Locating the line rather than the
+// expression tells apart the same expression on several lines of a script.
+func expressionLine(value string, start, end int) (string, int) {
+	if strings.Contains(value[start:end], "\n") {
+		return value[start:end], 0
+	}
+	from := strings.LastIndex(value[:start], "\n") + 1
+	to := strings.Index(value[end:], "\n")
+	if to < 0 {
+		to = len(value)
+	} else {
+		to += end
+	}
+	return value[from:to], start - from
+}
+
+// exprReference is a context followed by property names, such as
+// needs.build.outputs.version
+type exprReference struct {
+	context string
+	path    []string // Property names as written
+	offsets []int    // Offsets of the context and of each property
+}
+
+// expressionReferences returns the references of an expression. A reference
+// ends where a value is indexed by an expression or filtered.
+func expressionReferences(expr *expression) []exprReference {
+	var refs []exprReference
+	var visit func(node exprNode)
+	visit = func(node exprNode) {
+		switch n := node.(type) {
+		case *exprContext:
+			refs = append(refs, exprReference{context: n.name, offsets: []int{n.at}})
+		case *exprProperty:
+			if ref, ok := referenceOf(n); ok {
+				refs = append(refs, ref)
+				return
+			}
+			visit(n.target)
+		case *exprIndex:
+			visit(n.target)
+			visit(n.index)
+		case *exprFilter:
+			visit(n.target)
+		case *exprCall:
+			for _, arg := range n.args {
+				visit(arg)
+			}
+		case *exprNot:
+			visit(n.operand)
+		case *exprBinary:
+			visit(n.left)
+			visit(n.right)
+		}
+	}
+	visit(expr.root)
+	return refs
+}
+
+// referenceOf returns the reference a chain of properties on a context
+// makes up
+func referenceOf(node exprNode) (exprReference, bool) {
+	var names []string
+	var offsets []int
+	for {
+		switch n := node.(type) {
+		case *exprProperty:
+			names = append([]string{n.name}, names...)
+			offsets = append([]int{n.at}, offsets...)
+			node = n.target
+			continue
+		case *exprContext:
+			return exprReference{context: n.name, path: names, offsets: append([]int{n.at}, offsets...)}, true
+		}
+		return exprReference{}, false
+	}
+}
+
+// eachReference calls fn with every reference of the expressions that parse
+func eachReference(workflow *LintWorkflow, fn func(x *workflowExpression, ref exprReference)) {
+	eachExpression(workflow, func(x *workflowExpression, err error) {
+		if err != nil {
+			return
+		}
+		for _, ref := range expressionReferences(x.expr) {
+			fn(x, ref)
+		}
+	})
+}
+
+// checkExpressionSyntax reports expressions that do not parse, use unknown
+// functions or pass the wrong number of arguments
+func checkExpressionSyntax(workflow *LintWorkflow, report func(Finding)) {
+	eachExpression(workflow, func(x *workflowExpression, err error) {
+		if err == nil {
+			return
+		}
+		var exprErr *ExpressionError
+		if errors.As(err, &exprErr) {
+			report(x.finding(exprErr.Offset, "Invalid expression: "+exprErr.Message))
+			return
+		}
+		report(x.finding(0, "Invalid expression: "+err.Error()))
+	})
+}
+
+// checkExpressionContexts reports unknown contexts and contexts used where
+// they are not available
+func checkExpressionContexts(workflow *LintWorkflow, report func(Finding)) {
+	eachReference(workflow, func(x *workflowExpression, ref exprReference) {
+		switch {
+		case !expressionContextNames[ref.context]:
+			report(x.finding(ref.offsets[0], fmt.Sprintf("Unrecognized named-value: '%s'", ref.context)))
+		case x.job == nil && jobContextNames[ref.context]:
+			report(x.finding(ref.offsets[0], fmt.Sprintf("Context '%s' is only available within jobs", ref.context)))
+		case x.job != nil && ref.context == "jobs":
+			report(x.finding(ref.offsets[0], "Context 'jobs' is only available in workflow_call outputs"))
+		}
+	})
+}
+
+// checkMatrixReferences reports matrix keys the job's matrix does not define
+func checkMatrixReferences(workflow *LintWorkflow, report func(Finding)) {
+	eachReference(workflow, func(x *workflowExpression, ref exprReference) {
+		if ref.context != "matrix" || x.job == nil || len(ref.path) == 0 {
+			return
+		}
+		keys, known := matrixKeys(x.job.Node)
+		switch {
+		case !known:
+		case keys == nil:
+			report(x.finding(ref.offsets[0], fmt.Sprintf("Job %s has no matrix", x.job.ID)))
+		case !keys[strings.ToLower(ref.path[0])]:
+			report(x.finding(ref.offsets[1], fmt.Sprintf("Matrix of job %s has no key '%s'", x.job.ID, ref.path[0])))
+		}
+	})
+}
+
+// matrixKeys returns the lowercase keys of a job's matrix, nil for a job
+// without a matrix. A matrix built by an expression is not known.
+func matrixKeys(job *yaml.Node) (map[string]bool, bool) {
+	_, strategy := mappingValue(job, "strategy")
+	if strategy != nil && strategy.Kind != yaml.MappingNode {
+		return nil, false
+	}
+	_, matrix := mappingValue(strategy, "matrix")
+	if matrix == nil {
+		return nil, true
+	}
+	if matrix.Kind != yaml.MappingNode {
+		return nil, false
+	}
+
+	keys := make(map[string]bool)
+	known := true
+	eachPair(matrix, func(key, value *yaml.Node) {
+		switch key.Value {
+		case "exclude":
+		case "include":
+			if value.Kind != yaml.SequenceNode {
+				known = false
+				return
+			}
+			for _, item := range value.Content {
+				if item = resolveNode(item); item.Kind != yaml.MappingNode {
+					known = false
+					continue
+				}
+				eachPair(item, func(key, _ *yaml.Node) {
+					keys[strings.ToLower(key.Value)] = true
+				})
+			}
+		default:
+			keys[strings.ToLower(key.Value)] = true
+		}
+	})
+	return keys, known
+}
+
+// checkNeedsReferences reports needs entries naming jobs that do not exist,
+// and references to jobs the job does not need
+func checkNeedsReferences(workflow *LintWorkflow, report func(Finding)) {
+	jobs := workflowJobs(workflow)
+	needed := make(map[string]map[string]bool)
+	for _, job := range workflow.Jobs() {
+		needed[job.ID] = make(map[string]bool)
+		for _, need := range jobNeeds(job.Node) {
+			if _, ok := jobs[strings.ToLower(need.Value)]; !ok {
+				report(Finding{Node: need, Job: job.ID, Message: fmt.Sprintf("Job %s needs job '%s', which does not exist", job.ID, need.Value)})
+			}
+			needed[job.ID][strings.ToLower(need.Value)] = true
+		}
+	}
+
+	eachReference(workflow, func(x *workflowExpression, ref exprReference) {
+		if ref.context != "needs" || x.job == nil || len(ref.path) == 0 {
+			return
+		}
+		if !needed[x.job.ID][strings.ToLower(ref.path[0])] {
+			report(x.finding(ref.offsets[1], fmt.Sprintf("Job %s does not need job '%s'", x.job.ID, ref.path[0])))
+		}
+	})
+}
+
+// workflowJobs returns the jobs of a workflow by lowercase ID
+func workflowJobs(workflow *LintWorkflow) map[string]LintJob {
+	jobs := make(map[string]LintJob)
+	for _, job := range workflow.Jobs() {
+		jobs[strings.ToLower(job.ID)] = job
+	}
+	return jobs
+}
+
+// jobNeeds returns the needs entries of a job, given as a job or a list
+func jobNeeds(job *yaml.Node) []*yaml.Node {
+	_, needs := mappingValue(job, "needs")
+	switch {
+	case needs == nil:
+		return nil
+	case needs.Kind == yaml.ScalarNode:
+		return []*yaml.Node{needs}
+	case needs.Kind == yaml.SequenceNode:
+		var entries []*yaml.Node
+		for _, entry := range needs.Content {
+			if entry = resolveNode(entry); entry.Kind == yaml.ScalarNode {
+				entries = append(entries, entry)
+			}
+		}
+		return entries
+	}
+	return nil
+}
+
+// checkInputReferences reports inputs no trigger declares, through inputs or
+// github.event.inputs
+func checkInputReferences(workflow *LintWorkflow, report func(Finding)) {
+	declared := make(map[string]bool)
+	_, on := mappingValue(workflow.Root, "on")
+	for _, trigger := range []string{"workflow_dispatch", "workflow_call"} {
+		_, event := mappingValue(on, trigger)
+		_, inputs := mappingValue(event, "inputs")
+		eachPair(inputs, func(key, _ *yaml.Node) {
+			declared[strings.ToLower(key.Value)] = true
+		})
+	}
+
+	eachReference(workflow, func(x *workflowExpression, ref exprReference) {
+		name, offset := "", 0
+		switch {
+		case ref.context == "inputs" && len(ref.path) > 0:
+			name, offset = ref.path[0], ref.offsets[1]
+		case ref.context == "github" && len(ref.path) > 2 && strings.EqualFold(ref.path[0], "event") && strings.EqualFold(ref.path[1], "inputs"):
+			name, offset = ref.path[2], ref.offsets[3]
+		default:
+			return
+		}
+		if !declared[strings.ToLower(name)] {
+			report(x.finding(offset, fmt.Sprintf("Input '%s' is not declared", name)))
+		}
+	})
+}
+
+// checkOutputReferences reports outputs the referenced job does not declare,
+// through needs or, in workflow_call outputs, jobs. Jobs calling a reusable
+// workflow have the outputs of that workflow and are not checked.
+func checkOutputReferences(workflow *LintWorkflow, report func(Finding)) {
+	jobs := workflowJobs(workflow)
+	eachReference(workflow, func(x *workflowExpression, ref exprReference) {
+		if (ref.context != "needs" && ref.context != "jobs") || len(ref.path) < 3 || !strings.EqualFold(ref.path[1], "outputs") {
+			return
+		}
+		job, ok := jobs[strings.ToLower(ref.path[0])]
+		if !ok {
+			return
+		}
+		if uses, _ := mappingValue(job.Node, "uses"); uses != nil {
+			return
+		}
+		_, outputs := mappingValue(job.Node, "outputs")
+		if outputs != nil && outputs.Kind != yaml.MappingNode {
+			return
+		}
+		declared := false
+		eachPair(outputs, func(key, _ *yaml.Node) {
+			declared = declared || strings.EqualFold(key.Value, ref.path[2])
+		})
+		if !declared {
+			report(x.finding(ref.offsets[3], fmt.Sprintf("Job %s has no output '%s'", job.ID, ref.path[2])))
+		}
+	})
+}
+
+// checkStepReferences reports steps that do not exist in the job or that
+// only run after the step referring to them. Job outputs may refer to any
+// step of the job.
+func checkStepReferences(workflow *LintWorkflow, report func(Finding)) {
+	ids := make(map[string]map[string]int) // Step index by lowercase id, per job
+	for _, step := range workflow.Steps() {
+		if ids[step.Job] == nil {
+			ids[step.Job] = make(map[string]int)
+		}
+		if _, id := mappingValue(step.Node, "id"); id != nil && id.Kind == yaml.ScalarNode {
+			ids[step.Job][strings.ToLower(id.Value)] = step.Index
+		}
+	}
+
+	eachReference(workflow, func(x *workflowExpression, ref exprReference) {
+		if ref.context != "steps" || x.job == nil || len(ref.path) == 0 {
+			return
+		}
+		index, ok := ids[x.job.ID][strings.ToLower(ref.path[0])]
+		switch {
+		case !ok:
+			report(x.finding(ref.offsets[1], fmt.Sprintf("Job %s has no step with id '%s'", x.job.ID, ref.path[0])))
+		case x.step != nil && index >= x.step.Index:
+			report(x.finding(ref.offsets[1], fmt.Sprintf("Step '%s' does not run before this step", ref.path[0])))
+		}
+	})
+}
+
+// checkSecretReferences reports secrets the run does not provide, when the
+// secrets of the run are known. GITHUB_TOKEN is always simulated.
+func checkSecretReferences(workflow *LintWorkflow, report func(Finding)) {
+	if workflow.Secrets == nil {
+		return
+	}
+	provided := map[string]bool{"github_token": true}
+	for _, name := range workflow.Secrets {
+		provided[strings.ToLower(name)] = true
+	}
+
+	eachReference(workflow, func(x *workflowExpression, ref exprReference) {
+		if ref.context == "secrets" && len(ref.path) > 0 && !provided[strings.ToLower(ref.path[0])] {
+			report(x.finding(ref.offsets[1], fmt.Sprintf("Secret '%s' is not provided", ref.path[0])))
+		}
+	})
+}
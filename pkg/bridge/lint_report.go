@@ -0,0 +1,270 @@
+package bridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// Formats of lint reports
+const (
+	FormatText  = "text"
+	FormatJSON  = "json"
+	FormatSARIF = "sarif"
+)
+
+// ReportFormats are the formats lint reports can be written in
+var ReportFormats = []string{FormatText, FormatJSON, FormatSARIF}
+
+// sarifSchema is the JSON schema of SARIF 2.1.0 reports
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// LintReport collects the findings of linting one or more workflow files,
+// for writing as JSON or SARIF
+type LintReport struct {
+	Files       []LintedFile  `json:"files"`
+	Warnings    []FileWarning `json:"warnings"`
+	Suggestions []string      `json:"suggestions"` // Fix hints of the rules that reported warnings
+	// Root is the directory SARIF locations are relative to, usually the
+	// repository root; when empty, files are located as given
+	Root string `json:"-"`
+
+	checker *CompatibilityChecker
+}
+
+// LintedFile is a workflow checked for a report
+type LintedFile struct {
+	File      string `json:"file"`
+	Workflow  string `json:"workflow,omitempty"` // Path of the workflow within a unit
+	Supported bool   `json:"supported"`
+	Reason    string `json:"reason,omitempty"` // Why the workflow cannot run locally
+}
+
+// FileWarning is a warning of a workflow in a report
+type FileWarning struct {
+	File     string `json:"file"`
+	Workflow string `json:"workflow,omitempty"`
+	Warning
+}
+
+// NewLintReport starts an empty report of workflows checked by checker
+func NewLintReport(checker *CompatibilityChecker) *LintReport {
+	return &LintReport{Files: []LintedFile{}, Warnings: []FileWarning{}, Suggestions: []string{}, checker: checker}
+}
+
+// Add checks a workflow and adds its findings to the report. A workflow
+// that is the whole file, or the part of it starting at line, has workflow
+// empty and is reported with the lines of the file; a workflow of a bundle
+// is reported by its path and with lines of its own.
+func (r *LintReport) Add(file, workflow string, line int, data []byte, options LintOptions) []Warning {
+	supported, reason := r.checker.IsWorkflowSupported(data)
+	r.Files = append(r.Files, LintedFile{File: file, Workflow: workflow, Supported: supported, Reason: reason})
+
+	warnings := r.checker.CheckWorkflowWith(data, options)
+	all := make([]Warning, 0, len(r.Warnings)+len(warnings))
+	for _, w := range r.Warnings {
+		all = append(all, w.Warning)
+	}
+	for _, w := range warnings {
+		all = append(all, w)
+		if workflow == "" && w.Line > 0 {
+			w.Line += line - 1
+		}
+		r.Warnings = append(r.Warnings, FileWarning{File: file, Workflow: workflow, Warning: w})
+	}
+	r.Suggestions = r.checker.SuggestFixes(all)
+	return warnings
+}
+
+// AddError records a file that could not be read or parsed. It is reported
+// as a workflow that cannot run, so the rest of the files are still checked.
+func (r *LintReport) AddError(file string, err error) {
+	r.Files = append(r.Files, LintedFile{File: file, Supported: false, Reason: err.Error()})
+}
+
+// AtLeast returns the warnings at or above a severity
+func (r *LintReport) AtLeast(severity string) []FileWarning {
+	var result []FileWarning
+	for _, w := range r.Warnings {
+		if severityRank[w.Level] >= severityRank[severity] {
+			result = append(result, w)
+		}
+	}
+	return result
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *LintReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// SARIF 2.1.0 report layout, limited to what lint reports use
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool        sarifTool         `json:"tool"`
+		Invocations []sarifInvocation `json:"invocations"`
+		Results     []sarifResult     `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		Version        string      `json:"version,omitempty"`
+		InformationURI string      `json:"informationUri,omitempty"`
+		Rules          []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID                   string            `json:"id"`
+		ShortDescription     sarifMessage      `json:"shortDescription"`
+		Help                 *sarifMessage     `json:"help,omitempty"`
+		HelpURI              string            `json:"helpUri,omitempty"`
+		DefaultConfiguration sarifRuleDefaults `json:"defaultConfiguration"`
+	}
+	sarifRuleDefaults struct {
+		Level string `json:"level"`
+	}
+	sarifInvocation struct {
+		ExecutionSuccessful        bool                `json:"executionSuccessful"`
+		ToolExecutionNotifications []sarifNotification `json:"toolExecutionNotifications,omitempty"`
+	}
+	sarifNotification struct {
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations,omitempty"`
+	}
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		RuleIndex int             `json:"ruleIndex"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Region           *sarifRegion          `json:"region,omitempty"`
+	}
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+	sarifRegion struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn,omitempty"`
+	}
+)
+
+// sarifLevel maps a severity to a SARIF level
+func sarifLevel(severity string) string {
+	if severity == SeverityInfo {
+		return "note"
+	}
+	return severity
+}
+
+// sarifURI returns the URI of a file: relative to the report's root when the
+// file is below it, as code scanning expects, and a file URL otherwise
+func (r *LintReport) sarifURI(file string) string {
+	if r.Root == "" {
+		return filepath.ToSlash(filepath.Clean(file))
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return filepath.ToSlash(filepath.Clean(file))
+	}
+	root, err := filepath.Abs(r.Root)
+	if err == nil {
+		if rel, err := filepath.Rel(root, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+}
+
+// sarifFileLocation locates a warning in its file. Lines of workflows within
+// a unit do not match the lines of the file and are left out.
+func (r *LintReport) sarifFileLocation(file, workflow string, line, column int) sarifLocation {
+	location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: r.sarifURI(file)}}}
+	if workflow == "" && line > 0 {
+		location.PhysicalLocation.Region = &sarifRegion{StartLine: line, StartColumn: column}
+	}
+	return location
+}
+
+// WriteSARIF writes the report as a SARIF 2.1.0 log for code scanning.
+// Rules that reported warnings are described with their fix hint and
+// documentation link; workflows that cannot run are tool notifications.
+func (r *LintReport) WriteSARIF(w io.Writer, toolName, toolVersion string) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           toolName,
+			Version:        toolVersion,
+			InformationURI: rulesDocURL,
+			Rules:          []sarifRule{},
+		}},
+		Invocations: []sarifInvocation{{ExecutionSuccessful: true}},
+		Results:     []sarifResult{},
+	}
+
+	for _, f := range r.Files {
+		if f.Supported {
+			continue
+		}
+		run.Invocations[0].ToolExecutionNotifications = append(run.Invocations[0].ToolExecutionNotifications, sarifNotification{
+			Level:     "error",
+			Message:   sarifMessage{Text: f.Reason},
+			Locations: []sarifLocation{r.sarifFileLocation(f.File, f.Workflow, 0, 0)},
+		})
+	}
+
+	ruleIndex := make(map[string]int)
+	for _, fw := range r.Warnings {
+		index, ok := ruleIndex[fw.Rule]
+		if !ok {
+			info, _ := r.checker.Linter().Rule(fw.Rule)
+			rule := sarifRule{
+				ID:                   fw.Rule,
+				ShortDescription:     sarifMessage{Text: info.Description},
+				HelpURI:              info.DocURL,
+				DefaultConfiguration: sarifRuleDefaults{Level: sarifLevel(info.Severity)},
+			}
+			if info.Fix != "" {
+				rule.Help = &sarifMessage{Text: info.Fix}
+			}
+			index = len(run.Tool.Driver.Rules)
+			ruleIndex[fw.Rule] = index
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+		}
+
+		message := fw.Message
+		if fw.Workflow != "" && fw.Line > 0 {
+			message = fmt.Sprintf("%s (%s, line %d)", message, fw.Workflow, fw.Line)
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    fw.Rule,
+			RuleIndex: index,
+			Level:     sarifLevel(fw.Level),
+			Message:   sarifMessage{Text: message},
+			Locations: []sarifLocation{r.sarifFileLocation(fw.File, fw.Workflow, fw.Line, fw.Column)},
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{Schema: sarifSchema, Version: "2.1.0", Runs: []sarifRun{run}})
+}
@@ -0,0 +1,325 @@
+package bridge
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RuleSyntax is the rule YAML syntax errors are reported under
+const RuleSyntax = "AB000"
+
+// rulesDocURL is the page documenting the built-in rules
+const rulesDocURL = "https://github.com/confighub/actions-bridge/blob/main/docs/RULES.md"
+
+// PatternRule reports actions by reference, or scalar values matching a
+// pattern. Custom rules from a LintConfig are pattern rules.
+type PatternRule struct {
+	RuleInfo
+	Message string         // Defaults to the description; prefixed with the action for Uses rules
+	Uses    string         // Reports uses references starting with this, e.g. actions/cache@
+	Key     string         // Matches Pattern only in values of this key
+	Pattern *regexp.Regexp // Reports every match in scalar values
+	Once    bool           // Reports Pattern once per job and step
+}
+
+// Info describes the rule
+func (r *PatternRule) Info() RuleInfo { return r.RuleInfo }
+
+// Check reports the uses references or values the rule matches
+func (r *PatternRule) Check(workflow *LintWorkflow, report func(Finding)) {
+	message := r.Message
+	if message == "" {
+		message = r.Description
+	}
+
+	reported := make(map[string]bool)
+	workflow.Values(func(key, value *yaml.Node, job, step string) {
+		if r.Uses != "" {
+			if key.Value == "uses" && strings.HasPrefix(value.Value, r.Uses) {
+				report(Finding{Node: value, Job: job, Step: step, Action: value.Value,
+					Message: fmt.Sprintf("%s: %s", value.Value, message)})
+			}
+			return
+		}
+		if r.Pattern == nil || (r.Key != "" && key.Value != r.Key) {
+			return
+		}
+		for _, match := range r.Pattern.FindAllString(value.Value, -1) {
+			if r.Once {
+				scope := job + "\x00" + step
+				if reported[scope] {
+					return
+				}
+				reported[scope] = true
+			}
+			report(Finding{Node: value, Text: match, Job: job, Step: step, Message: message})
+		}
+	})
+}
+
+// PatternRuleConfig is a pattern rule in a lint configuration
+type PatternRuleConfig struct {
+	ID          string `yaml:"id"`
+	Severity    string `yaml:"severity"`
+	Description string `yaml:"description"`
+	Fix         string `yaml:"fix"`
+	Doc         string `yaml:"doc"`
+	Message     string `yaml:"message"`
+	Uses        string `yaml:"uses"`
+	Key         string `yaml:"key"`
+	Pattern     string `yaml:"pattern"`
+	Once        bool   `yaml:"once"`
+}
+
+// rule builds the configured rule; severity defaults to warning
+func (c PatternRuleConfig) rule() (*PatternRule, error) {
+	if c.ID == "" {
+		return nil, fmt.Errorf("custom rule without id")
+	}
+	if (c.Uses == "") == (c.Pattern == "") {
+		return nil, fmt.Errorf("custom rule %s: set either uses or pattern", c.ID)
+	}
+	if c.Description == "" && c.Message == "" {
+		return nil, fmt.Errorf("custom rule %s: description is required", c.ID)
+	}
+
+	rule := &PatternRule{
+		RuleInfo: RuleInfo{
+			ID:          c.ID,
+			Severity:    c.Severity,
+			Description: c.Description,
+			Fix:         c.Fix,
+			DocURL:      c.Doc,
+		},
+		Message: c.Message,
+		Uses:    c.Uses,
+		Key:     c.Key,
+		Once:    c.Once,
+	}
+	if rule.Severity == "" {
+		rule.Severity = SeverityWarning
+	}
+	if rule.Description == "" {
+		rule.Description = c.Message
+	}
+	if c.Pattern != "" {
+		pattern, err := regexp.Compile(c.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("custom rule %s: invalid pattern: %w", c.ID, err)
+		}
+		rule.Pattern = pattern
+	}
+	return rule, nil
+}
+
+// builtinInfo describes a built-in rule, linking to its documentation
+func builtinInfo(id, severity, description, fix string) RuleInfo {
+	return RuleInfo{
+		ID:          id,
+		Severity:    severity,
+		Description: description,
+		Fix:         fix,
+		DocURL:      rulesDocURL + "#" + strings.ToLower(id),
+	}
+}
+
+// builtinRules returns the rules for act's limitations. IDs are stable; a
+// new rule gets the next free ID.
+func builtinRules() []Rule {
+	actionRule := func(id, uses, message, fix string) Rule {
+		return &PatternRule{
+			RuleInfo: builtinInfo(id, SeverityInfo, fmt.Sprintf("%s* actions: %s", uses, message), fix),
+			Message:  message,
+			Uses:     uses,
+		}
+	}
+	expressionRule := func(id, severity, pattern, message, fix string) Rule {
+		return &PatternRule{
+			RuleInfo: builtinInfo(id, severity, message, fix),
+			Pattern:  regexp.MustCompile(pattern),
+		}
+	}
+
+	return []Rule{
+		NewRule(builtinInfo(RuleSyntax, SeverityError, "Workflow is not valid YAML", "Fix the syntax error at the reported line"), nil),
+
+		actionRule("AB001", "actions/cache@", "Caching not supported locally",
+			"Consider using volume mounts for caching dependencies locally"),
+		actionRule("AB002", "actions/upload-artifact@", "Artifacts saved to workspace only",
+			"Artifacts will be saved to the workspace output directory"),
+		actionRule("AB003", "actions/download-artifact@", "Cross-workflow artifacts not supported",
+			"Artifacts will be saved to the workspace output directory"),
+		actionRule("AB004", "docker/build-push-action@", "Registry push disabled locally",
+			"Ensure Docker daemon is running and accessible"),
+		actionRule("AB005", "actions/create-release@", "GitHub releases not supported locally",
+			"Skip release steps locally, e.g. with if: github.actor != 'confighub'"),
+		actionRule("AB006", "actions/upload-release-asset@", "Release assets not supported locally",
+			"Skip release steps locally, e.g. with if: github.actor != 'confighub'"),
+		actionRule("AB007", "peter-evans/create-pull-request@", "Pull requests not supported locally",
+			"Skip pull request steps locally, e.g. with if: github.actor != 'confighub'"),
+		actionRule("AB008", "github/super-linter@", "May timeout locally due to resource constraints",
+			"Raise the unit's timeout or lint only changed files"),
+
+		expressionRule("AB009", SeverityWarning, `\$\{\{\s*secrets\.GITHUB_TOKEN\s*\}\}`,
+			"GITHUB_TOKEN will be simulated locally", "Pass a real token as a secret if steps call GitHub"),
+		expressionRule("AB010", SeverityInfo, `\$\{\{\s*github\.event\.pull_request\.\w+\s*\}\}`,
+			"Pull request events are simulated locally", "Set the fields the workflow reads in the event payload"),
+		expressionRule("AB011", SeverityInfo, `\$\{\{\s*github\.repository_owner\s*\}\}`,
+			"Repository owner will be 'nektos' locally", "Pass the owner as an input or variable"),
+		&PatternRule{
+			RuleInfo: builtinInfo("AB012", SeverityWarning, "Scheduled workflows must be triggered manually locally",
+				"Run the unit with the schedule event to take the scheduled path"),
+			Key:     "if",
+			Pattern: regexp.MustCompile(`github\.event_name\s*==\s*'schedule'`),
+		},
+
+		NewRule(builtinInfo("AB013", SeverityWarning, "Self-hosted runners not supported, will use docker",
+			"Map the runner labels to an image with the runner-images annotation"), checkSelfHosted),
+		NewRule(builtinInfo("AB014", SeverityInfo, "Service containers require Docker networking configuration",
+			"Make sure job containers can reach the services' network"), checkServices),
+		NewRule(builtinInfo("AB015", SeverityInfo, "Matrix builds may impact performance locally",
+			"Run a single combination with the matrix target parameter"), checkMatrix),
+		NewRule(builtinInfo("AB016", SeverityInfo, "Long-running workflows may timeout on local resources",
+			"Raise the unit's timeout annotation to match"), checkLongTimeout),
+		NewRule(builtinInfo("AB017", SeverityInfo, "Network operations may fail locally without internet access",
+			"Handle failures with || true or || exit"), checkNetworkCommands),
+
+		&PatternRule{
+			RuleInfo: builtinInfo("AB018", SeverityWarning, "Hardcoded runner paths may not work locally",
+				"Use ${{ github.workspace }} or $HOME instead"),
+			Pattern: regexp.MustCompile(regexp.QuoteMeta("/home/runner/")),
+			Once:    true,
+		},
+		&PatternRule{
+			RuleInfo: builtinInfo("AB019", SeverityWarning, "GitHub API calls require authentication and may be rate-limited",
+				"Pass a token as a secret and handle rate limiting"),
+			Pattern: regexp.MustCompile(regexp.QuoteMeta("api.github.com")),
+			Once:    true,
+		},
+
+		NewRule(builtinInfo("AB020", SeverityInfo, "Concurrency groups are emulated per unit within the bridge worker",
+			"Nothing to do unless several workers run the same unit"), checkConcurrencyEmulated),
+		NewRule(builtinInfo("AB021", SeverityWarning, "Concurrency group is only known while the workflow runs and is not emulated",
+			"Build the group from github, inputs and vars only"), checkConcurrencyRuntime),
+	}
+}
+
+// checkSelfHosted reports self-hosted runner labels, given as a label, a list
+// of labels or a runner group with labels
+func checkSelfHosted(workflow *LintWorkflow, report func(Finding)) {
+	for _, job := range workflow.Jobs() {
+		_, node := mappingValue(job.Node, "runs-on")
+		if node == nil {
+			continue
+		}
+		if _, labels := mappingValue(node, "labels"); labels != nil {
+			node = labels
+		}
+		labels := []*yaml.Node{node}
+		if node.Kind == yaml.SequenceNode {
+			labels = node.Content
+		}
+		for _, label := range labels {
+			if label = resolveNode(label); label.Kind == yaml.ScalarNode && label.Value == "self-hosted" {
+				report(Finding{Node: label, Job: job.ID, Message: "Self-hosted runners not supported, will use docker"})
+				break
+			}
+		}
+	}
+}
+
+// checkServices reports jobs with service containers
+func checkServices(workflow *LintWorkflow, report func(Finding)) {
+	for _, job := range workflow.Jobs() {
+		if key, _ := mappingValue(job.Node, "services"); key != nil {
+			report(Finding{Node: key, Job: job.ID, Message: "Service containers require Docker networking configuration"})
+		}
+	}
+}
+
+// checkMatrix reports matrix strategies
+func checkMatrix(workflow *LintWorkflow, report func(Finding)) {
+	for _, job := range workflow.Jobs() {
+		_, strategy := mappingValue(job.Node, "strategy")
+		if key, _ := mappingValue(strategy, "matrix"); key != nil {
+			report(Finding{Node: key, Job: job.ID, Message: "Matrix builds may impact performance locally"})
+		}
+	}
+}
+
+// checkLongTimeout reports job and step timeouts above an hour. Expressions
+// are left alone.
+func checkLongTimeout(workflow *LintWorkflow, report func(Finding)) {
+	check := func(node *yaml.Node, job, step string) {
+		_, value := mappingValue(node, "timeout-minutes")
+		if value == nil || value.Kind != yaml.ScalarNode {
+			return
+		}
+		if minutes, err := strconv.ParseFloat(value.Value, 64); err == nil && minutes > 60 {
+			report(Finding{Node: value, Job: job, Step: step, Message: "Long-running workflows may timeout on local resources"})
+		}
+	}
+	for _, job := range workflow.Jobs() {
+		check(job.Node, job.ID, "")
+	}
+	for _, step := range workflow.Steps() {
+		check(step.Node, step.Job, step.Name)
+	}
+}
+
+// checkNetworkCommands reports scripts that download without handling
+// failures
+func checkNetworkCommands(workflow *LintWorkflow, report func(Finding)) {
+	for _, step := range workflow.Steps() {
+		_, run := mappingValue(step.Node, "run")
+		if run == nil || run.Kind != yaml.ScalarNode {
+			continue
+		}
+		if strings.Contains(run.Value, "|| true") || strings.Contains(run.Value, "|| exit") {
+			continue
+		}
+		for _, command := range []string{"curl ", "wget "} {
+			if strings.Contains(run.Value, command) {
+				report(Finding{Node: run, Text: command, Job: step.Job, Step: step.Name,
+					Message: "Network operations may fail locally without internet access"})
+				break
+			}
+		}
+	}
+}
+
+// concurrencyNodes calls fn with the workflow and job concurrency settings
+func concurrencyNodes(workflow *LintWorkflow, fn func(key, value *yaml.Node, job string)) {
+	if key, value := mappingValue(workflow.Root, "concurrency"); key != nil {
+		fn(key, value, "")
+	}
+	for _, job := range workflow.Jobs() {
+		if key, value := mappingValue(job.Node, "concurrency"); key != nil {
+			fn(key, value, job.ID)
+		}
+	}
+}
+
+// checkConcurrencyEmulated notes the concurrency groups the worker emulates
+func checkConcurrencyEmulated(workflow *LintWorkflow, report func(Finding)) {
+	concurrencyNodes(workflow, func(key, value *yaml.Node, job string) {
+		if len(runtimeContextsIn(value)) == 0 {
+			report(Finding{Node: key, Job: job, Message: "Concurrency groups are emulated per unit within the bridge worker"})
+		}
+	})
+}
+
+// checkConcurrencyRuntime reports concurrency groups built from contexts that
+// are only known while the workflow runs
+func checkConcurrencyRuntime(workflow *LintWorkflow, report func(Finding)) {
+	concurrencyNodes(workflow, func(key, value *yaml.Node, job string) {
+		if used := runtimeContextsIn(value); len(used) > 0 {
+			report(Finding{Node: key, Job: job, Message: fmt.Sprintf(
+				"Concurrency group uses %s, which is only known while the workflow runs; it is not emulated locally", strings.Join(used, ", "))})
+		}
+	})
+}
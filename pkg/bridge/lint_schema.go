@@ -0,0 +1,215 @@
+package bridge
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// schemaRules returns the rules checking the workflow syntax and the inputs
+// passed to local actions and reusable workflows
+func schemaRules() []Rule {
+	return []Rule{
+		NewRule(builtinInfo("AB030", SeverityError, "Workflow does not match the workflow syntax",
+			"Fix the key or value at the reported path"), checkWorkflowSchema),
+		NewRule(builtinInfo("AB031", SeverityWarning, "Local action's action.yml is invalid or its inputs do not match",
+			"Fix the action.yml or pass the inputs the action declares"), checkActionInputs),
+		NewRule(builtinInfo("AB032", SeverityError, "Inputs or secrets do not match the reusable workflow",
+			"Pass the inputs and secrets the workflow_call trigger declares"), checkReusableWorkflowInputs),
+	}
+}
+
+// checkWorkflowSchema reports keys and values that do not match the schema
+// of the workflow syntax, with their path
+func checkWorkflowSchema(workflow *LintWorkflow, report func(Finding)) {
+	for _, err := range workflowSchema.validate(workflow.Root) {
+		finding := Finding{Node: err.Node, Message: err.Error()}
+		if rest, ok := strings.CutPrefix(err.Path, "jobs."); ok {
+			finding.Job, _, _ = strings.Cut(rest, ".")
+			finding.Job, _, _ = strings.Cut(finding.Job, "[")
+		}
+		report(finding)
+	}
+}
+
+// localPath returns the repository path of a local uses reference such as
+// ./.github/actions/setup
+func localPath(uses string) (string, bool) {
+	if !strings.HasPrefix(uses, "./") {
+		return "", false
+	}
+	return path.Clean(strings.TrimPrefix(uses, "./")), true
+}
+
+// declaredInput is an input of an action or reusable workflow
+type declaredInput struct {
+	Required bool
+	Default  bool // Has a default
+}
+
+// declaredInputs reads the inputs of a mapping of input definitions by
+// lowercase name
+func declaredInputs(node *yaml.Node) map[string]declaredInput {
+	inputs := make(map[string]declaredInput)
+	eachPair(node, func(key, value *yaml.Node) {
+		var input declaredInput
+		if _, required := mappingValue(value, "required"); required != nil {
+			input.Required = required.Value == "true"
+		}
+		if def, _ := mappingValue(value, "default"); def != nil {
+			input.Default = true
+		}
+		inputs[strings.ToLower(key.Value)] = input
+	})
+	return inputs
+}
+
+// checkPassed reports passed values that are not declared, and required
+// declared values that are not passed. The report of a missing value is made
+// at the uses reference.
+func checkPassed(kind, target string, declared map[string]declaredInput, passed, uses *yaml.Node, report func(node *yaml.Node, message string)) {
+	given := make(map[string]bool)
+	eachPair(passed, func(key, _ *yaml.Node) {
+		name := strings.ToLower(key.Value)
+		given[name] = true
+		if _, ok := declared[name]; !ok {
+			report(key, fmt.Sprintf("%s has no %s '%s'", target, kind, key.Value))
+		}
+	})
+	for _, name := range sortedInputNames(declared) {
+		if input := declared[name]; input.Required && !input.Default && !given[name] {
+			report(uses, fmt.Sprintf("%s requires %s '%s'", target, kind, name))
+		}
+	}
+}
+
+// sortedInputNames returns the names of declared inputs, sorted
+func sortedInputNames(inputs map[string]declaredInput) []string {
+	names := make(map[string]bool, len(inputs))
+	for name := range inputs {
+		names[name] = true
+	}
+	return sortedKeys(names)
+}
+
+// checkActionInputs checks the action.yml of local actions against act's
+// schema, and the with inputs of the steps using them against its inputs.
+// Nothing is checked when the workflow's files cannot be read.
+func checkActionInputs(workflow *LintWorkflow, report func(Finding)) {
+	if workflow.ReadFile == nil {
+		return
+	}
+	for _, step := range workflow.Steps() {
+		_, uses := mappingValue(step.Node, "uses")
+		if uses == nil {
+			continue
+		}
+		dir, ok := localPath(uses.Value)
+		if !ok {
+			continue
+		}
+		stepReport := func(node *yaml.Node, message string) {
+			report(Finding{Node: node, Job: step.Job, Step: step.Name, Action: uses.Value, Message: message})
+		}
+
+		var data []byte
+		var err error
+		for _, name := range []string{"action.yml", "action.yaml"} {
+			if data, err = workflow.ReadFile(path.Join(dir, name)); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			stepReport(uses, fmt.Sprintf("Action %s not found: no action.yml or action.yaml", uses.Value))
+			continue
+		}
+		root, err := parseWorkflowNode(data)
+		if err != nil || root == nil {
+			stepReport(uses, fmt.Sprintf("Action %s has an invalid action.yml: %v", uses.Value, err))
+			continue
+		}
+		for _, err := range actionSchema.validate(root) {
+			stepReport(uses, fmt.Sprintf("Action %s has an invalid action.yml: %v", uses.Value, err))
+		}
+
+		_, inputs := mappingValue(root, "inputs")
+		declared := declaredInputs(inputs)
+		// Docker actions take their arguments and entrypoint through with
+		_, runs := mappingValue(root, "runs")
+		if _, using := mappingValue(runs, "using"); using != nil && using.Value == "docker" {
+			declared["args"] = declaredInput{}
+			declared["entrypoint"] = declaredInput{}
+		}
+		_, with := mappingValue(step.Node, "with")
+		checkPassed("input", "Action "+uses.Value, declared, with, uses, stepReport)
+	}
+}
+
+// checkReusableWorkflowInputs checks the with inputs and secrets of jobs
+// calling local reusable workflows against their workflow_call trigger
+func checkReusableWorkflowInputs(workflow *LintWorkflow, report func(Finding)) {
+	if workflow.ReadFile == nil {
+		return
+	}
+	for _, job := range workflow.Jobs() {
+		_, uses := mappingValue(job.Node, "uses")
+		if uses == nil {
+			continue
+		}
+		name, ok := localPath(uses.Value)
+		if !ok {
+			continue
+		}
+		jobReport := func(node *yaml.Node, message string) {
+			report(Finding{Node: node, Job: job.ID, Message: message})
+		}
+
+		data, err := workflow.ReadFile(name)
+		if err != nil {
+			jobReport(uses, fmt.Sprintf("Reusable workflow %s not found", uses.Value))
+			continue
+		}
+		root, err := parseWorkflowNode(data)
+		if err != nil || root == nil {
+			jobReport(uses, fmt.Sprintf("Reusable workflow %s does not parse: %v", uses.Value, err))
+			continue
+		}
+		_, on := mappingValue(root, "on")
+		call, trigger := mappingValue(on, "workflow_call")
+		if call == nil && !triggersOn(on, "workflow_call") {
+			jobReport(uses, fmt.Sprintf("Workflow %s has no workflow_call trigger and cannot be called", uses.Value))
+			continue
+		}
+
+		target := "Workflow " + uses.Value
+		_, inputs := mappingValue(trigger, "inputs")
+		_, with := mappingValue(job.Node, "with")
+		checkPassed("input", target, declaredInputs(inputs), with, uses, jobReport)
+
+		_, secrets := mappingValue(job.Node, "secrets")
+		if secrets != nil && secrets.Kind == yaml.ScalarNode && secrets.Value == "inherit" {
+			continue
+		}
+		_, declared := mappingValue(trigger, "secrets")
+		checkPassed("secret", target, declaredInputs(declared), secrets, uses, jobReport)
+	}
+}
+
+// triggersOn reports whether an on setting given as an event or a list of
+// events includes an event
+func triggersOn(on *yaml.Node, event string) bool {
+	if on == nil {
+		return false
+	}
+	if on.Kind == yaml.ScalarNode {
+		return on.Value == event
+	}
+	for _, item := range on.Content {
+		if item = resolveNode(item); item.Kind == yaml.ScalarNode && item.Value == event {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+package bridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nektos/act/pkg/model"
+)
+
+// LiveStateVersion identifies the schema of the LiveState document
+const LiveStateVersion = "actions.confighub.com/v1alpha1"
+
+// Conclusions reported for jobs and steps
+const (
+	ConclusionSuccess   = "success"
+	ConclusionFailure   = "failure"
+	ConclusionSkipped   = "skipped"
+	ConclusionCancelled = "cancelled"
+)
+
+// LiveState is the document published to ConfigHub after an execution
+type LiveState struct {
+	Version      string              `json:"version"`
+	ExecutionID  string              `json:"execution_id"`
+	Status       string              `json:"status"`
+	StartedAt    time.Time           `json:"started_at"`
+	CompletedAt  time.Time           `json:"completed_at"`
+	Duration     string              `json:"duration"`
+	ExitCode     int                 `json:"exit_code"`
+	DryRun       bool                `json:"dry_run,omitempty"`
+	Plan         *ExecutionPlan      `json:"plan,omitempty"`
+	Runtime      *RuntimeInfo        `json:"runtime,omitempty"`
+	Jobs         []JobResult         `json:"jobs"`
+	Outputs      map[string]string   `json:"outputs,omitempty"` // Workflow outputs declared under on.workflow_call
+	Artifacts    []string            `json:"artifacts"`
+	Logs         []string            `json:"logs"`
+	LogEntries   []LogEntry          `json:"log_entries,omitempty"`
+	Drift        *WorkflowDiff       `json:"drift,omitempty"`        // Set by Refresh: changes since this execution
+	Verification *VerificationResult `json:"verification,omitempty"` // Set by Refresh: outcome of the verification job
+	Destroyed    *DestroySummary     `json:"destroyed,omitempty"`    // Set by Destroy: what was removed
+	Archive      *ArchiveResult      `json:"archive,omitempty"`      // Set by Finalize: where the execution was archived
+}
+
+// JobResult describes one run of a job; matrix jobs have one result per combination
+type JobResult struct {
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Matrix      map[string]interface{} `json:"matrix,omitempty"`
+	Conclusion  string                 `json:"conclusion"`
+	StartedAt   *time.Time             `json:"started_at,omitempty"`
+	CompletedAt *time.Time             `json:"completed_at,omitempty"`
+	Outputs     map[string]string      `json:"outputs,omitempty"`
+	Steps       []StepResult           `json:"steps"`
+}
+
+// StepResult describes one step of a job run
+type StepResult struct {
+	ID          string            `json:"id,omitempty"`
+	Name        string            `json:"name"`
+	Conclusion  string            `json:"conclusion"`
+	StartedAt   *time.Time        `json:"started_at,omitempty"`
+	CompletedAt *time.Time        `json:"completed_at,omitempty"`
+	Outputs     map[string]string `json:"outputs,omitempty"`
+}
+
+// NewLiveState builds the LiveState document for an execution result
+func NewLiveState(result *ExecutionResult) *LiveState {
+	jobs := result.Jobs
+	if jobs == nil {
+		jobs = []JobResult{}
+	}
+
+	return &LiveState{
+		Version:     LiveStateVersion,
+		ExecutionID: result.ID,
+		Status:      result.Status,
+		StartedAt:   result.StartTime,
+		CompletedAt: result.EndTime,
+		Duration:    result.Duration.String(),
+		ExitCode:    result.ExitCode,
+		DryRun:      result.Plan != nil,
+		Plan:        result.Plan,
+		Runtime:     result.Runtime,
+		Jobs:        jobs,
+		Outputs:     result.Outputs,
+		Artifacts:   result.Artifacts,
+		Logs:        result.Logs,
+		LogEntries:  result.LogEntries,
+	}
+}
+
+// recordLiveState returns the LiveState stored with an execution record
+func recordLiveState(record *ExecutionRecord) (*LiveState, error) {
+	state := &LiveState{
+		Version:     LiveStateVersion,
+		ExecutionID: record.ID,
+		Status:      record.Status,
+	}
+	if len(record.OutputData) > 0 {
+		if err := json.Unmarshal(record.OutputData, state); err != nil {
+			return nil, fmt.Errorf("decode live state of execution %s: %w", record.ID, err)
+		}
+	}
+	return state, nil
+}
+
+// JSON encodes the LiveState document
+func (ls *LiveState) JSON() ([]byte, error) {
+	return json.Marshal(ls)
+}
+
+// buildJobResults combines the plan with what the collector saw at run time.
+// Planned jobs and steps that never reported a result were either skipped or,
+// if the execution was stopped, cancelled. Outputs are evaluated per run from
+// the outputs the jobs declared before the plan ran.
+func buildJobResults(plan *model.Plan, collector *logCollector, declared map[string]map[string]string, stopped bool) []JobResult {
+	notRun := ConclusionSkipped
+	if stopped {
+		notRun = ConclusionCancelled
+	}
+
+	var results []JobResult
+	for _, stage := range plan.Stages {
+		for _, run := range stage.Runs {
+			job := run.Job()
+			if job == nil {
+				continue
+			}
+
+			records := collector.jobRuns(run.JobID)
+			if len(records) == 0 {
+				results = append(results, JobResult{
+					ID:         run.JobID,
+					Name:       run.String(),
+					Conclusion: notRun,
+					Steps:      plannedSteps(job, nil, notRun),
+				})
+				continue
+			}
+
+			for _, record := range records {
+				conclusion := record.result
+				if conclusion == "" || (stopped && conclusion == ConclusionFailure) {
+					conclusion = notRun
+				}
+				var outputs map[string]string
+				if record.result != "" {
+					outputs = jobRunOutputs(declared[run.JobID], record, job.Outputs, len(records))
+				}
+				results = append(results, JobResult{
+					ID:          run.JobID,
+					Name:        record.name,
+					Matrix:      record.matrix,
+					Conclusion:  conclusion,
+					StartedAt:   timePtr(record.startedAt),
+					CompletedAt: timePtr(record.completedAt),
+					Outputs:     outputs,
+					Steps:       plannedSteps(job, record, notRun),
+				})
+			}
+		}
+	}
+
+	return results
+}
+
+// plannedSteps lists the steps of a job in workflow order with their results
+func plannedSteps(job *model.Job, record *jobRecord, notRun string) []StepResult {
+	steps := make([]StepResult, 0, len(job.Steps))
+	for i, step := range job.Steps {
+		if step == nil {
+			continue
+		}
+
+		// act numbers steps that have no explicit id
+		id := step.ID
+		if id == "" {
+			id = fmt.Sprintf("%d", i)
+		}
+
+		result := StepResult{
+			ID:         step.ID,
+			Name:       step.String(),
+			Conclusion: notRun,
+		}
+
+		if record != nil {
+			if seen, ok := record.stepIndex[id]; ok {
+				result.Name = seen.name
+				result.StartedAt = timePtr(seen.startedAt)
+				result.CompletedAt = timePtr(seen.completedAt)
+				if seen.result != "" {
+					result.Conclusion = seen.result
+				} else {
+					// Started but never finished
+					result.Conclusion = ConclusionCancelled
+				}
+				if len(seen.outputs) > 0 {
+					result.Outputs = seen.outputs
+				}
+			}
+		}
+
+		steps = append(steps, result)
+	}
+	return steps
+}
+
+// timePtr returns nil for the zero time
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,226 @@
+package bridge
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/nektos/act/pkg/model"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const resultsWorkflow = `name: release
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make
+      - id: lint
+        name: Lint
+        run: make lint
+  deploy:
+    needs: build
+    runs-on: ubuntu-latest
+    steps:
+      - run: make deploy
+`
+
+// planWorkflow plans every job of a workflow the way act does
+func planWorkflow(t *testing.T, workflow string) *model.Plan {
+	t.Helper()
+	planner, err := model.NewSingleWorkflowPlanner("pipeline.yml", strings.NewReader(workflow))
+	require.NoError(t, err)
+	plan, err := planner.PlanAll()
+	require.NoError(t, err)
+	return plan
+}
+
+// jobLog returns the logger act gives a job run, with the fields it attaches
+func jobLog(collector *logCollector, jobID, name string, matrix map[string]interface{}) *logrus.Entry {
+	return collector.WithJobLogger().WithFields(logrus.Fields{"job": name, "jobID": jobID, "matrix": matrix})
+}
+
+// stepLog returns the logger act gives a step of a job run
+func stepLog(job *logrus.Entry, stepID, name string) *logrus.Entry {
+	return job.WithFields(logrus.Fields{"step": name, "stepID": []string{stepID}, "stage": "Main"})
+}
+
+// conclusions maps job and step IDs to their conclusions
+func conclusions(jobs []JobResult) map[string]string {
+	result := make(map[string]string)
+	for _, job := range jobs {
+		result[job.Name] = job.Conclusion
+		for _, step := range job.Steps {
+			result[job.Name+"/"+step.Name] = step.Conclusion
+		}
+	}
+	return result
+}
+
+func TestBuildJobResults(t *testing.T) {
+	tests := []struct {
+		name    string
+		run     func(collector *logCollector)
+		stopped bool
+		want    map[string]string
+	}{
+		{
+			name: "all jobs succeed",
+			run: func(collector *logCollector) {
+				build := jobLog(collector, "build", "build", nil)
+				stepLog(build, "0", "make").WithField("stepResult", "success").Info("Success - Main make")
+				stepLog(build, "lint", "Lint").WithField("stepResult", "success").Info("Success - Main Lint")
+				build.WithField("jobResult", "success").Info("Job succeeded")
+				deploy := jobLog(collector, "deploy", "deploy", nil)
+				stepLog(deploy, "0", "make deploy").WithField("stepResult", "success").Info("Success - Main make deploy")
+				deploy.WithField("jobResult", "success").Info("Job succeeded")
+			},
+			want: map[string]string{
+				"build": "success", "build/make": "success", "build/Lint": "success",
+				"deploy": "success", "deploy/make deploy": "success",
+			},
+		},
+		{
+			name: "failed step skips the rest",
+			run: func(collector *logCollector) {
+				build := jobLog(collector, "build", "build", nil)
+				stepLog(build, "0", "make").WithField("stepResult", "failure").Error("Failure - Main make")
+				build.WithField("jobResult", "failure").Info("Job failed")
+			},
+			want: map[string]string{
+				"build": "failure", "build/make": "failure", "build/Lint": "skipped",
+				"deploy": "skipped", "deploy/make deploy": "skipped",
+			},
+		},
+		{
+			name:    "stopped run cancels what did not finish",
+			stopped: true,
+			run: func(collector *logCollector) {
+				build := jobLog(collector, "build", "build", nil)
+				stepLog(build, "0", "make").WithField("stepResult", "success").Info("Success - Main make")
+				stepLog(build, "lint", "Lint").WithField("raw_output", true).Info("linting")
+				build.WithField("jobResult", "failure").Info("Job failed")
+			},
+			want: map[string]string{
+				"build": "cancelled", "build/make": "success", "build/Lint": "cancelled",
+				"deploy": "cancelled", "deploy/make deploy": "cancelled",
+			},
+		},
+		{
+			name: "pre and post stages do not decide",
+			run: func(collector *logCollector) {
+				build := jobLog(collector, "build", "build", nil)
+				step := stepLog(build, "0", "make")
+				step.WithFields(logrus.Fields{"stage": "Pre", "stepResult": "failure"}).Info("Failure - Pre make")
+				step.WithField("stepResult", "success").Info("Success - Main make")
+				step.WithFields(logrus.Fields{"stage": "Post", "stepResult": "failure"}).Info("Failure - Post make")
+				build.WithField("jobResult", "failure").Info("Job failed")
+			},
+			want: map[string]string{
+				"build": "failure", "build/make": "success", "build/Lint": "skipped",
+				"deploy": "skipped", "deploy/make deploy": "skipped",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := newLogCollector()
+			tt.run(collector)
+			plan := planWorkflow(t, resultsWorkflow)
+			jobs := buildJobResults(plan, collector, declaredJobOutputs(plan), tt.stopped)
+			assert.Equal(t, tt.want, conclusions(jobs))
+		})
+	}
+}
+
+func TestBuildJobResultsMatrix(t *testing.T) {
+	workflow := `on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    strategy:
+      matrix:
+        node: [18, 20]
+    steps:
+      - run: npm test
+`
+	collector := newLogCollector()
+	for _, run := range []struct {
+		name   string
+		node   int
+		result string
+	}{{"test (18)", 18, "failure"}, {"test (20)", 20, "success"}} {
+		job := jobLog(collector, "test", run.name, map[string]interface{}{"node": run.node})
+		stepLog(job, "0", "npm test").WithField("stepResult", run.result).Info("done")
+		job.WithField("jobResult", run.result).Info("done")
+	}
+
+	plan := planWorkflow(t, workflow)
+	jobs := buildJobResults(plan, collector, declaredJobOutputs(plan), false)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, "test", jobs[0].ID)
+	assert.Equal(t, map[string]interface{}{"node": 18}, jobs[0].Matrix)
+	assert.Equal(t, map[string]string{
+		"test (18)": "failure", "test (18)/npm test": "failure",
+		"test (20)": "success", "test (20)/npm test": "success",
+	}, conclusions(jobs))
+	require.NotNil(t, jobs[1].StartedAt)
+
+	// The LiveState document carries every run
+	state, err := NewLiveState(&ExecutionResult{ID: "exec-1", Status: ExecutionStatusFailure, Jobs: jobs}).JSON()
+	require.NoError(t, err)
+	var decoded LiveState
+	require.NoError(t, json.Unmarshal(state, &decoded))
+	assert.Equal(t, LiveStateVersion, decoded.Version)
+	require.Len(t, decoded.Jobs, 2)
+	assert.Equal(t, "test (20)", decoded.Jobs[1].Name)
+}
+
+func TestBuildJobResultsMatrixOutputs(t *testing.T) {
+	workflow := `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    strategy:
+      matrix:
+        os: [linux, windows]
+    outputs:
+      artifact: ${{ steps.package.outputs.file }}
+      label: ${{ matrix.os }}-${{ steps.package.outputs.size }}
+      commit: ${{ github.sha }}
+      fixed: release
+    steps:
+      - id: package
+        run: ./package.sh
+`
+	plan := planWorkflow(t, workflow)
+	declared := declaredJobOutputs(plan)
+
+	collector := newLogCollector()
+	for _, os := range []string{"linux", "windows"} {
+		job := jobLog(collector, "build", "build ("+os+")", map[string]interface{}{"os": os})
+		step := stepLog(job, "package", "./package.sh")
+		step.WithFields(logrus.Fields{"command": "set-output", "name": "file", "arg": "app-" + os + ".tgz"}).Info("::set-output:: file")
+		step.WithFields(logrus.Fields{"command": "set-output", "name": "size", "arg": "10"}).Info("::set-output:: size")
+		step.WithField("stepResult", "success").Info("Success - Main ./package.sh")
+		job.WithField("jobResult", "success").Info("Job succeeded")
+	}
+
+	// act interpolates the shared outputs once, with the values of the first run
+	job := plan.Stages[0].Runs[0].Job()
+	job.Outputs = map[string]string{"artifact": "app-linux.tgz", "label": "linux-10", "commit": "abc123", "fixed": "release"}
+
+	jobs := buildJobResults(plan, collector, declared, false)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, map[string]string{"artifact": "app-linux.tgz", "label": "linux-10", "fixed": "release"}, jobs[0].Outputs)
+	assert.Equal(t, map[string]string{"artifact": "app-windows.tgz", "label": "windows-10", "fixed": "release"}, jobs[1].Outputs)
+	assert.Equal(t, map[string]string{"file": "app-windows.tgz", "size": "10"}, jobs[1].Steps[0].Outputs)
+
+	// A job that ran once takes act's value for what the bridge cannot evaluate
+	record := &jobRecord{stepIndex: map[string]*stepRecord{"package": {outputs: map[string]string{"file": "app.tgz"}}}}
+	outputs := jobRunOutputs(declared["build"], record, job.Outputs, 1)
+	assert.Equal(t, map[string]string{"artifact": "app.tgz", "label": "-", "commit": "abc123", "fixed": "release"}, outputs)
+}
@@ -0,0 +1,246 @@
+package bridge
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nektos/act/pkg/runner"
+	"github.com/sirupsen/logrus"
+)
+
+// Log streams recorded in LogEntry.Stream
+const (
+	LogStreamOutput = "output" // Output of a step's commands
+	LogStreamRunner = "runner" // Messages from act itself
+	LogStreamError  = "error"  // Warnings and errors reported by act
+)
+
+// maxLogEntries caps the number of entries kept per execution
+const maxLogEntries = 10000
+
+// LogEntry is a single line of workflow output captured from act
+type LogEntry struct {
+	Time    time.Time `json:"time"`
+	Job     string    `json:"job,omitempty"`
+	Step    string    `json:"step,omitempty"`
+	Stream  string    `json:"stream"`
+	Message string    `json:"message"`
+}
+
+// String formats the entry as a plain log line
+func (e LogEntry) String() string {
+	source := e.Job
+	if e.Step != "" {
+		source = fmt.Sprintf("%s/%s", e.Job, e.Step)
+	}
+	if source == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("[%s] %s", source, e.Message)
+}
+
+// logCollector hooks into act's job loggers and records every entry,
+// along with the job and step results act reports through log fields.
+// It implements runner.JobLoggerFactory and logrus.Hook.
+type logCollector struct {
+	mu       sync.Mutex
+	entries  []LogEntry
+	dropped  int
+	jobs     []*jobRecord
+	jobIndex map[string]*jobRecord // keyed by job name, unique per matrix combination
+}
+
+// jobRecord tracks one job run as seen through its logger
+type jobRecord struct {
+	id          string
+	name        string
+	matrix      map[string]interface{}
+	result      string
+	startedAt   time.Time
+	completedAt time.Time
+	steps       []*stepRecord
+	stepIndex   map[string]*stepRecord
+}
+
+// stepRecord tracks one step of a job run
+type stepRecord struct {
+	id          string
+	name        string
+	result      string
+	startedAt   time.Time
+	completedAt time.Time
+	outputs     map[string]string
+}
+
+// newLogCollector creates an empty log collector
+func newLogCollector() *logCollector {
+	return &logCollector{
+		jobIndex: make(map[string]*jobRecord),
+	}
+}
+
+// WithJobLogger returns the logger act uses for a job
+func (lc *logCollector) WithJobLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.SetLevel(logrus.InfoLevel)
+	logger.AddHook(lc)
+	return logger
+}
+
+// Levels returns the log levels the collector records
+func (lc *logCollector) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// masked replaces the values a job registered with ::add-mask:: in text.
+// Hooks fire before act's masking formatter, so entries arrive unmasked;
+// secrets are redacted later by the LeakDetector.
+func masked(entry *logrus.Entry, text string) string {
+	if entry.Context == nil {
+		return text
+	}
+	for _, value := range *runner.Masks(entry.Context) {
+		if value != "" {
+			text = strings.ReplaceAll(text, value, "***")
+		}
+	}
+	return text
+}
+
+// Fire records a log entry emitted by act
+func (lc *logCollector) Fire(entry *logrus.Entry) error {
+	message := masked(entry, strings.TrimRight(entry.Message, "\r\n"))
+	if message == "" {
+		return nil
+	}
+
+	e := LogEntry{
+		Time:    entry.Time,
+		Stream:  LogStreamRunner,
+		Message: message,
+	}
+	if job, ok := entry.Data["job"].(string); ok {
+		e.Job = job
+	}
+	if step, ok := entry.Data["step"].(string); ok {
+		e.Step = step
+	}
+	if raw, ok := entry.Data["raw_output"].(bool); ok && raw {
+		e.Stream = LogStreamOutput
+	} else if entry.Level <= logrus.WarnLevel {
+		e.Stream = LogStreamError
+	}
+
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	lc.track(entry, e)
+
+	if len(lc.entries) >= maxLogEntries {
+		lc.dropped++
+		return nil
+	}
+	lc.entries = append(lc.entries, e)
+	return nil
+}
+
+// track updates job and step records from the fields act attaches to an entry.
+// The caller must hold lc.mu.
+func (lc *logCollector) track(entry *logrus.Entry, e LogEntry) {
+	if e.Job == "" {
+		return
+	}
+
+	job, ok := lc.jobIndex[e.Job]
+	if !ok {
+		job = &jobRecord{
+			name:      e.Job,
+			startedAt: e.Time,
+			stepIndex: make(map[string]*stepRecord),
+		}
+		if id, ok := entry.Data["jobID"].(string); ok {
+			job.id = id
+		}
+		if matrix, ok := entry.Data["matrix"].(map[string]interface{}); ok && len(matrix) > 0 {
+			job.matrix = matrix
+		}
+		lc.jobIndex[e.Job] = job
+		lc.jobs = append(lc.jobs, job)
+	}
+	job.completedAt = e.Time
+
+	if result, ok := entry.Data["jobResult"]; ok {
+		job.result = fmt.Sprint(result)
+	}
+
+	if e.Step == "" {
+		return
+	}
+
+	stepID := e.Step
+	if ids, ok := entry.Data["stepID"].([]string); ok && len(ids) > 0 {
+		stepID = ids[0]
+	}
+	step, ok := job.stepIndex[stepID]
+	if !ok {
+		step = &stepRecord{
+			id:        stepID,
+			name:      e.Step,
+			startedAt: e.Time,
+			outputs:   make(map[string]string),
+		}
+		job.stepIndex[stepID] = step
+		job.steps = append(job.steps, step)
+	}
+	step.completedAt = e.Time
+
+	// Pre and post stages report their own results; the main stage decides
+	if result, ok := entry.Data["stepResult"]; ok {
+		if stage, _ := entry.Data["stage"].(string); stage == "" || stage == "Main" {
+			step.result = fmt.Sprint(result)
+		}
+	}
+
+	if command, _ := entry.Data["command"].(string); command == "set-output" {
+		name, _ := entry.Data["name"].(string)
+		value, _ := entry.Data["arg"].(string)
+		if name != "" {
+			step.outputs[name] = masked(entry, value)
+		}
+	}
+}
+
+// jobRuns returns the records of all runs of a job, one per matrix combination
+func (lc *logCollector) jobRuns(jobID string) []*jobRecord {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	var runs []*jobRecord
+	for _, job := range lc.jobs {
+		if job.id == jobID {
+			runs = append(runs, job)
+		}
+	}
+	return runs
+}
+
+// Entries returns the collected entries, noting any that were dropped
+func (lc *logCollector) Entries() []LogEntry {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	entries := make([]LogEntry, len(lc.entries), len(lc.entries)+1)
+	copy(entries, lc.entries)
+	if lc.dropped > 0 {
+		entries = append(entries, LogEntry{
+			Time:    time.Now(),
+			Stream:  LogStreamRunner,
+			Message: fmt.Sprintf("... %d log lines dropped (limit %d)", lc.dropped, maxLogEntries),
+		})
+	}
+	return entries
+}
@@ -0,0 +1,44 @@
+package bridge
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nektos/act/pkg/runner"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogCollectorMasks(t *testing.T) {
+	collector := newLogCollector()
+	masks := []string{}
+	ctx := runner.WithMasks(context.Background(), &masks)
+	logger := collector.WithJobLogger().WithFields(logrus.Fields{"job": "build", "jobID": "build"}).WithContext(ctx)
+	step := logger.WithFields(logrus.Fields{"step": "login", "stepID": []string{"login"}})
+
+	step.WithField("raw_output", true).Info("token is abc123\n")
+
+	// ::add-mask::abc123 registers the value with the job's run context
+	masks = append(masks, "abc123")
+	step.WithField("raw_output", true).Info("token is abc123\n")
+	step.WithFields(logrus.Fields{"command": "set-output", "name": "token", "arg": "abc123"}).Info("::set-output:: token=abc123")
+	step.WithField("raw_output", true).Info("\n")
+
+	entries := collector.Entries()
+	require.Len(t, entries, 3)
+	assert.Equal(t, "token is abc123", entries[0].Message, "lines before the mask are kept")
+	assert.Equal(t, "token is ***", entries[1].Message)
+	assert.Equal(t, "::set-output:: token=***", entries[2].Message)
+	assert.Equal(t, LogStreamOutput, entries[1].Stream)
+	assert.Equal(t, "[build/login] token is ***", entries[1].String())
+
+	runs := collector.jobRuns("build")
+	require.Len(t, runs, 1)
+	require.Len(t, runs[0].steps, 1)
+	assert.Equal(t, map[string]string{"token": "***"}, runs[0].steps[0].outputs)
+
+	// Entries without a run context are recorded as they are
+	collector.WithJobLogger().WithField("job", "build").Info("no context")
+	assert.Equal(t, "no context", collector.Entries()[3].Message)
+}
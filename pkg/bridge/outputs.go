@@ -0,0 +1,133 @@
+package bridge
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/nektos/act/pkg/model"
+	"gopkg.in/yaml.v3"
+)
+
+// jobOutputRefPattern matches ${{ jobs.<id>.outputs.<name> }} expressions
+var jobOutputRefPattern = regexp.MustCompile(`\$\{\{\s*jobs\.([A-Za-z0-9_-]+)\.outputs\.([A-Za-z0-9_-]+)\s*\}\}`)
+
+// stepOutputRefPattern matches ${{ steps.<id>.outputs.<name> }} expressions
+var stepOutputRefPattern = regexp.MustCompile(`\$\{\{\s*steps\.([A-Za-z0-9_-]+)\.outputs\.([A-Za-z0-9_-]+)\s*\}\}`)
+
+// matrixRefPattern matches ${{ matrix.<key> }} expressions
+var matrixRefPattern = regexp.MustCompile(`\$\{\{\s*matrix\.([A-Za-z0-9_-]+)\s*\}\}`)
+
+// declaredJobOutputs returns the outputs of the planned jobs as written.
+// act replaces a job's outputs with their values when a run of the job
+// completes, and all runs of a matrix job share them, so they must be taken
+// before the plan runs.
+func declaredJobOutputs(plan *model.Plan) map[string]map[string]string {
+	declared := make(map[string]map[string]string)
+	for _, stage := range plan.Stages {
+		for _, run := range stage.Runs {
+			job := run.Job()
+			if job == nil || len(job.Outputs) == 0 {
+				continue
+			}
+			outputs := make(map[string]string, len(job.Outputs))
+			for name, value := range job.Outputs {
+				outputs[name] = value
+			}
+			declared[run.JobID] = outputs
+		}
+	}
+	return declared
+}
+
+// jobRunOutputs evaluates the outputs of one job run from the outputs its
+// steps set and its matrix combination. Outputs that refer to anything else
+// take the value act evaluated if the job ran once, and are skipped for
+// matrix jobs, since act keeps the values of only one of their runs.
+func jobRunOutputs(declared map[string]string, record *jobRecord, evaluated map[string]string, runs int) map[string]string {
+	outputs := make(map[string]string)
+	for name, expr := range declared {
+		value := stepOutputRefPattern.ReplaceAllStringFunc(expr, func(ref string) string {
+			match := stepOutputRefPattern.FindStringSubmatch(ref)
+			if step, ok := record.stepIndex[match[1]]; ok {
+				return step.outputs[match[2]]
+			}
+			return ""
+		})
+		value = matrixRefPattern.ReplaceAllStringFunc(value, func(ref string) string {
+			match := matrixRefPattern.FindStringSubmatch(ref)
+			if value, ok := record.matrix[match[1]]; ok {
+				return fmt.Sprint(value)
+			}
+			return ""
+		})
+
+		if strings.Contains(value, "${{") {
+			if runs != 1 {
+				continue
+			}
+			var ok bool
+			if value, ok = evaluated[name]; !ok || strings.Contains(value, "${{") {
+				continue
+			}
+		}
+		outputs[name] = value
+	}
+	if len(outputs) == 0 {
+		return nil
+	}
+	return outputs
+}
+
+// workflowOutputs evaluates the outputs a workflow declares under
+// on.workflow_call.outputs. Values may only reference job outputs; outputs
+// that reference anything else, or jobs that produced nothing, are skipped.
+func workflowOutputs(workflowPath string, jobs []JobResult) (map[string]string, error) {
+	data, err := os.ReadFile(workflowPath)
+	if err != nil {
+		return nil, fmt.Errorf("read workflow: %w", err)
+	}
+
+	var workflow struct {
+		On struct {
+			WorkflowCall struct {
+				Outputs map[string]struct {
+					Value string `yaml:"value"`
+				} `yaml:"outputs"`
+			} `yaml:"workflow_call"`
+		} `yaml:"on"`
+	}
+	// Triggers given as a string or list declare no outputs
+	if err := yaml.Unmarshal(data, &workflow); err != nil || len(workflow.On.WorkflowCall.Outputs) == 0 {
+		return nil, nil
+	}
+
+	// Later runs of a matrix job win, as on GitHub
+	jobOutputs := make(map[string]map[string]string)
+	for _, job := range jobs {
+		if len(job.Outputs) > 0 {
+			jobOutputs[job.ID] = job.Outputs
+		}
+	}
+
+	outputs := make(map[string]string)
+	for name, output := range workflow.On.WorkflowCall.Outputs {
+		resolved := true
+		value := jobOutputRefPattern.ReplaceAllStringFunc(output.Value, func(ref string) string {
+			match := jobOutputRefPattern.FindStringSubmatch(ref)
+			value, ok := jobOutputs[match[1]][match[2]]
+			if !ok {
+				resolved = false
+			}
+			return value
+		})
+		if resolved && !strings.Contains(value, "${{") {
+			outputs[name] = value
+		}
+	}
+	if len(outputs) == 0 {
+		return nil, nil
+	}
+	return outputs, nil
+}
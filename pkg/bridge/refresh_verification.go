@@ -0,0 +1,110 @@
+package bridge
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AnnotationRefreshJob names the jobs Refresh runs to verify a unit's live
+// state, comma-separated. The jobs are left out of Apply runs.
+const AnnotationRefreshJob = "actions.confighub.com/refresh-job"
+
+// EventConfigHubRefresh is the pseudo-event of verification runs. A workflow
+// with this trigger is run by Refresh instead of Apply.
+const EventConfigHubRefresh = "confighub-refresh"
+
+// EnvReadOnly is set to "true" in verification runs. Steps must only read
+// the live state; the run is not recorded in the execution history.
+const EnvReadOnly = "CONFIGHUB_READ_ONLY"
+
+// EventFieldReadOnly is set to true in the event payload of verification
+// runs, so workflows can check github.event.read_only in their conditions
+const EventFieldReadOnly = "read_only"
+
+// VerificationResult is the outcome of a verification run, published in the
+// Refresh LiveState
+type VerificationResult struct {
+	ExecutionID string            `json:"execution_id"`
+	Workflow    string            `json:"workflow"`
+	Event       string            `json:"event"`
+	Jobs        []string          `json:"jobs,omitempty"`
+	Status      string            `json:"status"`
+	Outputs     map[string]string `json:"outputs"`
+	Mismatches  []OutputMismatch  `json:"mismatches"`
+}
+
+// OutputMismatch is an output whose observed value differs from the last Apply
+type OutputMismatch struct {
+	Name     string `json:"name"`
+	Applied  string `json:"applied"`
+	Observed string `json:"observed"`
+}
+
+// Drifted reports whether the observed outputs differ from the last Apply
+func (vr *VerificationResult) Drifted() bool {
+	return len(vr.Mismatches) > 0
+}
+
+// Summary describes the mismatches in one line
+func (vr *VerificationResult) Summary() string {
+	var parts []string
+	for _, mismatch := range vr.Mismatches {
+		parts = append(parts, fmt.Sprintf("output %s is %q, last apply produced %q", mismatch.Name, mismatch.Observed, mismatch.Applied))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// appliedOutputs flattens the outputs of an execution by name. Workflow
+// outputs win over job outputs, and later jobs over earlier ones.
+func appliedOutputs(record *ExecutionRecord) map[string]string {
+	outputs := make(map[string]string)
+	for _, job := range record.Jobs {
+		for name, value := range job.Outputs {
+			outputs[name] = value
+		}
+	}
+	for name, value := range record.Outputs {
+		outputs[name] = value
+	}
+	return outputs
+}
+
+// observedOutputs flattens the outputs of the verification jobs by name
+func observedOutputs(result *ExecutionResult, jobs []string) map[string]string {
+	selected := make(map[string]bool, len(jobs))
+	for _, job := range jobs {
+		selected[job] = true
+	}
+
+	outputs := make(map[string]string)
+	for _, job := range result.Jobs {
+		// Jobs pulled in through needs are not verification jobs
+		if len(selected) > 0 && !selected[job.ID] {
+			continue
+		}
+		for name, value := range job.Outputs {
+			outputs[name] = value
+		}
+	}
+	for name, value := range result.Outputs {
+		outputs[name] = value
+	}
+	return outputs
+}
+
+// compareOutputs returns the observed outputs whose value differs from the
+// applied one. Outputs the last Apply did not produce are not compared.
+func compareOutputs(applied, observed map[string]string) []OutputMismatch {
+	mismatches := []OutputMismatch{}
+	for name, value := range observed {
+		expected, ok := applied[name]
+		if ok && expected != value {
+			mismatches = append(mismatches, OutputMismatch{Name: name, Applied: expected, Observed: value})
+		}
+	}
+	sort.Slice(mismatches, func(i, j int) bool {
+		return mismatches[i].Name < mismatches[j].Name
+	})
+	return mismatches
+}
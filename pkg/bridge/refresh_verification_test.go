@@ -0,0 +1,66 @@
+package bridge
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareOutputs(t *testing.T) {
+	applied := appliedOutputs(&ExecutionRecord{
+		Jobs: []JobResult{
+			{ID: "build", Outputs: map[string]string{"image": "app:1", "release": "1.3"}},
+			{ID: "deploy", Outputs: map[string]string{"release": "1.4"}},
+		},
+		Outputs: map[string]string{"url": "https://app.example.com"},
+	})
+	assert.Equal(t, map[string]string{"image": "app:1", "release": "1.4", "url": "https://app.example.com"}, applied)
+
+	// Jobs pulled in through needs do not count as observations
+	observed := observedOutputs(&ExecutionResult{
+		Jobs: []JobResult{
+			{ID: "setup", Outputs: map[string]string{"image": "app:2"}},
+			{ID: "verify", Outputs: map[string]string{"release": "1.2", "replicas": "3"}},
+		},
+		Outputs: map[string]string{"url": "https://app.example.com"},
+	}, []string{"verify"})
+	assert.Equal(t, map[string]string{"release": "1.2", "replicas": "3", "url": "https://app.example.com"}, observed)
+
+	result := &VerificationResult{Mismatches: compareOutputs(applied, observed)}
+	assert.True(t, result.Drifted())
+	assert.Equal(t, []OutputMismatch{{Name: "release", Applied: "1.4", Observed: "1.2"}}, result.Mismatches)
+	assert.Equal(t, `output release is "1.2", last apply produced "1.4"`, result.Summary())
+
+	// Matching outputs are no drift
+	result = &VerificationResult{Mismatches: compareOutputs(applied, map[string]string{"release": "1.4"})}
+	assert.False(t, result.Drifted())
+	assert.Empty(t, result.Summary())
+}
+
+func TestReadOnlyRun(t *testing.T) {
+	execCtx := &ExecutionContext{
+		Workspace:   &Workspace{Root: t.TempDir()},
+		Environment: map[string]string{"STAGE": "prod"},
+		Metadata:    ExecutionMetadata{Space: "platform", Unit: "web", Revision: 3},
+		EventPayload: map[string]interface{}{
+			EventFieldReadOnly: false,
+		},
+	}
+	runner := &ActRunner{}
+
+	// Apply runs are not flagged
+	assert.Equal(t, map[string]string{"STAGE": "prod"}, executionEnv(execCtx))
+	_, event, err := runner.prepareEvent(execCtx, EventConfigHubRefresh, "")
+	require.NoError(t, err)
+	assert.Equal(t, false, event[EventFieldReadOnly])
+
+	// Verification runs are, in the environment and the event payload
+	execCtx.ReadOnly = true
+	assert.Equal(t, map[string]string{"STAGE": "prod", EnvReadOnly: "true"}, executionEnv(execCtx))
+	assert.Equal(t, map[string]string{"STAGE": "prod"}, execCtx.Environment)
+	_, event, err = runner.prepareEvent(execCtx, EventConfigHubRefresh, "")
+	require.NoError(t, err)
+	assert.Equal(t, true, event[EventFieldReadOnly])
+	assert.Equal(t, "refresh", event["action"])
+}
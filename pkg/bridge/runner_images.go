@@ -0,0 +1,235 @@
+package bridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/nektos/act/pkg/model"
+	"gopkg.in/yaml.v3"
+)
+
+// AnnotationRunnerImages maps runs-on labels to images for a unit, as a YAML
+// or JSON object or as "label=image,label=image"
+const AnnotationRunnerImages = "actions.confighub.com/runner-images"
+
+// RunnerImages maps runs-on labels to container images. Keys may use
+// wildcards such as "ubuntu-*"; "*" matches any label.
+type RunnerImages map[string]string
+
+// DefaultRunnerImages maps every ubuntu label to the given image
+func DefaultRunnerImages(image string) RunnerImages {
+	return RunnerImages{"ubuntu-*": image}
+}
+
+// LoadRunnerImages reads a mapping table from a YAML or JSON file. The table
+// may be the whole document or sit under a runner_images key.
+func LoadRunnerImages(filePath string) (RunnerImages, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("read runner images: %w", err)
+	}
+
+	var file struct {
+		RunnerImages RunnerImages `yaml:"runner_images"`
+	}
+	if err := yaml.Unmarshal(data, &file); err == nil && len(file.RunnerImages) > 0 {
+		return file.RunnerImages.validate()
+	}
+
+	var images RunnerImages
+	if err := yaml.Unmarshal(data, &images); err != nil {
+		return nil, fmt.Errorf("parse runner images %s: %w", filePath, err)
+	}
+	return images.validate()
+}
+
+// ParseRunnerImages parses a mapping given as a YAML or JSON object or as
+// comma-separated label=image pairs
+func ParseRunnerImages(value string) (RunnerImages, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return RunnerImages{}, nil
+	}
+
+	var images RunnerImages
+	if strings.HasPrefix(value, "{") || strings.Contains(value, ":\n") || strings.Contains(value, ": ") {
+		if err := yaml.Unmarshal([]byte(value), &images); err != nil {
+			return nil, fmt.Errorf("parse runner images: %w", err)
+		}
+		return images.validate()
+	}
+
+	images = RunnerImages{}
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid runner image %q (expected label=image)", pair)
+		}
+		images[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return images.validate()
+}
+
+// runnerImagesParam accepts the runner_images target param as an object or a string
+func runnerImagesParam(value interface{}) (RunnerImages, error) {
+	switch v := value.(type) {
+	case string:
+		return ParseRunnerImages(v)
+	case map[string]interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		var images RunnerImages
+		if err := json.Unmarshal(data, &images); err != nil {
+			return nil, fmt.Errorf("runner images must map labels to image names: %w", err)
+		}
+		return images.validate()
+	default:
+		return nil, fmt.Errorf("invalid runner images type %T", value)
+	}
+}
+
+// validate checks labels, patterns and images and lowercases the labels
+func (ri RunnerImages) validate() (RunnerImages, error) {
+	images := make(RunnerImages, len(ri))
+	for label, image := range ri {
+		label = strings.ToLower(strings.TrimSpace(label))
+		if label == "" {
+			return nil, fmt.Errorf("empty runner label")
+		}
+		if _, err := path.Match(label, ""); err != nil {
+			return nil, fmt.Errorf("invalid runner label pattern %q: %w", label, err)
+		}
+		if strings.TrimSpace(image) == "" {
+			return nil, fmt.Errorf("no image for runner label %q", label)
+		}
+		images[label] = strings.TrimSpace(image)
+	}
+	return images, nil
+}
+
+// Merge returns a copy of the mapping with other's entries taking precedence
+func (ri RunnerImages) Merge(other RunnerImages) RunnerImages {
+	merged := make(RunnerImages, len(ri)+len(other))
+	for label, image := range ri {
+		merged[label] = image
+	}
+	for label, image := range other {
+		merged[label] = image
+	}
+	return merged
+}
+
+// Resolve returns the image for a label. Exact entries win over patterns,
+// and longer patterns win over shorter ones.
+func (ri RunnerImages) Resolve(label string) (string, bool) {
+	label = strings.ToLower(label)
+	if image, ok := ri[label]; ok {
+		return image, true
+	}
+
+	var patterns []string
+	for pattern := range ri {
+		if strings.ContainsAny(pattern, "*?[") {
+			patterns = append(patterns, pattern)
+		}
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, label); matched {
+			return ri[pattern], true
+		}
+	}
+	return "", false
+}
+
+// resolvePlatforms builds act's label to image table for the jobs in a plan.
+// Like act, the first label of a job with an image decides where it runs.
+// Labels that depend on a matrix are resolved for every combination.
+func resolvePlatforms(plan *model.Plan, images RunnerImages, matrix map[string]map[string]bool) (map[string]string, error) {
+	platforms := make(map[string]string)
+
+	for _, stage := range plan.Stages {
+		for _, run := range stage.Runs {
+			job := run.Job()
+			if job == nil || job.Uses != "" {
+				// Reusable workflow calls run their own jobs
+				continue
+			}
+
+			for _, labels := range runnerLabelSets(job, matrix) {
+				if len(labels) == 0 {
+					continue
+				}
+				mapped := false
+				for _, label := range labels {
+					if strings.Contains(label, "${{") {
+						// Depends on something only known at run time
+						mapped = true
+						continue
+					}
+					if image, ok := images.Resolve(label); ok {
+						platforms[strings.ToLower(label)] = image
+						mapped = true
+						break
+					}
+				}
+				if !mapped {
+					return nil, fmt.Errorf("no runner image mapped for runs-on %s of job %s; map it with the runner_images target param or the %s annotation",
+						strings.Join(labels, ", "), run.JobID, AnnotationRunnerImages)
+				}
+			}
+		}
+	}
+
+	return platforms, nil
+}
+
+// runnerLabelSets returns the runs-on labels of a job, once per matrix
+// combination when the labels reference the matrix
+func runnerLabelSets(job *model.Job, matrix map[string]map[string]bool) [][]string {
+	labels := job.RunsOn()
+
+	usesMatrix := false
+	for _, label := range labels {
+		if matrixRefPattern.MatchString(label) {
+			usesMatrix = true
+			break
+		}
+	}
+	if !usesMatrix {
+		return [][]string{labels}
+	}
+
+	combinations, err := job.GetMatrixes()
+	if err != nil {
+		return [][]string{labels}
+	}
+
+	var sets [][]string
+	for _, combination := range filterMatrixes(combinations, matrix) {
+		set := make([]string, len(labels))
+		for i, label := range labels {
+			set[i] = matrixRefPattern.ReplaceAllStringFunc(label, func(ref string) string {
+				key := matrixRefPattern.FindStringSubmatch(ref)[1]
+				if value, ok := combination[key]; ok {
+					return fmt.Sprint(value)
+				}
+				return ref
+			})
+		}
+		sets = append(sets, set)
+	}
+	return sets
+}
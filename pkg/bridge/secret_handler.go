@@ -50,6 +50,15 @@ func NewLeakDetector() *LeakDetector {
 	}
 }
 
+// secretDetector creates a leak detector tracking the secrets of a run
+func secretDetector(secrets map[string]string) *LeakDetector {
+	detector := NewLeakDetector()
+	for name, value := range secrets {
+		detector.Track(name, value)
+	}
+	return detector
+}
+
 // PrepareSecrets prepares secrets for workflow execution
 func (sh *SecretHandler) PrepareSecrets(workspace *Workspace, secrets map[string]string) (string, error) {
 	sh.mu.Lock()
@@ -81,7 +90,7 @@ func (sh *SecretHandler) PrepareSecrets(workspace *Workspace, secrets map[string
 
 	// Also write encrypted secrets file for secure storage
 	encryptedPath := filepath.Join(workspace.SecretDir, ".secrets.enc")
-	encryptedSecrets, err := sh.EncryptSecrets(secrets)
+	encryptedSecrets, err := sh.encryptSecrets(secrets)
 	if err != nil {
 		return "", fmt.Errorf("encrypt secrets: %w", err)
 	}
@@ -106,6 +115,11 @@ func (sh *SecretHandler) EncryptSecrets(secrets map[string]string) (map[string]s
 	sh.mu.RLock()
 	defer sh.mu.RUnlock()
 
+	return sh.encryptSecrets(secrets)
+}
+
+// encryptSecrets encrypts secrets; the caller must hold sh.mu
+func (sh *SecretHandler) encryptSecrets(secrets map[string]string) (map[string]string, error) {
 	encrypted := make(map[string]string)
 
 	for key, value := range secrets {
@@ -148,6 +162,11 @@ func (sh *SecretHandler) SanitizeLogs(logs []string) []string {
 	return sh.leakDetector.SanitizeLogs(logs)
 }
 
+// SanitizeEntries removes secrets from structured log entries
+func (sh *SecretHandler) SanitizeEntries(entries []LogEntry) []LogEntry {
+	return sh.leakDetector.SanitizeEntries(entries)
+}
+
 // validateSecret validates a secret key and value
 func (sh *SecretHandler) validateSecret(key, value string) error {
 	// Key validation
@@ -239,6 +258,43 @@ func (ld *LeakDetector) SanitizeLogs(logs []string) []string {
 	return sanitized
 }
 
+// SanitizeString removes tracked secrets from a single string
+func (ld *LeakDetector) SanitizeString(s string) string {
+	ld.mu.RLock()
+	defer ld.mu.RUnlock()
+
+	return ld.sanitizeLine(s)
+}
+
+// SanitizeEntries removes tracked secrets from structured log entries
+func (ld *LeakDetector) SanitizeEntries(entries []LogEntry) []LogEntry {
+	ld.mu.RLock()
+	defer ld.mu.RUnlock()
+
+	sanitized := make([]LogEntry, len(entries))
+
+	for i, entry := range entries {
+		entry.Message = ld.sanitizeLine(entry.Message)
+		entry.Step = ld.sanitizeLine(entry.Step)
+		sanitized[i] = entry
+	}
+
+	return sanitized
+}
+
+// SanitizeMap removes tracked secrets from the values of a map in place
+// and returns it
+func (ld *LeakDetector) SanitizeMap(values map[string]string) map[string]string {
+	ld.mu.RLock()
+	defer ld.mu.RUnlock()
+
+	for k, v := range values {
+		values[k] = ld.sanitizeLine(v)
+	}
+
+	return values
+}
+
 // sanitizeLine sanitizes a single log line
 func (ld *LeakDetector) sanitizeLine(line string) string {
 	// Replace all tracked patterns
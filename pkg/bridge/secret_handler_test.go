@@ -0,0 +1,53 @@
+package bridge
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrepareSecrets(t *testing.T) {
+	handler, err := NewSecretHandler()
+	require.NoError(t, err)
+	workspace := &Workspace{SecretDir: t.TempDir()}
+	secrets := map[string]string{"API_TOKEN": "s3cr3t-value"}
+
+	// PrepareSecrets used to wait forever for the read lock EncryptSecrets
+	// takes while it holds the write lock itself
+	type prepared struct {
+		path string
+		err  error
+	}
+	done := make(chan prepared, 1)
+	go func() {
+		path, err := handler.PrepareSecrets(workspace, secrets)
+		done <- prepared{path, err}
+	}()
+	var result prepared
+	select {
+	case result = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("PrepareSecrets did not return")
+	}
+	require.NoError(t, result.err)
+
+	written, err := ParseSecretsFile(result.path)
+	require.NoError(t, err)
+	assert.Equal(t, secrets, written)
+
+	data, err := os.ReadFile(filepath.Join(workspace.SecretDir, ".secrets.enc"))
+	require.NoError(t, err)
+	var encrypted map[string]string
+	require.NoError(t, json.Unmarshal(data, &encrypted))
+	assert.NotEqual(t, secrets["API_TOKEN"], encrypted["API_TOKEN"])
+	decrypted, err := handler.DecryptSecrets(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, secrets, decrypted)
+
+	assert.Equal(t, []string{"token ***API_TOKEN***"}, handler.SanitizeLogs([]string{"token s3cr3t-value"}))
+}
@@ -0,0 +1,113 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/confighub/sdk/bridge-worker/api"
+)
+
+// unitLocks keeps Destroy from releasing a unit's resources while another
+// operation of the unit uses them. Apply and Refresh share a unit's lock,
+// Destroy holds it alone. A waiting Destroy stops new holders from joining,
+// so a stream of Applies cannot hold it off forever.
+type unitLocks struct {
+	mu    sync.Mutex
+	units map[string]*unitLock
+}
+
+// unitLock is the state of one unit's lock
+type unitLock struct {
+	shared    int
+	exclusive bool
+	waiting   int           // Exclusive holders waiting
+	changed   chan struct{} // Closed and replaced whenever the lock is released
+}
+
+// unitKey names the lock of a payload's unit. Unit slugs are only unique
+// within a space.
+func unitKey(payload api.BridgeWorkerPayload) string {
+	return payload.SpaceID.String() + "/" + payload.UnitSlug
+}
+
+// newUnitLocks creates a set of unheld locks
+func newUnitLocks() *unitLocks {
+	return &unitLocks{units: make(map[string]*unitLock)}
+}
+
+// acquire waits until the unit's lock is free for a shared or exclusive
+// holder and returns the function releasing it. It gives up when ctx is done.
+func (ul *unitLocks) acquire(ctx context.Context, unit string, exclusive bool) (func(), error) {
+	ul.mu.Lock()
+	if exclusive {
+		ul.lock(unit).waiting++
+	}
+	for {
+		// A lock nobody used was forgotten while waiting
+		lock := ul.lock(unit)
+		free := !lock.exclusive && lock.waiting == 0
+		if exclusive {
+			free = !lock.exclusive && lock.shared == 0
+		}
+		if free {
+			if exclusive {
+				lock.waiting--
+				lock.exclusive = true
+			} else {
+				lock.shared++
+			}
+			ul.mu.Unlock()
+			return func() { ul.release(unit, exclusive) }, nil
+		}
+
+		changed := lock.changed
+		ul.mu.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			ul.mu.Lock()
+			if exclusive {
+				lock.waiting--
+			}
+			ul.notify(unit, lock)
+			ul.mu.Unlock()
+			return nil, fmt.Errorf("wait for unit %s: %w", unit, context.Cause(ctx))
+		}
+		ul.mu.Lock()
+	}
+}
+
+// lock returns the state of a unit's lock. The caller must hold ul.mu.
+func (ul *unitLocks) lock(unit string) *unitLock {
+	lock := ul.units[unit]
+	if lock == nil {
+		lock = &unitLock{changed: make(chan struct{})}
+		ul.units[unit] = lock
+	}
+	return lock
+}
+
+// release gives up a holder's share of the unit's lock
+func (ul *unitLocks) release(unit string, exclusive bool) {
+	ul.mu.Lock()
+	defer ul.mu.Unlock()
+
+	lock := ul.units[unit]
+	if exclusive {
+		lock.exclusive = false
+	} else {
+		lock.shared--
+	}
+	ul.notify(unit, lock)
+}
+
+// notify wakes the waiters of a lock and forgets it once nobody uses it.
+// The caller must hold ul.mu.
+func (ul *unitLocks) notify(unit string, lock *unitLock) {
+	close(lock.changed)
+	lock.changed = make(chan struct{})
+	if lock.shared == 0 && !lock.exclusive && lock.waiting == 0 && ul.units[unit] == lock {
+		delete(ul.units, unit)
+	}
+}
@@ -0,0 +1,66 @@
+package bridge
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitLocks(t *testing.T) {
+	locks := newUnitLocks()
+	ctx := context.Background()
+
+	// Operations of a unit share its lock
+	releaseApply, err := locks.acquire(ctx, "web", false)
+	require.NoError(t, err)
+	releaseRefresh, err := locks.acquire(ctx, "web", false)
+	require.NoError(t, err)
+
+	// Destroy waits for both and keeps new operations out meanwhile
+	destroyed := make(chan func(), 1)
+	go func() {
+		release, err := locks.acquire(ctx, "web", true)
+		assert.NoError(t, err)
+		destroyed <- release
+	}()
+	require.Eventually(t, func() bool {
+		locks.mu.Lock()
+		defer locks.mu.Unlock()
+		return locks.units["web"].waiting == 1
+	}, time.Second, time.Millisecond)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = locks.acquire(cancelled, "web", false)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Contains(t, err.Error(), "wait for unit web")
+
+	// Other units are not affected
+	releaseOther, err := locks.acquire(ctx, "api", true)
+	require.NoError(t, err)
+	releaseOther()
+
+	releaseApply()
+	select {
+	case <-destroyed:
+		t.Fatal("destroy started while an operation held the unit")
+	default:
+	}
+	releaseRefresh()
+	releaseDestroy := <-destroyed
+
+	// Operations after Destroy wait for it to finish
+	applied := make(chan func(), 1)
+	go func() {
+		release, err := locks.acquire(ctx, "web", false)
+		assert.NoError(t, err)
+		applied <- release
+	}()
+	releaseDestroy()
+	(<-applied)()
+
+	assert.Empty(t, locks.units, "unused locks are forgotten")
+}
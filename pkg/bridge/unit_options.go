@@ -0,0 +1,150 @@
+package bridge
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Annotations that control how a unit runs, next to AnnotationEvent,
+// AnnotationTimeout and AnnotationRunnerImages
+const (
+	// AnnotationJobs names the jobs Apply runs, comma-separated
+	AnnotationJobs = "actions.confighub.com/jobs"
+	// AnnotationKeepWorkspaceOnFailure keeps the workspace of a failed run for inspection
+	AnnotationKeepWorkspaceOnFailure = "actions.confighub.com/keep-workspace-on-failure"
+	// AnnotationAllowedNetwork is the network job containers are attached to
+	AnnotationAllowedNetwork = "actions.confighub.com/allowed-network"
+	// AnnotationRequiredApprovals is the number of trusted approvers who must
+	// sign an Apply or Destroy of the unit
+	AnnotationRequiredApprovals = "actions.confighub.com/required-approvals"
+)
+
+// Values of the allowed-network annotation besides user-defined networks
+const (
+	NetworkNone   = "none"   // No network access
+	NetworkBridge = "bridge" // Docker's default bridge network
+)
+
+// UnitOptions are the execution settings of a unit, read from its
+// annotations. They win over the target params; zero values leave the target
+// params and worker defaults in place.
+type UnitOptions struct {
+	Event                  string
+	Jobs                   []string
+	Timeout                time.Duration
+	RunnerImages           RunnerImages
+	KeepWorkspaceOnFailure bool
+	Network                string
+	RequiredApprovals      int
+}
+
+var (
+	// jobIDPattern matches the job ids GitHub accepts
+	jobIDPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+	// networkNamePattern matches the network names Docker accepts
+	networkNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+)
+
+// ParseUnitOptions reads and validates the execution annotations of a unit
+func ParseUnitOptions(annotations map[string]string) (*UnitOptions, error) {
+	options := &UnitOptions{}
+
+	if value, ok := annotations[AnnotationEvent]; ok {
+		if err := ValidateEvent(value); err != nil {
+			return nil, fmt.Errorf("%s: %w", AnnotationEvent, err)
+		}
+		options.Event = value
+	}
+
+	if value, ok := annotations[AnnotationJobs]; ok {
+		jobs, err := parseStringList(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", AnnotationJobs, err)
+		}
+		if len(jobs) == 0 {
+			return nil, fmt.Errorf("%s must name at least one job", AnnotationJobs)
+		}
+		for _, job := range jobs {
+			if !jobIDPattern.MatchString(job) {
+				return nil, fmt.Errorf("%s: invalid job id %q", AnnotationJobs, job)
+			}
+		}
+		options.Jobs = jobs
+	}
+
+	if value, ok := annotations[AnnotationTimeout]; ok {
+		timeout, err := parseTimeout(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", AnnotationTimeout, err)
+		}
+		options.Timeout = timeout
+	}
+
+	if value, ok := annotations[AnnotationRunnerImages]; ok {
+		images, err := ParseRunnerImages(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", AnnotationRunnerImages, err)
+		}
+		options.RunnerImages = images
+	}
+
+	if value, ok := annotations[AnnotationKeepWorkspaceOnFailure]; ok {
+		keep, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: expected true or false, got %q", AnnotationKeepWorkspaceOnFailure, value)
+		}
+		options.KeepWorkspaceOnFailure = keep
+	}
+
+	if value, ok := annotations[AnnotationAllowedNetwork]; ok {
+		if err := validateNetwork(value); err != nil {
+			return nil, fmt.Errorf("%s: %w", AnnotationAllowedNetwork, err)
+		}
+		options.Network = value
+	}
+
+	if value, ok := annotations[AnnotationRequiredApprovals]; ok {
+		approvals, err := strconv.Atoi(value)
+		if err != nil || approvals < 0 {
+			return nil, fmt.Errorf("%s: expected a non-negative number, got %q", AnnotationRequiredApprovals, value)
+		}
+		options.RequiredApprovals = approvals
+	}
+
+	return options, nil
+}
+
+// Validate checks the options against the files of the unit: selected jobs
+// must exist in the entrypoint
+func (o *UnitOptions) Validate(bundle *Bundle) error {
+	if len(o.Jobs) == 0 {
+		return nil
+	}
+	workflow, err := parseWorkflowDocument(bundle.Files[bundle.Entrypoint])
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", bundle.Entrypoint, err)
+	}
+	defined := asMap(workflow["jobs"])
+	for _, job := range o.Jobs {
+		if _, ok := defined[job]; !ok {
+			return fmt.Errorf("%s: job %s not found in %s", AnnotationJobs, job, bundle.Entrypoint)
+		}
+	}
+	return nil
+}
+
+// validateNetwork accepts none, bridge or the name of a user-defined network.
+// Host networking and joining another container's network are not allowed.
+func validateNetwork(name string) error {
+	switch {
+	case name == "host":
+		return fmt.Errorf("host networking is not allowed")
+	case name == NetworkNone || name == NetworkBridge:
+		return nil
+	case !networkNamePattern.MatchString(name):
+		return fmt.Errorf("invalid network name %q", name)
+	}
+	return nil
+}
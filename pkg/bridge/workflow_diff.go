@@ -0,0 +1,455 @@
+package bridge
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of workflow changes reported by DiffWorkflows
+const (
+	ChangeWorkflow      = "workflow_changed"
+	ChangeTriggerAdded  = "trigger_added"
+	ChangeTriggerRemove = "trigger_removed"
+	ChangeTrigger       = "trigger_changed"
+	ChangeEnvAdded      = "env_added"
+	ChangeEnvRemoved    = "env_removed"
+	ChangeEnv           = "env_changed"
+	ChangeJobAdded      = "job_added"
+	ChangeJobRemoved    = "job_removed"
+	ChangeJob           = "job_changed"
+	ChangeStepAdded     = "step_added"
+	ChangeStepRemoved   = "step_removed"
+	ChangeStep          = "step_changed"
+	ChangeStepMoved     = "step_moved"
+	ChangeActionVersion = "action_version_changed"
+	ChangeFileAdded     = "file_added"
+	ChangeFileRemoved   = "file_removed"
+	ChangeFile          = "file_changed"
+)
+
+// WorkflowDiff is the semantic difference between two versions of a unit.
+// Formatting, comments, key order and the ConfigHub header are ignored.
+type WorkflowDiff struct {
+	Changes []WorkflowChange `json:"changes"`
+}
+
+// WorkflowChange is one difference between two workflow versions
+type WorkflowChange struct {
+	Kind    string `json:"kind"`
+	Path    string `json:"path"`
+	Before  string `json:"before,omitempty"`
+	After   string `json:"after,omitempty"`
+	Message string `json:"message"`
+}
+
+// HasChanges reports whether the versions differ
+func (d *WorkflowDiff) HasChanges() bool {
+	return len(d.Changes) > 0
+}
+
+// Summary describes the changes in one line
+func (d *WorkflowDiff) Summary() string {
+	if !d.HasChanges() {
+		return "no changes"
+	}
+
+	const shown = 5
+	var parts []string
+	for i, change := range d.Changes {
+		if i == shown {
+			parts = append(parts, fmt.Sprintf("and %d more", len(d.Changes)-shown))
+			break
+		}
+		parts = append(parts, change.Message)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// add records a change
+func (d *WorkflowDiff) add(kind, path, before, after, format string, args ...interface{}) {
+	d.Changes = append(d.Changes, WorkflowChange{
+		Kind:    kind,
+		Path:    path,
+		Before:  before,
+		After:   after,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// DiffBundles compares every file of two unit versions; workflows are compared semantically
+func DiffBundles(before, after *Bundle) (*WorkflowDiff, error) {
+	diff := &WorkflowDiff{Changes: []WorkflowChange{}}
+
+	if before.Entrypoint != after.Entrypoint {
+		diff.add(ChangeWorkflow, "entrypoint", before.Entrypoint, after.Entrypoint,
+			"entrypoint changed from %s to %s", before.Entrypoint, after.Entrypoint)
+	}
+
+	// Single-workflow units are not prefixed with their file name
+	single := len(before.Files) == 1 && len(after.Files) == 1 && before.Entrypoint == after.Entrypoint
+
+	for _, name := range unionKeys(before.Files, after.Files) {
+		oldContent, inBefore := before.Files[name]
+		newContent, inAfter := after.Files[name]
+
+		switch {
+		case !inBefore:
+			diff.add(ChangeFileAdded, name, "", "", "file %s added", name)
+		case !inAfter:
+			diff.add(ChangeFileRemoved, name, "", "", "file %s removed", name)
+		case isWorkflowPath(name):
+			prefix := name + ":"
+			if single {
+				prefix = ""
+			}
+			if err := diffWorkflow(diff, prefix, []byte(oldContent), []byte(newContent)); err != nil {
+				return nil, fmt.Errorf("diff %s: %w", name, err)
+			}
+		case oldContent != newContent:
+			diff.add(ChangeFile, name, "", "", "file %s changed", name)
+		}
+	}
+
+	return diff, nil
+}
+
+// DiffWorkflows compares two workflow documents semantically
+func DiffWorkflows(before, after []byte) (*WorkflowDiff, error) {
+	diff := &WorkflowDiff{Changes: []WorkflowChange{}}
+	if err := diffWorkflow(diff, "", before, after); err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// diffWorkflow adds the changes between two workflow documents, with paths prefixed
+func diffWorkflow(diff *WorkflowDiff, prefix string, before, after []byte) error {
+	var oldWorkflow, newWorkflow map[string]interface{}
+	if err := yaml.Unmarshal(before, &oldWorkflow); err != nil {
+		return fmt.Errorf("parse previous workflow: %w", err)
+	}
+	if err := yaml.Unmarshal(after, &newWorkflow); err != nil {
+		return fmt.Errorf("parse workflow: %w", err)
+	}
+
+	for _, key := range unionKeys(oldWorkflow, newWorkflow) {
+		oldValue, newValue := oldWorkflow[key], newWorkflow[key]
+		switch key {
+		case "on":
+			diffTriggers(diff, prefix, oldValue, newValue)
+		case "env":
+			diffEnv(diff, prefix+"env", asMap(oldValue), asMap(newValue))
+		case "jobs":
+			diffJobs(diff, prefix, asMap(oldValue), asMap(newValue))
+		default:
+			if !reflect.DeepEqual(oldValue, newValue) {
+				diff.add(ChangeWorkflow, prefix+key, scalarString(oldValue), scalarString(newValue),
+					"workflow %s changed", key)
+			}
+		}
+	}
+	return nil
+}
+
+// diffTriggers compares the on: sections of two workflows
+func diffTriggers(diff *WorkflowDiff, prefix string, before, after interface{}) {
+	oldTriggers, newTriggers := normalizeTriggers(before), normalizeTriggers(after)
+	for _, event := range unionKeys(oldTriggers, newTriggers) {
+		oldConfig, inBefore := oldTriggers[event]
+		newConfig, inAfter := newTriggers[event]
+		path := prefix + "on." + event
+
+		switch {
+		case !inBefore:
+			diff.add(ChangeTriggerAdded, path, "", "", "trigger %s added", event)
+		case !inAfter:
+			diff.add(ChangeTriggerRemove, path, "", "", "trigger %s removed", event)
+		case !reflect.DeepEqual(oldConfig, newConfig):
+			diff.add(ChangeTrigger, path, "", "", "trigger %s changed", event)
+		}
+	}
+}
+
+// normalizeTriggers turns the string, list and map forms of on: into a map
+func normalizeTriggers(value interface{}) map[string]interface{} {
+	triggers := make(map[string]interface{})
+	switch v := value.(type) {
+	case string:
+		triggers[v] = nil
+	case []interface{}:
+		for _, event := range v {
+			triggers[fmt.Sprint(event)] = nil
+		}
+	case map[string]interface{}:
+		for event, config := range v {
+			triggers[event] = config
+		}
+	}
+	return triggers
+}
+
+// diffEnv compares two env maps
+func diffEnv(diff *WorkflowDiff, path string, before, after map[string]interface{}) {
+	for _, name := range unionKeys(before, after) {
+		oldValue, inBefore := before[name]
+		newValue, inAfter := after[name]
+		varPath := path + "." + name
+
+		switch {
+		case !inBefore:
+			diff.add(ChangeEnvAdded, varPath, "", scalarString(newValue), "env %s added", varPath)
+		case !inAfter:
+			diff.add(ChangeEnvRemoved, varPath, scalarString(oldValue), "", "env %s removed", varPath)
+		case !reflect.DeepEqual(oldValue, newValue):
+			diff.add(ChangeEnv, varPath, scalarString(oldValue), scalarString(newValue), "env %s changed", varPath)
+		}
+	}
+}
+
+// diffJobs compares the jobs of two workflows
+func diffJobs(diff *WorkflowDiff, prefix string, before, after map[string]interface{}) {
+	for _, id := range unionKeys(before, after) {
+		oldJob, inBefore := before[id]
+		newJob, inAfter := after[id]
+		path := prefix + "jobs." + id
+
+		switch {
+		case !inBefore:
+			diff.add(ChangeJobAdded, path, "", "", "job %s added", id)
+			continue
+		case !inAfter:
+			diff.add(ChangeJobRemoved, path, "", "", "job %s removed", id)
+			continue
+		}
+
+		oldFields, newFields := asMap(oldJob), asMap(newJob)
+		var changed []string
+		for _, key := range unionKeys(oldFields, newFields) {
+			oldValue, newValue := oldFields[key], newFields[key]
+			switch key {
+			case "env":
+				diffEnv(diff, path+".env", asMap(oldValue), asMap(newValue))
+			case "steps":
+				diffSteps(diff, path, asList(oldValue), asList(newValue))
+			case "uses":
+				if !reflect.DeepEqual(oldValue, newValue) {
+					diffUses(diff, path, id, scalarString(oldValue), scalarString(newValue))
+				}
+			default:
+				if !reflect.DeepEqual(oldValue, newValue) {
+					changed = append(changed, key)
+				}
+			}
+		}
+		if len(changed) > 0 {
+			diff.add(ChangeJob, path, "", "", "job %s changed %s", id, strings.Join(changed, ", "))
+		}
+	}
+}
+
+// diffSteps compares the steps of a job. Steps are matched by id, name or
+// action, so inserting a step does not report every later step as changed.
+// Matched steps whose order relative to the others changed are reported as
+// moved, with their positions in the job.
+func diffSteps(diff *WorkflowDiff, jobPath string, before, after []interface{}) {
+	oldKeys, oldSteps := indexSteps(before)
+	newKeys, newSteps := indexSteps(after)
+	jobID := jobPath[strings.LastIndex(jobPath, ".")+1:]
+	moved := movedSteps(oldKeys, newKeys, oldSteps, newSteps)
+	oldPositions := make(map[string]int, len(oldKeys))
+	for i, key := range oldKeys {
+		oldPositions[key] = i + 1
+	}
+
+	for _, key := range oldKeys {
+		if _, ok := newSteps[key]; !ok {
+			diff.add(ChangeStepRemoved, fmt.Sprintf("%s.steps[%s]", jobPath, key), "", "", "step %s removed from job %s", key, jobID)
+		}
+	}
+
+	for i, key := range newKeys {
+		path := fmt.Sprintf("%s.steps[%s]", jobPath, key)
+		oldStep, ok := oldSteps[key]
+		if !ok {
+			diff.add(ChangeStepAdded, path, "", "", "step %s added to job %s", key, jobID)
+			continue
+		}
+		if moved[key] {
+			from, to := strconv.Itoa(oldPositions[key]), strconv.Itoa(i+1)
+			diff.add(ChangeStepMoved, path, from, to, "step %s of job %s moved from position %s to %s", key, jobID, from, to)
+		}
+
+		newStep := newSteps[key]
+		var changed []string
+		for _, field := range unionKeys(oldStep, newStep) {
+			oldValue, newValue := oldStep[field], newStep[field]
+			if reflect.DeepEqual(oldValue, newValue) {
+				continue
+			}
+			switch field {
+			case "env":
+				diffEnv(diff, path+".env", asMap(oldValue), asMap(newValue))
+			case "uses":
+				diffUses(diff, path, fmt.Sprintf("step %s of job %s", key, jobID), scalarString(oldValue), scalarString(newValue))
+			default:
+				changed = append(changed, field)
+			}
+		}
+		if len(changed) > 0 {
+			diff.add(ChangeStep, path, "", "", "step %s of job %s changed %s", key, jobID, strings.Join(changed, ", "))
+		}
+	}
+}
+
+// movedSteps returns the steps present in both versions that changed order.
+// The longest run of steps that kept their relative order stays in place, so
+// steps shifted by an insertion or removal are not moved.
+func movedSteps(oldKeys, newKeys []string, oldSteps, newSteps map[string]map[string]interface{}) map[string]bool {
+	var oldOrder, newOrder []string
+	for _, key := range oldKeys {
+		if _, ok := newSteps[key]; ok {
+			oldOrder = append(oldOrder, key)
+		}
+	}
+	for _, key := range newKeys {
+		if _, ok := oldSteps[key]; ok {
+			newOrder = append(newOrder, key)
+		}
+	}
+
+	// Longest common subsequence of the two orders
+	lengths := make([][]int, len(oldOrder)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(newOrder)+1)
+	}
+	for i := len(oldOrder) - 1; i >= 0; i-- {
+		for j := len(newOrder) - 1; j >= 0; j-- {
+			if oldOrder[i] == newOrder[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	moved := make(map[string]bool)
+	for _, key := range newOrder {
+		moved[key] = true
+	}
+	for i, j := 0, 0; i < len(oldOrder) && j < len(newOrder); {
+		switch {
+		case oldOrder[i] == newOrder[j]:
+			delete(moved, oldOrder[i])
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return moved
+}
+
+// diffUses reports a change of a uses: reference, distinguishing version bumps
+func diffUses(diff *WorkflowDiff, path, owner, before, after string) {
+	oldAction, oldRef := splitActionRef(before)
+	newAction, newRef := splitActionRef(after)
+	if oldAction == newAction && oldAction != "" {
+		diff.add(ChangeActionVersion, path+".uses", oldRef, newRef,
+			"%s: %s pinned version changed from %s to %s", owner, newAction, displayRef(oldRef), displayRef(newRef))
+		return
+	}
+	diff.add(ChangeStep, path+".uses", before, after, "%s: uses changed from %s to %s", owner, before, after)
+}
+
+// splitActionRef splits "owner/repo@ref" into action and ref
+func splitActionRef(uses string) (string, string) {
+	if i := strings.LastIndex(uses, "@"); i >= 0 {
+		return uses[:i], uses[i+1:]
+	}
+	return uses, ""
+}
+
+// displayRef renders a possibly empty action ref
+func displayRef(ref string) string {
+	if ref == "" {
+		return "(none)"
+	}
+	return ref
+}
+
+// indexSteps keys the steps of a job by id, name or action, in order
+func indexSteps(steps []interface{}) ([]string, map[string]map[string]interface{}) {
+	keys := make([]string, 0, len(steps))
+	index := make(map[string]map[string]interface{}, len(steps))
+	seen := make(map[string]int)
+
+	for i, raw := range steps {
+		step := asMap(raw)
+		key := fmt.Sprintf("#%d", i+1)
+		if id, ok := step["id"].(string); ok && id != "" {
+			key = id
+		} else if name, ok := step["name"].(string); ok && name != "" {
+			key = name
+		} else if uses, ok := step["uses"].(string); ok && uses != "" {
+			key, _ = splitActionRef(uses)
+		}
+
+		// Repeated keys are told apart by occurrence
+		seen[key]++
+		if seen[key] > 1 {
+			key = fmt.Sprintf("%s#%d", key, seen[key])
+		}
+
+		keys = append(keys, key)
+		index[key] = step
+	}
+	return keys, index
+}
+
+// asMap returns a YAML mapping, or an empty map for anything else
+func asMap(value interface{}) map[string]interface{} {
+	if m, ok := value.(map[string]interface{}); ok {
+		return m
+	}
+	return map[string]interface{}{}
+}
+
+// asList returns a YAML sequence, or nil for anything else
+func asList(value interface{}) []interface{} {
+	list, _ := value.([]interface{})
+	return list
+}
+
+// scalarString renders scalar values; collections are left out of the diff
+func scalarString(value interface{}) string {
+	switch value.(type) {
+	case nil, map[string]interface{}, []interface{}:
+		return ""
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// unionKeys returns the keys of two maps, sorted
+func unionKeys[V any](a, b map[string]V) []string {
+	set := make(map[string]bool, len(a)+len(b))
+	for key := range a {
+		set[key] = true
+	}
+	for key := range b {
+		set[key] = true
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,76 @@
+package bridge
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// parseWorkflowNode parses a workflow into the node tree of its root mapping,
+// which keeps the line and column of every key and value. An empty document
+// returns nil.
+func parseWorkflowNode(data []byte) (*yaml.Node, error) {
+	_, root, err := parseWorkflowTree(data)
+	return root, err
+}
+
+// parseWorkflowTree parses a workflow into its document node, which holds
+// the comments at the top of the file, and its root mapping
+func parseWorkflowTree(data []byte) (*yaml.Node, *yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, err
+	}
+	if len(doc.Content) == 0 {
+		return &doc, nil, nil
+	}
+	root := resolveNode(doc.Content[0])
+	if root.Kind != yaml.MappingNode {
+		return &doc, nil, fmt.Errorf("line %d: workflow must be a mapping", root.Line)
+	}
+	return &doc, root, nil
+}
+
+// resolveNode follows aliases to the node they refer to
+func resolveNode(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+// mappingValue returns the key and value nodes of a key in a mapping, or
+// nils if the node is not a mapping or has no such key
+func mappingValue(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	node = resolveNode(node)
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], resolveNode(node.Content[i+1])
+		}
+	}
+	return nil, nil
+}
+
+// eachPair calls fn with the keys and values of a mapping in order
+func eachPair(node *yaml.Node, fn func(key, value *yaml.Node)) {
+	node = resolveNode(node)
+	if node == nil || node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		fn(node.Content[i], resolveNode(node.Content[i+1]))
+	}
+}
+
+// stepLabel names a step by its name, id or action, or by its position
+func stepLabel(step *yaml.Node, index int) string {
+	for _, key := range []string{"name", "id", "uses"} {
+		if _, value := mappingValue(step, key); value != nil && value.Kind == yaml.ScalarNode && value.Value != "" {
+			return value.Value
+		}
+	}
+	return fmt.Sprintf("#%d", index+1)
+}
@@ -0,0 +1,367 @@
+package bridge
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nektos/act/pkg/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// workflowSchema checks workflows, and actionSchema the action.yml of local
+// actions, against the schemas act validates them with
+var (
+	workflowSchema = &schemaValidator{schema: schema.GetWorkflowSchema(), root: "workflow-root-strict", skip: isLifecycleTrigger}
+	actionSchema   = &schemaValidator{schema: schema.GetActionSchema(), root: "action-root"}
+)
+
+// lifecycleTriggerPattern matches the paths of on and of the events it lists
+var lifecycleTriggerPattern = regexp.MustCompile(`^on(\[[0-9]+\]|\.[^.\[]+)?$`)
+
+// isLifecycleTrigger reports the pseudo-events of lifecycle jobs in on,
+// which GitHub, and so act's schema, does not know
+func isLifecycleTrigger(path string, node *yaml.Node) bool {
+	return lifecycleTriggerPattern.MatchString(path) && node.Kind == yaml.ScalarNode && isLifecycleEvent(node.Value)
+}
+
+// schemaValidator checks documents against one of act's schemas and reports
+// where they do not match with the path of the offending node
+type schemaValidator struct {
+	schema *schema.Schema
+	root   string // Definition of the document
+	// skip reports nodes, by path, that the bridge accepts although the
+	// schema does not
+	skip func(path string, node *yaml.Node) bool
+}
+
+// schemaError is a node that does not match the schema
+type schemaError struct {
+	Node    *yaml.Node
+	Path    string // Such as jobs.build.steps[0]; empty for the document
+	Message string
+	missing string // The required key whose absence is the error, if any
+}
+
+func (e schemaError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// validate checks a document against the schema
+func (v *schemaValidator) validate(root *yaml.Node) []schemaError {
+	return v.check(v.root, root, root, "")
+}
+
+// check matches a node against a definition. Errors about the node as a
+// whole, such as a missing key, are reported at at: the key of the node, if
+// any. Like act, nodes with expressions are not checked here; AB022 to AB029
+// check the expressions themselves.
+func (v *schemaValidator) check(name string, node, at *yaml.Node, path string) []schemaError {
+	node = resolveNode(node)
+	if v.skip != nil && v.skip(path, node) {
+		return nil
+	}
+	if node.Kind == yaml.ScalarNode && strings.Contains(node.Value, "${{") {
+		return nil
+	}
+	fail := func(format string, args ...interface{}) []schemaError {
+		return []schemaError{{Node: at, Path: path, Message: fmt.Sprintf(format, args...)}}
+	}
+
+	def := v.schema.GetDefinition(name)
+	switch {
+	case def.Mapping != nil:
+		return v.checkMapping(def.Mapping, node, at, path)
+	case def.Sequence != nil:
+		if node.Kind != yaml.SequenceNode {
+			return fail("expected a sequence, got %s", describeNode(node))
+		}
+		var errs []schemaError
+		for i, item := range node.Content {
+			errs = append(errs, v.check(def.Sequence.ItemType, item, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return errs
+	case def.OneOf != nil:
+		return v.checkOneOf(*def.OneOf, node, at, path)
+	}
+
+	if !v.fits(name, node) {
+		return fail("expected %s, got %s", v.describe(name), describeNode(node))
+	}
+	switch {
+	case def.String != nil && def.String.Constant != "" && node.Value != def.String.Constant:
+		return fail("expected %q, got %q", def.String.Constant, node.Value)
+	case def.AllowedValues != nil:
+		return enumErrors(*def.AllowedValues, node, fail)
+	}
+	return nil
+}
+
+// checkOneOf matches a node against the first definition it fits. When none
+// does, the errors of the alternative of the node's kind with the fewest
+// errors explain best, and of those the one matching deepest.
+func (v *schemaValidator) checkOneOf(names []string, node, at *yaml.Node, path string) []schemaError {
+	fail := func(format string, args ...interface{}) []schemaError {
+		return []schemaError{{Node: at, Path: path, Message: fmt.Sprintf(format, args...)}}
+	}
+
+	// Alternatives that are all constants, such as the events of on, are
+	// one list of values
+	if values, ok := v.constants(names); ok && node.Kind == yaml.ScalarNode {
+		return enumErrors(values, node, fail)
+	}
+
+	var best []schemaError
+	var missing []string
+	fitting := 0
+	for _, name := range names {
+		if !v.fits(name, node) {
+			continue
+		}
+		errs := v.check(name, node, at, path)
+		if len(errs) == 0 {
+			return nil
+		}
+		if fitting == 0 || len(errs) < len(best) || (len(errs) == len(best) && errorDepth(errs) > errorDepth(best)) {
+			best = errs
+		}
+		if len(errs) == 1 && errs[0].missing != "" {
+			missing = append(missing, errs[0].missing)
+		}
+		fitting++
+	}
+	switch {
+	case fitting == 0:
+		return fail("expected %s, got %s", v.describe(names...), describeNode(node))
+	case fitting > 1 && len(missing) == fitting:
+		// Mappings told apart by a required key, such as run and uses steps
+		return fail("needs one of %s", strings.Join(missing, " or "))
+	}
+	return best
+}
+
+// errorDepth is the total length of the paths of errors: deeper errors mean
+// more of the node matched
+func errorDepth(errs []schemaError) int {
+	depth := 0
+	for _, err := range errs {
+		depth += len(err.Path)
+	}
+	return depth
+}
+
+// checkMapping matches the keys and values of a mapping
+func (v *schemaValidator) checkMapping(m *schema.MappingDefinition, node, at *yaml.Node, path string) []schemaError {
+	if node.Kind != yaml.MappingNode {
+		return []schemaError{{Node: at, Path: path, Message: fmt.Sprintf("expected a mapping, got %s", describeNode(node))}}
+	}
+
+	var errs []schemaError
+	present := make(map[string]bool)
+	eachPair(node, func(key, value *yaml.Node) {
+		present[key.Value] = true
+		keyPath := key.Value
+		if path != "" {
+			keyPath = path + "." + key.Value
+		}
+		if (v.skip != nil && v.skip(keyPath, key)) || strings.Contains(key.Value, "${{") {
+			return
+		}
+		property, ok := m.Properties[key.Value]
+		if !ok {
+			if m.LooseValueType == "" {
+				errs = append(errs, schemaError{Node: key, Path: path, Message: fmt.Sprintf("unknown key %q%s", key.Value, didYouMean(key.Value, sortedSchemaKeys(m.Properties)))})
+				return
+			}
+			property.Type = m.LooseValueType
+		}
+		errs = append(errs, v.check(property.Type, value, key, keyPath)...)
+	})
+
+	for _, name := range sortedSchemaKeys(m.Properties) {
+		if m.Properties[name].Required && !present[name] {
+			errs = append(errs, schemaError{Node: at, Path: path, Message: fmt.Sprintf("missing required key %q", name), missing: name})
+		}
+	}
+	return errs
+}
+
+// fits reports whether a node is of the kind a definition expects, without
+// checking its contents
+func (v *schemaValidator) fits(name string, node *yaml.Node) bool {
+	def := v.schema.GetDefinition(name)
+	scalar := node.Kind == yaml.ScalarNode
+	switch {
+	case def.Mapping != nil:
+		return node.Kind == yaml.MappingNode
+	case def.Sequence != nil:
+		return node.Kind == yaml.SequenceNode
+	case def.OneOf != nil:
+		for _, alternative := range *def.OneOf {
+			if v.fits(alternative, node) {
+				return true
+			}
+		}
+		return false
+	case def.Number != nil:
+		var number float64
+		return scalar && node.Decode(&number) == nil
+	case def.Boolean != nil:
+		var boolean bool
+		return scalar && node.Decode(&boolean) == nil
+	case def.Null != nil:
+		return scalar && node.Tag == "!!null"
+	case def.String != nil, def.AllowedValues != nil:
+		return scalar
+	}
+	return true
+}
+
+// constants returns the values of definitions that only allow fixed
+// strings, and false if any allows other values
+func (v *schemaValidator) constants(names []string) ([]string, bool) {
+	var values []string
+	for _, name := range names {
+		def := v.schema.GetDefinition(name)
+		switch {
+		case def.String != nil && def.String.Constant != "":
+			values = append(values, def.String.Constant)
+		case def.AllowedValues != nil:
+			values = append(values, *def.AllowedValues...)
+		case def.OneOf != nil:
+			more, ok := v.constants(*def.OneOf)
+			if !ok {
+				return nil, false
+			}
+			values = append(values, more...)
+		default:
+			return nil, false
+		}
+	}
+	return values, true
+}
+
+// enumErrors checks that a scalar is one of a list of values
+func enumErrors(values []string, node *yaml.Node, fail func(format string, args ...interface{}) []schemaError) []schemaError {
+	for _, value := range values {
+		if node.Value == value {
+			return nil
+		}
+	}
+	if len(values) <= 5 {
+		return fail("expected one of %s, got %q", strings.Join(values, ", "), node.Value)
+	}
+	return fail("unknown value %q%s", node.Value, didYouMean(node.Value, values))
+}
+
+// describe names what definitions expect, such as "a string or a sequence"
+func (v *schemaValidator) describe(names ...string) string {
+	var kinds []string
+	seen := make(map[string]bool)
+	var collect func(name string)
+	collect = func(name string) {
+		def := v.schema.GetDefinition(name)
+		kind := ""
+		switch {
+		case def.OneOf != nil:
+			for _, alternative := range *def.OneOf {
+				collect(alternative)
+			}
+			return
+		case def.Mapping != nil:
+			kind = "a mapping"
+		case def.Sequence != nil:
+			kind = "a sequence"
+		case def.Number != nil:
+			kind = "a number"
+		case def.Boolean != nil:
+			kind = "a boolean"
+		case def.Null != nil:
+			kind = "null"
+		case def.String != nil, def.AllowedValues != nil:
+			kind = "a string"
+		default:
+			kind = "any value"
+		}
+		if !seen[kind] {
+			seen[kind] = true
+			kinds = append(kinds, kind)
+		}
+	}
+	for _, name := range names {
+		collect(name)
+	}
+	if len(kinds) == 1 {
+		return kinds[0]
+	}
+	return strings.Join(kinds[:len(kinds)-1], ", ") + " or " + kinds[len(kinds)-1]
+}
+
+// describeNode names the kind of a node for error messages
+func describeNode(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a sequence"
+	}
+	switch node.Tag {
+	case "!!null":
+		return "null"
+	case "!!bool":
+		return fmt.Sprintf("boolean %s", node.Value)
+	case "!!int", "!!float":
+		return fmt.Sprintf("number %s", node.Value)
+	}
+	return fmt.Sprintf("string %q", node.Value)
+}
+
+// sortedSchemaKeys returns the known keys of a mapping, sorted
+func sortedSchemaKeys(keys map[string]schema.MappingProperty) []string {
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// didYouMean suggests the closest name to a misspelled one, if any is close
+func didYouMean(name string, names []string) string {
+	// Allow about one edit per three characters
+	best, bestDistance := "", len(name)/3+2
+	for _, candidate := range names {
+		if distance := editDistance(strings.ToLower(name), strings.ToLower(candidate)); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %q?)", best)
+}
+
+// editDistance is the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -22,12 +22,15 @@ type WorkspaceManager struct {
 // Workspace represents an isolated execution environment
 type Workspace struct {
 	ID          string
+	Space       string // Space of the unit the workspace runs for, if any
+	Unit        string // Unit the workspace runs for, if any
 	Root        string
 	WorkflowDir string
 	ConfigDir   string
 	SecretDir   string
 	OutputDir   string
 	created     time.Time
+	kept        bool // Kept after a failed run; guarded by the manager's mu
 	mu          sync.Mutex
 }
 
@@ -45,11 +48,18 @@ func NewWorkspaceManager(baseDir string) (*WorkspaceManager, error) {
 
 // CreateWorkspace creates a new isolated workspace
 func (wm *WorkspaceManager) CreateWorkspace(execID string) (*Workspace, error) {
+	return wm.CreateUnitWorkspace(execID, "", "")
+}
+
+// CreateUnitWorkspace creates a new isolated workspace for a unit of a space
+func (wm *WorkspaceManager) CreateUnitWorkspace(execID, space, unit string) (*Workspace, error) {
 	wm.mu.Lock()
 	defer wm.mu.Unlock()
 
 	ws := &Workspace{
 		ID:          execID,
+		Space:       space,
+		Unit:        unit,
 		Root:        filepath.Join(wm.baseDir, "exec", execID),
 		WorkflowDir: filepath.Join(wm.baseDir, "exec", execID, ".github", "workflows"),
 		ConfigDir:   filepath.Join(wm.baseDir, "exec", execID, "configs"),
@@ -79,11 +89,11 @@ func (wm *WorkspaceManager) CreateWorkspace(execID string) (*Workspace, error) {
 
 	wm.active[execID] = ws
 
-	// Auto-cleanup after timeout
+	// Auto-cleanup after timeout; kept workspaces expire on their own schedule
 	go func() {
 		time.Sleep(1 * time.Hour)
 		wm.mu.Lock()
-		if ws, exists := wm.active[execID]; exists {
+		if ws, exists := wm.active[execID]; exists && !ws.kept {
 			if err := ws.SecureCleanup(); err != nil {
 				log.Printf("Failed to auto-cleanup workspace %s: %v", execID, err)
 			}
@@ -104,6 +114,47 @@ func (wm *WorkspaceManager) GetWorkspace(execID string) (*Workspace, bool) {
 	return ws, exists
 }
 
+// UnitWorkspaces returns the active workspaces of a unit of a space. Unit
+// slugs are only unique within a space.
+func (wm *WorkspaceManager) UnitWorkspaces(space, unit string) []*Workspace {
+	if unit == "" {
+		return nil
+	}
+
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	var workspaces []*Workspace
+	for _, ws := range wm.active {
+		if ws.Space == space && ws.Unit == unit {
+			workspaces = append(workspaces, ws)
+		}
+	}
+	return workspaces
+}
+
+// KeepWorkspace keeps a workspace, without its secrets, for ttl. It stays
+// tracked until then, so removing the unit's workspaces removes it sooner.
+func (wm *WorkspaceManager) KeepWorkspace(ws *Workspace, ttl time.Duration) {
+	ws.RemoveSecrets()
+
+	wm.mu.Lock()
+	ws.kept = true
+	wm.mu.Unlock()
+
+	time.AfterFunc(ttl, func() {
+		wm.mu.Lock()
+		defer wm.mu.Unlock()
+		if wm.active[ws.ID] != ws {
+			return // Already removed
+		}
+		if err := ws.SecureCleanup(); err != nil {
+			log.Printf("Failed to cleanup kept workspace %s: %v", ws.ID, err)
+		}
+		delete(wm.active, ws.ID)
+	})
+}
+
 // RemoveWorkspace removes a workspace from active tracking
 func (wm *WorkspaceManager) RemoveWorkspace(execID string) {
 	wm.mu.Lock()
@@ -126,15 +177,28 @@ func (ws *Workspace) SecureCleanup() error {
 	defer ws.mu.Unlock()
 
 	// First, securely delete secrets
+	ws.removeSecrets()
+
+	// Then remove everything
+	return os.RemoveAll(ws.Root)
+}
+
+// RemoveSecrets securely deletes the secret files of a workspace that is kept
+func (ws *Workspace) RemoveSecrets() {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	ws.removeSecrets()
+}
+
+// removeSecrets securely deletes the secret files. The caller must hold ws.mu.
+func (ws *Workspace) removeSecrets() {
 	secretFiles, _ := filepath.Glob(filepath.Join(ws.SecretDir, "*"))
 	for _, f := range secretFiles {
 		if err := secureDelete(f); err != nil {
 			log.Printf("Warning: failed to secure delete %s: %v", f, err)
 		}
 	}
-
-	// Then remove everything
-	return os.RemoveAll(ws.Root)
 }
 
 // WriteWorkflow writes a workflow file to the workspace
@@ -179,6 +243,51 @@ func (ws *Workspace) WriteConfig(name string, content []byte) error {
 	return os.WriteFile(path, content, 0644)
 }
 
+// WriteFile writes a file at a path relative to the workspace root,
+// creating parent directories as needed
+func (ws *Workspace) WriteFile(relPath string, content []byte, perm os.FileMode) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	// Validate path - prevent directory traversal and writes into secrets
+	if err := validateRelativePath(relPath); err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+
+	path := filepath.Join(ws.Root, filepath.FromSlash(relPath))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+	if err := os.WriteFile(path, content, perm); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of existing files
+	return os.Chmod(path, perm)
+}
+
+// validateRelativePath ensures a slash-separated path stays inside the workspace
+func validateRelativePath(relPath string) error {
+	if relPath == "" {
+		return fmt.Errorf("empty path")
+	}
+	if strings.HasPrefix(relPath, "/") || filepath.IsAbs(relPath) || strings.Contains(relPath, "\\") {
+		return fmt.Errorf("path must be relative: %s", relPath)
+	}
+
+	for _, part := range strings.Split(relPath, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("path contains invalid element: %s", relPath)
+		}
+	}
+
+	// Secrets are written by the bridge only
+	if strings.SplitN(relPath, "/", 2)[0] == ".secrets" {
+		return fmt.Errorf("path is reserved: %s", relPath)
+	}
+
+	return nil
+}
+
 // validateFilename ensures the filename is safe from directory traversal attacks
 func validateFilename(name string) error {
 	if name == "" {